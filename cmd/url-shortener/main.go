@@ -4,6 +4,7 @@ import (
 	"context"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
 	"syscall"
@@ -11,9 +12,22 @@ import (
 
 	ssoGrpc "url-shortener/internal/clients/sso/grpc"
 	"url-shortener/internal/config"
+	grpcserver "url-shortener/internal/grpc-server"
 	httpserver "url-shortener/internal/http-server"
+	"url-shortener/internal/lib/events"
+	"url-shortener/internal/lib/geoip"
+	"url-shortener/internal/lib/hub"
+	"url-shortener/internal/lib/idempotency"
+	"url-shortener/internal/lib/linkhealth"
 	"url-shortener/internal/lib/logger/handlers/slogpretty"
 	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/pagemeta"
+	"url-shortener/internal/lib/rollup"
+	"url-shortener/internal/lib/targeting"
+	"url-shortener/internal/lib/trash"
+	"url-shortener/internal/lib/urlpolicy"
+	"url-shortener/internal/lib/visitors"
+	"url-shortener/internal/lib/webhook"
 	"url-shortener/internal/storage/sqlite"
 )
 
@@ -41,6 +55,13 @@ func main() {
 		os.Exit(1)
 	}
 
+	// links saved before url hashes existed can't be reused until hashed
+	if n, err := storage.HashURLs(context.Background(), 500); err != nil {
+		log.Error("failed to hash urls", sl.Err(err))
+	} else if n > 0 {
+		log.Info("hashed urls", slog.Int64("count", n))
+	}
+
 	ssoClient, err := ssoGrpc.New(
 		context.Background(), log, cfg.Clients.SSO.Address, cfg.Clients.SSO.Timeout, cfg.Clients.SSO.Retries,
 	)
@@ -49,14 +70,103 @@ func main() {
 		os.Exit(1)
 	}
 
+	var countryResolver targeting.CountryResolver
+	if cfg.GeoIP.Path != "" {
+		geoReader, err := geoip.New(cfg.GeoIP.Path)
+		if err != nil {
+			log.Error("failed to open geoip database", sl.Err(err))
+			os.Exit(1)
+		}
+		defer geoReader.Close()
+
+		countryResolver = geoReader
+	}
+
+	// background workers are stopped on shutdown
+	appCtx, stopApp := context.WithCancel(context.Background())
+	defer stopApp()
+
+	shortDomains := cfg.URLPolicy.ShortDomains
+	if baseURL, err := url.Parse(cfg.HTTPServer.BaseURL); err == nil && baseURL.Host != "" {
+		shortDomains = append(shortDomains, baseURL.Host)
+	}
+
+	urlPolicy, err := urlpolicy.New(urlpolicy.Options{
+		AllowedSchemes:   cfg.URLPolicy.AllowedSchemes,
+		ShortDomains:     shortDomains,
+		DenyListPath:     cfg.URLPolicy.DenyListPath,
+		PhishingListPath: cfg.URLPolicy.PhishingListPath,
+	})
+	if err != nil {
+		log.Error("failed to init url policy", sl.Err(err))
+		os.Exit(1)
+	}
+	go urlPolicy.Watch(appCtx, log, cfg.URLPolicy.ReloadInterval)
+	go trash.Run(appCtx, log, storage, cfg.Trash.GracePeriod, cfg.Trash.PurgeInterval)
+	go idempotency.Run(appCtx, log, storage, cfg.Idempotency.TTL, cfg.Idempotency.PruneInterval)
+
+	if cfg.Metadata.Enabled {
+		fetcher := pagemeta.New(pagemeta.Options{
+			Timeout:      cfg.Metadata.Timeout,
+			MaxBytes:     cfg.Metadata.MaxBytes,
+			MaxRedirects: cfg.Metadata.MaxRedirects,
+			UserAgent:    cfg.Metadata.UserAgent,
+		})
+		go pagemeta.Run(appCtx, log, storage, fetcher, cfg.Metadata.Interval, cfg.Metadata.BatchSize)
+	}
+
+	if cfg.LinkHealth.Enabled {
+		checker := linkhealth.NewChecker(linkhealth.CheckerOptions{
+			Timeout:      cfg.LinkHealth.Timeout,
+			MaxRedirects: cfg.LinkHealth.MaxRedirects,
+			UserAgent:    cfg.LinkHealth.UserAgent,
+		})
+		monitor := linkhealth.New(log, storage, checker, linkhealth.Options{
+			Interval:           cfg.LinkHealth.Interval,
+			RetryInterval:      cfg.LinkHealth.RetryInterval,
+			FailureThreshold:   cfg.LinkHealth.FailureThreshold,
+			Concurrency:        cfg.LinkHealth.Concurrency,
+			PerHostConcurrency: cfg.LinkHealth.PerHostConcurrency,
+			BatchSize:          cfg.LinkHealth.BatchSize,
+			PollInterval:       cfg.LinkHealth.PollInterval,
+		})
+		go monitor.Run(appCtx)
+	}
+
+	dispatcher := webhook.NewDispatcher(log, storage, webhook.Options{
+		Timeout:          cfg.Webhooks.Timeout,
+		MaxAttempts:      cfg.Webhooks.MaxAttempts,
+		RetryInterval:    cfg.Webhooks.RetryInterval,
+		MaxRetryInterval: cfg.Webhooks.MaxRetryInterval,
+		BatchSize:        cfg.Webhooks.BatchSize,
+		PollInterval:     cfg.Webhooks.PollInterval,
+		UserAgent:        cfg.Webhooks.UserAgent,
+	})
+	go dispatcher.Run(appCtx)
+
+	go rollup.Run(appCtx, log, storage, rollup.Options{
+		Interval:        cfg.Rollup.Interval,
+		BatchSize:       cfg.Rollup.BatchSize,
+		Retention:       cfg.Rollup.Retention,
+		HourlyRetention: cfg.Rollup.HourlyRetention,
+	})
+
+	visitorCounter := visitors.New(log, storage)
+	go visitorCounter.Run(appCtx, cfg.Visitors.FlushInterval)
+
+	// live click streams are fed in-process, webhooks through the outbox
+	eventHub := hub.New(log, storage, cfg.Live.BufferSize)
+	publisher := events.Multi{webhook.NewOutbox(storage), eventHub, visitorCounter}
+
 	// Setup router with all routes
-	router := httpserver.NewRouter(log, storage, ssoClient, cfg)
+	router := httpserver.NewRouter(log, storage, ssoClient, cfg, countryResolver, urlPolicy, publisher, eventHub)
 
 	log.Info("starting server", slog.String("address", cfg.Address))
 
 	done := make(chan os.Signal, 1)
 	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 
+	// live streams push their own write deadline forward, see handlers/url/live
 	srv := &http.Server{
 		Addr:         cfg.Address,
 		Handler:      router,
@@ -64,6 +174,9 @@ func main() {
 		WriteTimeout: cfg.HTTPServer.Timeout,
 		IdleTimeout:  cfg.HTTPServer.IdleTimeout,
 	}
+	// Shutdown waits for handlers to return, live streams only do once the
+	// hub lets go of them
+	srv.RegisterOnShutdown(eventHub.Close)
 
 	go func() {
 		if err := srv.ListenAndServe(); err != nil {
@@ -73,17 +186,41 @@ func main() {
 
 	log.Info("server started on port", slog.String("port", cfg.HTTPServer.Address))
 
+	var grpcServer *grpcserver.Server
+	if cfg.GRPC.Enabled {
+		grpcServer = grpcserver.New(log, cfg.GRPC, cfg.AppSecret, storage, ssoClient, urlPolicy, publisher)
+
+		go func() {
+			if err := grpcServer.Run(); err != nil {
+				log.Error("failed to start grpc server", sl.Err(err))
+			}
+		}()
+	}
+
 	<-done
 	log.Info("stopping server")
 
+	stopApp()
+
 	// TODO: move timeout to config
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	if grpcServer != nil {
+		grpcServer.Shutdown(ctx)
+	}
+
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Error("failed to stop server", sl.Err(err))
+	}
+
+	// write out what was counted since the last flush, also when shutdown
+	// timed out, with its own deadline as ctx may be spent by now
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFlush()
 
-		return
+	if err := visitorCounter.Flush(flushCtx); err != nil {
+		log.Error("failed to flush visitor sketches", sl.Err(err))
 	}
 
 	// TODO: close storage
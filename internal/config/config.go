@@ -16,21 +16,92 @@ type Config struct {
 	Env         string `yaml:"env" env-default:"local"`
 	StoragePath string `yaml:"storage_path" env-required:"true"`
 	HTTPServer  `yaml:"http_server"`
-	Clients     ClientsConfig `yaml:"clients" env-required:"true"`
+	Clients     ClientsConfig     `yaml:"clients" env-required:"true"`
+	GeoIP       GeoIPConfig       `yaml:"geoip"`
+	URLPolicy   URLPolicy         `yaml:"url_policy"`
+	Admin       AdminConfig       `yaml:"admin"`
+	Trash       TrashConfig       `yaml:"trash"`
+	Idempotency IdempotencyConfig `yaml:"idempotency"`
+	Metadata    MetadataConfig    `yaml:"metadata"`
+	LinkHealth  LinkHealthConfig  `yaml:"link_health"`
+	Webhooks    WebhooksConfig    `yaml:"webhooks"`
+	Live        LiveConfig        `yaml:"live"`
+	Visitors    VisitorsConfig    `yaml:"visitors"`
+	Bots        BotsConfig        `yaml:"bots"`
+	Rollup      RollupConfig      `yaml:"rollup"`
+	GRPC        GRPCConfig        `yaml:"grpc"`
+	API         APIConfig         `yaml:"api"`
 }
 
 type HTTPServer struct {
 	Address     string        `yaml:"address" env-default:"localhost:8080"`
 	Timeout     time.Duration `yaml:"timeout" env-default:"4s"`
 	IdleTimeout time.Duration `yaml:"idle_timeout" env-default:"60s"`
+	// BaseURL is the public address short links are served from, e.g. https://sho.rt.
+	// The request host is used when empty.
+	BaseURL string `yaml:"base_url" env-default:""`
+	// SwaggerUI serves a Swagger UI page for the OpenAPI document at /openapi.
+	// The document itself is always served at /openapi.json.
+	SwaggerUI bool `yaml:"swagger_ui" env-default:"false"`
+	// TrustedProxies are the IPs or CIDRs of reverse proxies whose
+	// X-Forwarded-For and X-Real-IP headers are used as the client address.
+	// The headers are ignored when empty.
+	TrustedProxies []string `yaml:"trusted_proxies"`
+}
+
+// GeoIPConfig points to an offline MaxMind DB used for country targeting.
+// Country conditions never match when Path is empty.
+type GeoIPConfig struct {
+	Path string `yaml:"path" env-default:""`
+}
+
+// URLPolicy restricts which destination urls can be shortened.
+// Deny and phishing lists are optional and reloaded when the files change.
+type URLPolicy struct {
+	AllowedSchemes   []string      `yaml:"allowed_schemes" env-default:"http,https"`
+	ShortDomains     []string      `yaml:"short_domains"`
+	DenyListPath     string        `yaml:"deny_list_path" env-default:""`
+	PhishingListPath string        `yaml:"phishing_list_path" env-default:""`
+	ReloadInterval   time.Duration `yaml:"reload_interval" env-default:"30s"`
+}
+
+// AdminConfig configures the /admin API.
+type AdminConfig struct {
+	// CacheTTL is how long admin checks answered by the SSO service are reused.
+	CacheTTL time.Duration `yaml:"cache_ttl" env-default:"1m"`
+}
+
+// IdempotencyConfig controls how long responses to requests sent with an
+// Idempotency-Key header are replayed.
+type IdempotencyConfig struct {
+	TTL           time.Duration `yaml:"ttl" env-default:"24h"`
+	PruneInterval time.Duration `yaml:"prune_interval" env-default:"1h"`
+}
+
+// TrashConfig controls how long deleted links are kept before they are purged.
+type TrashConfig struct {
+	GracePeriod   time.Duration `yaml:"grace_period" env-default:"720h"`
+	PurgeInterval time.Duration `yaml:"purge_interval" env-default:"1h"`
+}
+
+// MetadataConfig controls the background fetcher of destination page titles
+// and OpenGraph tags.
+type MetadataConfig struct {
+	Enabled      bool          `yaml:"enabled" env-default:"true"`
+	Timeout      time.Duration `yaml:"timeout" env-default:"5s"`
+	MaxBytes     int64         `yaml:"max_bytes" env-default:"524288"`
+	MaxRedirects int           `yaml:"max_redirects" env-default:"3"`
+	UserAgent    string        `yaml:"user_agent" env-default:"url-shortener-bot/1.0"`
+	Interval     time.Duration `yaml:"interval" env-default:"30s"`
+	BatchSize    int           `yaml:"batch_size" env-default:"20"`
 }
 
 type Client struct {
-	Address string `yaml:"address" env-required:"true"`
-	Timeout time.Duration `yaml:"timeout" env-default:"4s"`
-	Retries int `yaml:"retries" env-default:"3"`
-	Insecure bool `yaml:"insecure" env-default:"false"`
-	AppId int `yaml:"app_id" env-required:"true"`
+	Address  string        `yaml:"address" env-required:"true"`
+	Timeout  time.Duration `yaml:"timeout" env-default:"4s"`
+	Retries  int           `yaml:"retries" env-default:"3"`
+	Insecure bool          `yaml:"insecure" env-default:"false"`
+	AppId    int           `yaml:"app_id" env-required:"true"`
 }
 
 type ClientsConfig struct {
@@ -60,8 +131,97 @@ func MustLoad() *AppConfig {
 
 	appCfg := &AppConfig{
 		AppSecret: appSecret,
-		Config: cfg,
+		Config:    cfg,
 	}
 
 	return appCfg
 }
+
+// LinkHealthConfig controls the background checks of link destinations.
+type LinkHealthConfig struct {
+	Enabled bool `yaml:"enabled" env-default:"true"`
+	// Interval is how often a healthy destination is checked.
+	Interval time.Duration `yaml:"interval" env-default:"24h"`
+	// RetryInterval is the first wait after a failed check, doubling up to Interval.
+	RetryInterval      time.Duration `yaml:"retry_interval" env-default:"1h"`
+	FailureThreshold   int           `yaml:"failure_threshold" env-default:"3"`
+	Timeout            time.Duration `yaml:"timeout" env-default:"10s"`
+	MaxRedirects       int           `yaml:"max_redirects" env-default:"5"`
+	UserAgent          string        `yaml:"user_agent" env-default:"url-shortener-linkcheck/1.0 (+link health monitor)"`
+	Concurrency        int           `yaml:"concurrency" env-default:"10"`
+	PerHostConcurrency int           `yaml:"per_host_concurrency" env-default:"2"`
+	BatchSize          int           `yaml:"batch_size" env-default:"100"`
+	PollInterval       time.Duration `yaml:"poll_interval" env-default:"1m"`
+}
+
+// WebhooksConfig controls delivery of webhook events.
+type WebhooksConfig struct {
+	Timeout     time.Duration `yaml:"timeout" env-default:"10s"`
+	MaxAttempts int           `yaml:"max_attempts" env-default:"8"`
+	// RetryInterval is the wait after the first failed attempt, doubling up to MaxRetryInterval.
+	RetryInterval    time.Duration `yaml:"retry_interval" env-default:"30s"`
+	MaxRetryInterval time.Duration `yaml:"max_retry_interval" env-default:"6h"`
+	BatchSize        int           `yaml:"batch_size" env-default:"50"`
+	PollInterval     time.Duration `yaml:"poll_interval" env-default:"5s"`
+	UserAgent        string        `yaml:"user_agent" env-default:"url-shortener-webhooks/1.0"`
+}
+
+// LiveConfig controls the live click streams.
+type LiveConfig struct {
+	// Heartbeat is how often idle streams get a heartbeat event.
+	Heartbeat time.Duration `yaml:"heartbeat" env-default:"15s"`
+	// BufferSize is the number of events kept for a slow stream before
+	// new ones are dropped.
+	BufferSize  int           `yaml:"buffer_size" env-default:"64"`
+	MaxDuration time.Duration `yaml:"max_duration" env-default:"1h"`
+}
+
+// VisitorsConfig controls unique visitor counting.
+type VisitorsConfig struct {
+	// FlushInterval is how often visitor sketches are written to storage,
+	// unique visitor stats lag behind by up to this long.
+	FlushInterval time.Duration `yaml:"flush_interval" env-default:"1m"`
+}
+
+// BotsConfig controls how automated traffic is served.
+type BotsConfig struct {
+	// PreviewPages serves link preview fetchers a page with OpenGraph tags of
+	// the link instead of redirecting them.
+	PreviewPages bool `yaml:"preview_pages" env-default:"false"`
+}
+
+// RollupConfig controls the job aggregating clicks into hourly, daily and
+// breakdown rollups, and the retention of raw clicks.
+type RollupConfig struct {
+	Interval  time.Duration `yaml:"interval" env-default:"1m"`
+	BatchSize int           `yaml:"batch_size" env-default:"5000"`
+	// Retention is how long raw clicks are kept, 0 keeps them forever.
+	// Stats don't need them once they are rolled up.
+	Retention time.Duration `yaml:"retention" env-default:"2160h"`
+	// HourlyRetention is how long hourly rollups are kept, 0 keeps them forever.
+	HourlyRetention time.Duration `yaml:"hourly_retention" env-default:"2160h"`
+}
+
+// GRPCConfig configures the gRPC API served beside the HTTP one.
+type GRPCConfig struct {
+	Enabled bool `yaml:"enabled" env-default:"false"`
+	Port    int  `yaml:"port" env-default:"44045"`
+	// Timeout bounds the handling of a single call.
+	Timeout time.Duration `yaml:"timeout" env-default:"5s"`
+	// APIKeys maps keys of backend services to the user their calls act as.
+	// Set them in GRPC_API_KEYS ("key1:userID1,key2:userID2") rather than in
+	// the config file.
+	APIKeys map[string]int64 `yaml:"api_keys" env:"GRPC_API_KEYS"`
+}
+
+// APIConfig controls the versions of the JSON API. The current version is
+// served under /api/v1, its routes are also served at the root as deprecated
+// aliases.
+type APIConfig struct {
+	// LegacyDeprecatedAt is sent in the Deprecation header of the root aliases,
+	// "true" is sent when it is zero.
+	LegacyDeprecatedAt time.Time `yaml:"legacy_deprecated_at"`
+	// LegacySunset is sent in the Sunset header of the root aliases, none is
+	// sent when it is zero.
+	LegacySunset time.Time `yaml:"legacy_sunset"`
+}
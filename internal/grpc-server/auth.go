@@ -0,0 +1,139 @@
+package grpcserver
+
+import (
+	"context"
+	"crypto/subtle"
+	"log/slog"
+	"net"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/lib/audit"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/random"
+)
+
+// Metadata keys credentials are read from.
+const (
+	authorizationKey = "authorization"
+	apiKeyKey        = "x-api-key"
+	requestIDKey     = "x-request-id"
+)
+
+const requestIDLength = 16
+
+// authInterceptor authenticates calls with an SSO token ("authorization:
+// Bearer <token>") or an API key ("x-api-key: <key>") and puts the user into
+// the context under the keys of the HTTP auth middleware, together with the
+// audit actor. Health checks don't need credentials.
+func authInterceptor(log *slog.Logger, appSecret string, apiKeys map[string]int64) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if strings.HasPrefix(info.FullMethod, "/grpc.health.v1.Health/") {
+			return handler(ctx, req)
+		}
+
+		md, _ := metadata.FromIncomingContext(ctx)
+
+		if token, ok := bearerToken(md); ok {
+			userID, email, err := auth.ParseToken(token, appSecret)
+			if err != nil {
+				log.Info("invalid auth token", slog.String("method", info.FullMethod), sl.Err(err))
+				return nil, status.Error(codes.Unauthenticated, "invalid auth token")
+			}
+
+			ctx = context.WithValue(ctx, auth.UserIDContextKey, userID)
+			ctx = context.WithValue(ctx, auth.UserEmailContextKey, email)
+			ctx = audit.WithActor(ctx, newActor(ctx, md, userID, email))
+
+			return handler(ctx, req)
+		}
+
+		if keys := md.Get(apiKeyKey); len(keys) > 0 {
+			userID, ok := lookupAPIKey(apiKeys, keys[0])
+			if !ok {
+				log.Info("invalid api key", slog.String("method", info.FullMethod))
+				return nil, status.Error(codes.Unauthenticated, "invalid api key")
+			}
+
+			ctx = context.WithValue(ctx, auth.UserIDContextKey, userID)
+			ctx = audit.WithActor(ctx, newActor(ctx, md, userID, ""))
+
+			return handler(ctx, req)
+		}
+
+		log.Info("no credentials", slog.String("method", info.FullMethod))
+
+		return nil, status.Error(codes.Unauthenticated, "auth token or api key required")
+	}
+}
+
+// newActor describes the caller for the audit log. The request id is taken
+// from the "x-request-id" metadata when the client sends one.
+func newActor(ctx context.Context, md metadata.MD, userID int64, email string) audit.Actor {
+	actor := audit.Actor{
+		UserID:    userID,
+		Email:     email,
+		RequestID: random.NewRandomString(requestIDLength),
+	}
+	if ids := md.Get(requestIDKey); len(ids) > 0 && ids[0] != "" {
+		actor.RequestID = ids[0]
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		actor.IP = p.Addr.String()
+		if host, _, err := net.SplitHostPort(actor.IP); err == nil {
+			actor.IP = host
+		}
+	}
+
+	return actor
+}
+
+func bearerToken(md metadata.MD) (string, bool) {
+	values := md.Get(authorizationKey)
+	if len(values) == 0 {
+		return "", false
+	}
+
+	scheme, token, ok := strings.Cut(values[0], " ")
+	if !ok || !strings.EqualFold(scheme, "bearer") {
+		return "", false
+	}
+
+	return strings.TrimSpace(token), true
+}
+
+// lookupAPIKey compares the key with every configured key in constant time.
+func lookupAPIKey(apiKeys map[string]int64, key string) (int64, bool) {
+	var (
+		userID int64
+		found  bool
+	)
+	for k, id := range apiKeys {
+		if subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+			userID, found = id, true
+		}
+	}
+
+	return userID, found
+}
+
+// timeoutInterceptor bounds the handling of every call, 0 disables it.
+func timeoutInterceptor(timeout time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if timeout <= 0 {
+			return handler(ctx, req)
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,114 @@
+// Package grpcserver serves the gRPC API beside the HTTP router.
+package grpcserver
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+
+	grpcLogger "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/logging"
+	grpcRecovery "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/recovery"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthv1 "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	"url-shortener/internal/config"
+	"url-shortener/internal/grpc-server/shortener"
+)
+
+type Server struct {
+	log        *slog.Logger
+	gRPCServer *grpc.Server
+	port       int
+}
+
+// New creates the gRPC server with the Shortener and health services. Calls
+// are authenticated with the SSO token or an API key from cfg.APIKeys and
+// bounded by cfg.Timeout.
+func New(
+	log *slog.Logger,
+	cfg config.GRPCConfig,
+	appSecret string,
+	urlStorage shortener.URLStorage,
+	adminChecker shortener.AdminChecker,
+	urlChecker shortener.URLChecker,
+	eventPublisher shortener.EventPublisher,
+) *Server {
+	loggerOpts := []grpcLogger.Option{
+		grpcLogger.WithLogOnEvents(grpcLogger.FinishCall),
+	}
+
+	recoveryOpts := []grpcRecovery.Option{
+		grpcRecovery.WithRecoveryHandler(func(p any) error {
+			log.Error("recovered from panic", slog.Any("panic", p))
+
+			return status.Error(codes.Internal, "internal error")
+		}),
+	}
+
+	gRPCServer := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		grpcRecovery.UnaryServerInterceptor(recoveryOpts...),
+		grpcLogger.UnaryServerInterceptor(interceptorLogger(log), loggerOpts...),
+		timeoutInterceptor(cfg.Timeout),
+		authInterceptor(log, appSecret, cfg.APIKeys),
+	))
+
+	shortener.Register(gRPCServer, log, urlStorage, adminChecker, urlChecker, eventPublisher)
+	healthv1.RegisterHealthServer(gRPCServer, health.NewServer())
+
+	return &Server{
+		log:        log,
+		gRPCServer: gRPCServer,
+		port:       cfg.Port,
+	}
+}
+
+// Run listens on the configured port and serves until Shutdown.
+func (s *Server) Run() error {
+	const op = "grpcserver.Run"
+
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", s.port))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.log.Info("grpc server started", slog.String("addr", l.Addr().String()))
+
+	return s.Serve(l)
+}
+
+// Serve serves calls accepted on l until Shutdown.
+func (s *Server) Serve(l net.Listener) error {
+	const op = "grpcserver.Serve"
+
+	if err := s.gRPCServer.Serve(l); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Shutdown waits for calls in flight to finish and cancels those still
+// running when ctx is done.
+func (s *Server) Shutdown(ctx context.Context) {
+	stopped := make(chan struct{})
+	go func() {
+		s.gRPCServer.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		s.gRPCServer.Stop()
+	}
+}
+
+func interceptorLogger(l *slog.Logger) grpcLogger.Logger {
+	return grpcLogger.LoggerFunc(func(ctx context.Context, level grpcLogger.Level, msg string, fields ...any) {
+		l.Log(ctx, slog.Level(level), msg, fields...)
+	})
+}
@@ -0,0 +1,384 @@
+package grpcserver_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/sqlite3"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthv1 "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	shortenerv1 "url-shortener/gen/go/shortener"
+	"url-shortener/internal/config"
+	grpcserver "url-shortener/internal/grpc-server"
+	"url-shortener/internal/grpc-server/shortener/mocks"
+	"url-shortener/internal/lib/audit"
+	"url-shortener/internal/lib/events"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/lib/urlpolicy"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/sqlite"
+)
+
+const (
+	appSecret = "test-secret"
+	apiKey    = "service-key"
+)
+
+type suite struct {
+	client         shortenerv1.ShortenerClient
+	conn           *grpc.ClientConn
+	urlStorage     *mocks.URLStorage
+	adminChecker   *mocks.AdminChecker
+	urlChecker     *mocks.URLChecker
+	eventPublisher *mocks.EventPublisher
+}
+
+func newSuite(t *testing.T) *suite {
+	t.Helper()
+
+	s := &suite{
+		urlStorage:     mocks.NewURLStorage(t),
+		adminChecker:   mocks.NewAdminChecker(t),
+		urlChecker:     mocks.NewURLChecker(t),
+		eventPublisher: mocks.NewEventPublisher(t),
+	}
+
+	srv := grpcserver.New(slogdiscard.NewDiscardLogger(), config.GRPCConfig{
+		Timeout: time.Second,
+		APIKeys: map[string]int64{apiKey: 42},
+	}, appSecret, s.urlStorage, s.adminChecker, s.urlChecker, s.eventPublisher)
+
+	s.conn = serve(t, srv)
+	s.client = shortenerv1.NewShortenerClient(s.conn)
+
+	return s
+}
+
+// serve runs srv on an in-memory listener and returns a client connection.
+func serve(t *testing.T, srv *grpcserver.Server) *grpc.ClientConn {
+	t.Helper()
+
+	l := bufconn.Listen(1 << 20)
+	go func() {
+		_ = srv.Serve(l)
+	}()
+	t.Cleanup(func() { srv.Shutdown(context.Background()) })
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return l.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+func withToken(t *testing.T, userID int64) context.Context {
+	t.Helper()
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"uid":   userID,
+		"email": "user@example.com",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte(appSecret))
+	require.NoError(t, err)
+
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func withAPIKey(key string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "x-api-key", key)
+}
+
+func TestShorten(t *testing.T) {
+	s := newSuite(t)
+
+	s.urlChecker.On("Check", "https://example.com").Return(nil).Once()
+	s.urlStorage.On("SaveURL", mock.Anything, "https://example.com", "", "abc", int64(7), models.URLAttrs{
+		Tags:   []string{"go"},
+		Folder: "docs",
+	}).Return(int64(1), nil).Once()
+	s.eventPublisher.On("Publish", mock.Anything, mock.MatchedBy(func(e events.Event) bool {
+		return e.Type == events.LinkCreated && e.Alias == "abc"
+	})).Return(nil).Once()
+
+	res, err := s.client.Shorten(withToken(t, 7), &shortenerv1.ShortenRequest{
+		Url:    "https://example.com",
+		Alias:  "abc",
+		Tags:   []string{" Go "},
+		Folder: " docs ",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "abc", res.GetAlias())
+}
+
+func TestShortenRecordsAuditActor(t *testing.T) {
+	storagePath := filepath.Join(t.TempDir(), "storage.db")
+	migrationsPath, err := filepath.Abs("../../migrations")
+	require.NoError(t, err)
+
+	m, err := migrate.New("file://"+migrationsPath, "sqlite3://"+storagePath)
+	require.NoError(t, err)
+	require.NoError(t, m.Up())
+	srcErr, dbErr := m.Close()
+	require.NoError(t, srcErr)
+	require.NoError(t, dbErr)
+
+	urlStorage, err := sqlite.New(storagePath)
+	require.NoError(t, err)
+
+	urlPolicy, err := urlpolicy.New(urlpolicy.Options{})
+	require.NoError(t, err)
+
+	eventPublisher := mocks.NewEventPublisher(t)
+	eventPublisher.On("Publish", mock.Anything, mock.Anything).Return(nil)
+
+	srv := grpcserver.New(slogdiscard.NewDiscardLogger(), config.GRPCConfig{
+		Timeout: time.Second,
+		APIKeys: map[string]int64{apiKey: 42},
+	}, appSecret, urlStorage, mocks.NewAdminChecker(t), urlPolicy, eventPublisher)
+	client := shortenerv1.NewShortenerClient(serve(t, srv))
+
+	ctx := metadata.AppendToOutgoingContext(withToken(t, 7), "x-request-id", "req-1")
+	_, err = client.Shorten(ctx, &shortenerv1.ShortenRequest{Url: "https://example.com", Alias: "abc"})
+	require.NoError(t, err)
+
+	_, err = client.Shorten(withAPIKey(apiKey), &shortenerv1.ShortenRequest{Url: "https://example.com", Alias: "def"})
+	require.NoError(t, err)
+
+	entries, err := urlStorage.GetAuditLog(context.Background(), models.AuditFilter{Action: audit.ActionURLCreate})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	// newest first
+	assert.Equal(t, "def", entries[0].Alias)
+	assert.Equal(t, int64(42), entries[0].UserID)
+	assert.NotEmpty(t, entries[0].RequestID)
+	assert.NotEmpty(t, entries[0].IP)
+
+	assert.Equal(t, "abc", entries[1].Alias)
+	assert.Equal(t, int64(7), entries[1].UserID)
+	assert.Equal(t, "user@example.com", entries[1].Email)
+	assert.Equal(t, "req-1", entries[1].RequestID)
+	assert.NotEmpty(t, entries[1].IP)
+}
+
+func TestShortenRandomAliasWithAPIKey(t *testing.T) {
+	s := newSuite(t)
+
+	s.urlChecker.On("Check", "https://example.com").Return(nil).Once()
+	s.urlStorage.On("SaveURL", mock.Anything, "https://example.com", "", mock.AnythingOfType("string"), int64(42), mock.Anything).
+		Return(int64(1), nil).Once()
+	s.eventPublisher.On("Publish", mock.Anything, mock.Anything).Return(nil).Once()
+
+	res, err := s.client.Shorten(withAPIKey(apiKey), &shortenerv1.ShortenRequest{Url: "https://example.com"})
+	require.NoError(t, err)
+	assert.Len(t, res.GetAlias(), 6)
+}
+
+func TestShortenInvalid(t *testing.T) {
+	s := newSuite(t)
+
+	_, err := s.client.Shorten(withAPIKey(apiKey), &shortenerv1.ShortenRequest{Url: "not a url"})
+	st, _ := status.FromError(err)
+	require.Equal(t, codes.InvalidArgument, st.Code())
+	assert.Equal(t, "field URL is not a valid URL", st.Message())
+
+	require.Len(t, st.Details(), 1)
+	details, ok := st.Details()[0].(*errdetails.BadRequest)
+	require.True(t, ok)
+	require.Len(t, details.GetFieldViolations(), 1)
+	assert.Equal(t, "url", details.GetFieldViolations()[0].GetField())
+}
+
+func TestShortenRejectedByPolicy(t *testing.T) {
+	s := newSuite(t)
+
+	s.urlChecker.On("Check", "https://sho.rt/abc").
+		Return([]urlpolicy.Violation{{Code: "short_link", Message: "links to other short links are not allowed"}}).Once()
+
+	_, err := s.client.Shorten(withAPIKey(apiKey), &shortenerv1.ShortenRequest{Url: "https://sho.rt/abc"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestShortenReservedAlias(t *testing.T) {
+	s := newSuite(t)
+
+	s.urlChecker.On("Check", "https://example.com").Return(nil).Once()
+
+	_, err := s.client.Shorten(withAPIKey(apiKey), &shortenerv1.ShortenRequest{Url: "https://example.com", Alias: "admin"})
+	st, _ := status.FromError(err)
+	require.Equal(t, codes.InvalidArgument, st.Code())
+	assert.Equal(t, "field Alias is reserved", st.Message())
+}
+
+func TestUnauthenticated(t *testing.T) {
+	cases := []struct {
+		name string
+		ctx  context.Context
+	}{
+		{name: "No credentials", ctx: context.Background()},
+		{name: "Unknown API key", ctx: withAPIKey("other-key")},
+		{
+			name: "Invalid token",
+			ctx:  metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer nope"),
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			s := newSuite(t)
+
+			_, err := s.client.Resolve(tc.ctx, &shortenerv1.ResolveRequest{Alias: "abc"})
+			assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		})
+	}
+}
+
+func TestHealthWithoutCredentials(t *testing.T) {
+	s := newSuite(t)
+
+	res, err := healthv1.NewHealthClient(s.conn).Check(context.Background(), &healthv1.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, healthv1.HealthCheckResponse_SERVING, res.GetStatus())
+}
+
+func TestStorageErrors(t *testing.T) {
+	cases := []struct {
+		name     string
+		call     func(s *suite) error
+		mockErr  error
+		expected codes.Code
+	}{
+		{
+			name: "Resolve not found",
+			call: func(s *suite) error {
+				_, err := s.client.Resolve(withAPIKey(apiKey), &shortenerv1.ResolveRequest{Alias: "abc"})
+				return err
+			},
+			mockErr:  storage.ErrURLNotFound,
+			expected: codes.NotFound,
+		},
+		{
+			name: "Resolve quarantined",
+			call: func(s *suite) error {
+				_, err := s.client.Resolve(withAPIKey(apiKey), &shortenerv1.ResolveRequest{Alias: "abc"})
+				return err
+			},
+			mockErr:  storage.ErrURLQuarantined,
+			expected: codes.FailedPrecondition,
+		},
+		{
+			name: "Resolve failure",
+			call: func(s *suite) error {
+				_, err := s.client.Resolve(withAPIKey(apiKey), &shortenerv1.ResolveRequest{Alias: "abc"})
+				return err
+			},
+			mockErr:  fmt.Errorf("disk I/O error"),
+			expected: codes.Internal,
+		},
+		{
+			name: "Delete not owned",
+			call: func(s *suite) error {
+				s.adminChecker.On("IsAdmin", mock.Anything, int64(42)).Return(false, nil).Once()
+				_, err := s.client.Delete(withAPIKey(apiKey), &shortenerv1.DeleteRequest{Alias: "abc"})
+				return err
+			},
+			mockErr:  storage.ErrURLNotOwned,
+			expected: codes.PermissionDenied,
+		},
+		{
+			name: "Shorten exists",
+			call: func(s *suite) error {
+				s.urlChecker.On("Check", "https://example.com").Return(nil).Once()
+				_, err := s.client.Shorten(withAPIKey(apiKey), &shortenerv1.ShortenRequest{Url: "https://example.com", Alias: "abc"})
+				return err
+			},
+			mockErr:  storage.ErrURLExists,
+			expected: codes.AlreadyExists,
+		},
+		{
+			name: "Shorten on unverified domain",
+			call: func(s *suite) error {
+				s.urlChecker.On("Check", "https://example.com").Return(nil).Once()
+				_, err := s.client.Shorten(withAPIKey(apiKey), &shortenerv1.ShortenRequest{
+					Url: "https://example.com", Domain: "go.example.com",
+				})
+				return err
+			},
+			mockErr:  storage.ErrDomainNotVerified,
+			expected: codes.PermissionDenied,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			s := newSuite(t)
+
+			wrapped := fmt.Errorf("storage.sqlite.X: %w", tc.mockErr)
+			s.urlStorage.On("GetURL", mock.Anything, "", "abc").Return("", wrapped).Maybe()
+			s.urlStorage.On("DeleteURL", mock.Anything, "", "abc", int64(42), false).Return(wrapped).Maybe()
+			s.urlStorage.On("SaveURL", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Return(int64(0), wrapped).Maybe()
+
+			assert.Equal(t, tc.expected, status.Code(tc.call(s)))
+		})
+	}
+}
+
+func TestList(t *testing.T) {
+	s := newSuite(t)
+
+	created := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	s.urlStorage.On("GetUserURLs", mock.Anything, int64(7), models.URLFilter{Tag: "go"}).Return([]models.URL{
+		{ID: 1, Alias: "abc", URL: "https://example.com", Tags: []string{"go"}, CreatedAt: created, UpdatedAt: created},
+	}, nil).Once()
+	s.urlStorage.On("GetUserURLs", mock.Anything, int64(7), models.URLFilter{Tag: "none"}).
+		Return(nil, storage.ErrUserURLsNotFound).Once()
+
+	res, err := s.client.List(withToken(t, 7), &shortenerv1.ListRequest{Tag: "Go"})
+	require.NoError(t, err)
+	require.Len(t, res.GetLinks(), 1)
+	assert.Equal(t, "abc", res.GetLinks()[0].GetAlias())
+	assert.Equal(t, created, res.GetLinks()[0].GetCreatedAt().AsTime())
+
+	res, err = s.client.List(withToken(t, 7), &shortenerv1.ListRequest{Tag: "none"})
+	require.NoError(t, err)
+	assert.Empty(t, res.GetLinks())
+}
+
+func TestDelete(t *testing.T) {
+	s := newSuite(t)
+
+	s.adminChecker.On("IsAdmin", mock.Anything, int64(7)).Return(true, nil).Once()
+	s.urlStorage.On("DeleteURL", mock.Anything, "go.example.com", "abc", int64(7), true).Return(nil).Once()
+	s.eventPublisher.On("Publish", mock.Anything, mock.MatchedBy(func(e events.Event) bool {
+		return e.Type == events.LinkDeleted && e.Domain == "go.example.com" && e.Alias == "abc"
+	})).Return(nil).Once()
+
+	_, err := s.client.Delete(withToken(t, 7), &shortenerv1.DeleteRequest{Domain: "go.example.com", Alias: "abc"})
+	require.NoError(t, err)
+}
@@ -0,0 +1,326 @@
+// Package shortener serves the Shortener gRPC service, the counterpart of the
+// /url HTTP API for backend services.
+package shortener
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	shortenerv1 "url-shortener/gen/go/shortener"
+	"url-shortener/internal/http-server/handlers/url/save"
+	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/lib/aliases"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/events"
+	"url-shortener/internal/lib/hostname"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/random"
+	"url-shortener/internal/lib/tags"
+	"url-shortener/internal/lib/urlpolicy"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+// TODO: move to config if needed
+const aliasLength = 6
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=URLStorage
+type URLStorage interface {
+	SaveURL(
+		ctx context.Context, urlToSave string, domain string, alias string, userID int64, attrs models.URLAttrs,
+	) (int64, error)
+	GetURL(ctx context.Context, domain string, alias string) (string, error)
+	GetUserURLs(ctx context.Context, userID int64, filter models.URLFilter) ([]models.URL, error)
+	DeleteURL(ctx context.Context, domain string, alias string, userID int64, isAdmin bool) error
+}
+
+// AdminChecker tells whether the user is an admin, admins may delete any link.
+//
+//go:generate go run github.com/vektra/mockery/v2@latest --name=AdminChecker
+type AdminChecker interface {
+	IsAdmin(ctx context.Context, userID int64) (bool, error)
+}
+
+// URLChecker checks destination urls against the url policy.
+//
+//go:generate go run github.com/vektra/mockery/v2@latest --name=URLChecker
+type URLChecker interface {
+	Check(rawURL string) []urlpolicy.Violation
+}
+
+// EventPublisher publishes link events to webhooks and live streams.
+//
+//go:generate go run github.com/vektra/mockery/v2@latest --name=EventPublisher
+type EventPublisher interface {
+	Publish(ctx context.Context, event events.Event) error
+}
+
+type serverAPI struct {
+	shortenerv1.UnimplementedShortenerServer
+	log            *slog.Logger
+	urlStorage     URLStorage
+	adminChecker   AdminChecker
+	urlChecker     URLChecker
+	eventPublisher EventPublisher
+}
+
+// Register registers the Shortener service on the server. Calls must be
+// authenticated before they reach it, the user id is read from the context
+// as set by the HTTP auth middleware.
+func Register(
+	gRPCServer *grpc.Server,
+	log *slog.Logger,
+	urlStorage URLStorage,
+	adminChecker AdminChecker,
+	urlChecker URLChecker,
+	eventPublisher EventPublisher,
+) {
+	shortenerv1.RegisterShortenerServer(gRPCServer, &serverAPI{
+		log:            log,
+		urlStorage:     urlStorage,
+		adminChecker:   adminChecker,
+		urlChecker:     urlChecker,
+		eventPublisher: eventPublisher,
+	})
+}
+
+// Shorten validates the request like POST /url does and saves the link.
+func (s *serverAPI) Shorten(ctx context.Context, in *shortenerv1.ShortenRequest) (*shortenerv1.ShortenResponse, error) {
+	const op = "grpc.shortener.Shorten"
+
+	log := s.log.With(slog.String("op", op))
+
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		log.Error("user_id not found in context")
+		return nil, err
+	}
+
+	req := save.Request{
+		URL:         in.GetUrl(),
+		Alias:       in.GetAlias(),
+		Domain:      hostname.Normalize(in.GetDomain()),
+		Tags:        in.GetTags(),
+		Folder:      in.GetFolder(),
+		Title:       in.GetTitle(),
+		Description: in.GetDescription(),
+		Notes:       in.GetNotes(),
+	}
+	if err := validator.New().Struct(req); err != nil {
+		var validateErr validator.ValidationErrors
+		if !errors.As(err, &validateErr) {
+			log.Error("failed to validate request", sl.Err(err))
+			return nil, status.Error(codes.Internal, "failed to validate request")
+		}
+		log.Info("invalid request", sl.Err(err))
+		return nil, invalidArgument(resp.ValidationError(validateErr))
+	}
+
+	if violations := s.urlChecker.Check(req.URL); len(violations) > 0 {
+		log.Info("url rejected by policy", slog.String("url", req.URL), slog.Any("violations", violations))
+		return nil, invalidArgument(resp.FieldErrors(urlpolicy.FieldErrors("URL", violations)))
+	}
+
+	if errs := aliases.Check("Alias", req.Alias); len(errs) > 0 {
+		log.Info("alias is reserved", slog.String("alias", req.Alias))
+		return nil, invalidArgument(resp.FieldErrors(errs))
+	}
+
+	alias := req.Alias
+	if alias == "" {
+		alias = random.NewRandomString(aliasLength)
+	}
+
+	attrs := models.URLAttrs{
+		Tags:        tags.Normalize(req.Tags),
+		Folder:      strings.TrimSpace(req.Folder),
+		Title:       strings.TrimSpace(req.Title),
+		Description: strings.TrimSpace(req.Description),
+		Notes:       req.Notes,
+	}
+
+	id, err := s.urlStorage.SaveURL(ctx, req.URL, req.Domain, alias, userID, attrs)
+	if err != nil {
+		return nil, storageError(log, err, "failed to add url")
+	}
+
+	log.Info("url added", slog.Int64("id", id))
+
+	event := events.New(events.LinkCreated, req.Domain, alias, events.LinkData{
+		URL:         req.URL,
+		Tags:        attrs.Tags,
+		Folder:      attrs.Folder,
+		Title:       attrs.Title,
+		Description: attrs.Description,
+	})
+	if err := s.eventPublisher.Publish(ctx, event); err != nil {
+		log.Error("failed to publish event", sl.Err(err))
+	}
+
+	return &shortenerv1.ShortenResponse{Alias: alias, Domain: req.Domain}, nil
+}
+
+// Resolve returns the default destination of any active link, targeting
+// rules and split variants are not applied.
+func (s *serverAPI) Resolve(ctx context.Context, in *shortenerv1.ResolveRequest) (*shortenerv1.ResolveResponse, error) {
+	const op = "grpc.shortener.Resolve"
+
+	log := s.log.With(slog.String("op", op))
+
+	if in.GetAlias() == "" {
+		return nil, status.Error(codes.InvalidArgument, "alias is required")
+	}
+
+	url, err := s.urlStorage.GetURL(ctx, hostname.Normalize(in.GetDomain()), in.GetAlias())
+	if err != nil {
+		return nil, storageError(log, err, "failed to get url")
+	}
+
+	return &shortenerv1.ResolveResponse{Url: url}, nil
+}
+
+// List returns the links of the user narrowed down like GET /url.
+func (s *serverAPI) List(ctx context.Context, in *shortenerv1.ListRequest) (*shortenerv1.ListResponse, error) {
+	const op = "grpc.shortener.List"
+
+	log := s.log.With(slog.String("op", op))
+
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		log.Error("user_id not found in context")
+		return nil, err
+	}
+
+	urls, err := s.urlStorage.GetUserURLs(ctx, userID, models.URLFilter{
+		Tag:    tags.Name(in.GetTag()),
+		Folder: strings.TrimSpace(in.GetFolder()),
+		Broken: in.GetBroken(),
+	})
+	if errors.Is(err, storage.ErrUserURLsNotFound) {
+		return &shortenerv1.ListResponse{}, nil
+	}
+	if err != nil {
+		return nil, storageError(log, err, "failed to get user urls")
+	}
+
+	links := make([]*shortenerv1.Link, 0, len(urls))
+	for _, u := range urls {
+		links = append(links, &shortenerv1.Link{
+			Id:          u.ID,
+			Domain:      u.Domain,
+			Alias:       u.Alias,
+			Url:         u.URL,
+			Tags:        u.Tags,
+			Folder:      u.Folder,
+			Title:       u.Title,
+			Description: u.Description,
+			Notes:       u.Notes,
+			CreatedAt:   timestamppb.New(u.CreatedAt),
+			UpdatedAt:   timestamppb.New(u.UpdatedAt),
+		})
+	}
+
+	return &shortenerv1.ListResponse{Links: links}, nil
+}
+
+// Delete moves the link to the trash like DELETE /url/{alias}.
+func (s *serverAPI) Delete(ctx context.Context, in *shortenerv1.DeleteRequest) (*shortenerv1.DeleteResponse, error) {
+	const op = "grpc.shortener.Delete"
+
+	log := s.log.With(slog.String("op", op))
+
+	userID, err := userIDFromContext(ctx)
+	if err != nil {
+		log.Error("user_id not found in context")
+		return nil, err
+	}
+
+	if in.GetAlias() == "" {
+		return nil, status.Error(codes.InvalidArgument, "alias is required")
+	}
+
+	isAdmin, err := s.adminChecker.IsAdmin(ctx, userID)
+	if err != nil {
+		log.Error("failed to check if user is admin", sl.Err(err))
+		return nil, status.Error(codes.Internal, "failed to check if user is admin")
+	}
+
+	domain := hostname.Normalize(in.GetDomain())
+
+	if err := s.urlStorage.DeleteURL(ctx, domain, in.GetAlias(), userID, isAdmin); err != nil {
+		return nil, storageError(log, err, "failed to delete url")
+	}
+
+	if err := s.eventPublisher.Publish(ctx, events.New(events.LinkDeleted, domain, in.GetAlias(), nil)); err != nil {
+		log.Error("failed to publish event", sl.Err(err))
+	}
+
+	return &shortenerv1.DeleteResponse{}, nil
+}
+
+func userIDFromContext(ctx context.Context) (int64, error) {
+	userID, ok := ctx.Value(auth.UserIDContextKey).(int64)
+	if !ok {
+		return 0, status.Error(codes.Internal, "user_id not found in token")
+	}
+
+	return userID, nil
+}
+
+// storageError maps storage errors to gRPC codes the same way the HTTP API
+// maps them to statuses. Unexpected errors are logged and reported as msg.
+func storageError(log *slog.Logger, err error, msg string) error {
+	switch {
+	case errors.Is(err, storage.ErrURLNotFound):
+		log.Info("url not found", sl.Err(err))
+		return status.Error(codes.NotFound, "url not found")
+	case errors.Is(err, storage.ErrURLNotOwned):
+		log.Info("url not owned", sl.Err(err))
+		return status.Error(codes.PermissionDenied, "url not owned")
+	case errors.Is(err, storage.ErrURLExists):
+		log.Info("url already exists", sl.Err(err))
+		return status.Error(codes.AlreadyExists, "url already exists")
+	case errors.Is(err, storage.ErrURLQuarantined):
+		log.Info("url quarantined", sl.Err(err))
+		return status.Error(codes.FailedPrecondition, "url quarantined")
+	case errors.Is(err, storage.ErrDomainNotFound):
+		log.Info("domain not found", sl.Err(err))
+		return status.Error(codes.InvalidArgument, "domain not found")
+	case errors.Is(err, storage.ErrDomainNotOwned), errors.Is(err, storage.ErrDomainNotVerified):
+		log.Info("domain not available", sl.Err(err))
+		return status.Error(codes.PermissionDenied, "domain not available")
+	}
+
+	log.Error(msg, sl.Err(err))
+
+	return status.Error(codes.Internal, msg)
+}
+
+// invalidArgument returns the field errors of the response as an
+// InvalidArgument status with BadRequest details. Fields are named like in
+// the proto messages.
+func invalidArgument(r resp.Response) error {
+	details := &errdetails.BadRequest{}
+	for _, e := range r.Errors {
+		details.FieldViolations = append(details.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       strings.ToLower(e.Field),
+			Description: e.Message,
+		})
+	}
+
+	st, err := status.New(codes.InvalidArgument, r.Error).WithDetails(details)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, r.Error)
+	}
+
+	return st.Err()
+}
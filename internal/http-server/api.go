@@ -0,0 +1,149 @@
+package httpserver
+
+import (
+	"log/slog"
+	"net"
+
+	"github.com/go-chi/chi/v5"
+
+	ssoGrpc "url-shortener/internal/clients/sso/grpc"
+	"url-shortener/internal/config"
+	"url-shortener/internal/http-server/handlers/admin"
+	"url-shortener/internal/http-server/handlers/domains"
+	"url-shortener/internal/http-server/handlers/login"
+	"url-shortener/internal/http-server/handlers/register"
+	"url-shortener/internal/http-server/handlers/settings"
+	"url-shortener/internal/http-server/handlers/tags"
+	"url-shortener/internal/http-server/handlers/url/delete"
+	"url-shortener/internal/http-server/handlers/url/getUrls"
+	"url-shortener/internal/http-server/handlers/url/live"
+	"url-shortener/internal/http-server/handlers/url/qr"
+	"url-shortener/internal/http-server/handlers/url/rules"
+	"url-shortener/internal/http-server/handlers/url/save"
+	"url-shortener/internal/http-server/handlers/url/stats"
+	"url-shortener/internal/http-server/handlers/url/trash"
+	"url-shortener/internal/http-server/handlers/url/update"
+	"url-shortener/internal/http-server/handlers/url/variants"
+	"url-shortener/internal/http-server/handlers/webhooks"
+	mwAdmin "url-shortener/internal/http-server/middleware/admin"
+	mwAudit "url-shortener/internal/http-server/middleware/audit"
+	"url-shortener/internal/http-server/middleware/auth"
+	mwIdempotency "url-shortener/internal/http-server/middleware/idempotency"
+	"url-shortener/internal/lib/dnsverify"
+	"url-shortener/internal/lib/events"
+	"url-shortener/internal/lib/hub"
+	"url-shortener/internal/lib/urlpolicy"
+	"url-shortener/internal/storage"
+)
+
+// apiDeps are shared by all versions of the JSON API. A new version gets its
+// own method registering its routes, reusing the handlers that didn't change.
+type apiDeps struct {
+	log            *slog.Logger
+	urlStorage     storage.Storage
+	ssoClient      *ssoGrpc.Client
+	cfg            *config.AppConfig
+	urlPolicy      *urlpolicy.Policy
+	eventPublisher events.Publisher
+	eventHub       *hub.Hub
+}
+
+// v1 registers the routes of the v1 JSON API on r.
+func (d apiDeps) v1(r chi.Router) {
+	log, urlStorage, ssoClient, cfg := d.log, d.urlStorage, d.ssoClient, d.cfg
+	urlPolicy, eventPublisher, eventHub := d.urlPolicy, d.eventPublisher, d.eventHub
+
+	// Auth routes
+	r.Post("/login", login.New(log, ssoClient, cfg, urlStorage))
+	r.Post("/register", register.New(log, ssoClient, cfg, urlStorage))
+	r.Get("/login", login.GetLogin(log, cfg))
+
+	liveOpts := live.Options{Heartbeat: cfg.Live.Heartbeat, MaxDuration: cfg.Live.MaxDuration}
+
+	// Retried writes with the same Idempotency-Key get the first response
+	idempotent := mwIdempotency.New(log, urlStorage, cfg.Idempotency.TTL)
+
+	// Protected routes
+	r.Route("/url", func(r chi.Router) {
+		r.Use(auth.New(log, cfg))
+		r.Use(mwAudit.New())
+		r.Use(idempotent)
+		r.Post("/", save.New(log, urlStorage, urlPolicy, eventPublisher))
+		r.Get("/", getUrls.New(log, urlStorage))
+		r.Get("/trash", trash.NewList(log, urlStorage, cfg.Trash.GracePeriod))
+		r.Get("/live", live.NewUser(log, eventHub, liveOpts))
+		r.Get("/stats", stats.NewUser(log, urlStorage))
+		r.Get("/clicks/export", stats.NewUserExport(log, urlStorage))
+		r.Patch("/{alias}", update.New(log, urlStorage, urlPolicy, eventPublisher))
+		r.Delete("/{alias}", delete.New(log, urlStorage, ssoClient, eventPublisher))
+		r.Post("/{alias}/restore", trash.NewRestore(log, urlStorage))
+		r.Get("/{alias}/rules", rules.NewList(log, urlStorage))
+		r.Post("/{alias}/rules", rules.NewCreate(log, urlStorage, urlPolicy))
+		r.Put("/{alias}/rules/{id}", rules.NewUpdate(log, urlStorage, urlPolicy))
+		r.Delete("/{alias}/rules/{id}", rules.NewDelete(log, urlStorage))
+		r.Get("/{alias}/variants", variants.NewList(log, urlStorage))
+		r.Put("/{alias}/variants", variants.NewSet(log, urlStorage, urlPolicy))
+		r.Get("/{alias}/live", live.New(log, eventHub, urlStorage, liveOpts))
+		r.Get("/{alias}/stats", stats.New(log, urlStorage))
+		r.Get("/{alias}/clicks/export", stats.NewExport(log, urlStorage))
+		r.Get("/{alias}/qr", qr.New(log, urlStorage, cfg.HTTPServer.BaseURL))
+		// TODO: add DELETE /url/{id}
+	})
+
+	r.Route("/tags", func(r chi.Router) {
+		r.Use(auth.New(log, cfg))
+		r.Use(mwAudit.New())
+		r.Use(idempotent)
+		r.Get("/", tags.NewList(log, urlStorage))
+		r.Post("/merge", tags.NewMerge(log, urlStorage))
+		r.Put("/{name}", tags.NewRename(log, urlStorage))
+	})
+	r.With(auth.New(log, cfg)).Get("/folders", tags.NewListFolders(log, urlStorage))
+
+	r.Route("/settings", func(r chi.Router) {
+		r.Use(auth.New(log, cfg))
+		r.Use(mwAudit.New())
+		r.Use(idempotent)
+		r.Get("/", settings.NewGet(log, urlStorage))
+		r.Put("/", settings.NewUpdate(log, urlStorage))
+	})
+
+	r.Route("/webhooks", func(r chi.Router) {
+		r.Use(auth.New(log, cfg))
+		r.Use(mwAudit.New())
+		r.Use(idempotent)
+		r.Post("/", webhooks.NewCreate(log, urlStorage))
+		r.Get("/", webhooks.NewList(log, urlStorage))
+		r.Delete("/{id}", webhooks.NewDelete(log, urlStorage))
+		r.Get("/{id}/deliveries", webhooks.NewDeliveries(log, urlStorage))
+		r.Post("/{id}/deliveries/{deliveryID}/redeliver", webhooks.NewRedeliver(log, urlStorage))
+	})
+
+	r.Route("/domains", func(r chi.Router) {
+		r.Use(auth.New(log, cfg))
+		r.Use(mwAudit.New())
+		r.Use(idempotent)
+		r.Post("/", domains.NewCreate(log, urlStorage))
+		r.Get("/", domains.NewList(log, urlStorage))
+		r.Post("/{hostname}/verify", domains.NewVerify(log, urlStorage, dnsverify.New(net.DefaultResolver)))
+		r.Delete("/{hostname}", domains.NewDelete(log, urlStorage))
+	})
+
+	// Admin routes
+	r.Route("/admin", func(r chi.Router) {
+		r.Use(auth.New(log, cfg))
+		r.Use(mwAdmin.New(log, ssoClient, cfg.Admin.CacheTTL))
+		r.Use(mwAudit.New())
+		r.Use(idempotent)
+		r.Get("/audit", admin.NewAuditLog(log, urlStorage))
+		r.Get("/stats", admin.NewStats(log, urlStorage))
+		r.Get("/reports", admin.NewListReports(log, urlStorage))
+		r.Get("/urls", admin.NewSearchURLs(log, urlStorage))
+		r.Post("/urls/bulk-delete", admin.NewBulkDelete(log, urlStorage))
+		r.Post("/urls/transfer", admin.NewTransfer(log, urlStorage))
+		r.Post("/urls/{alias}/quarantine", admin.NewQuarantine(log, urlStorage))
+		r.Post("/urls/{alias}/restore", admin.NewRestore(log, urlStorage))
+		r.Delete("/urls/{alias}", admin.NewDelete(log, urlStorage))
+		r.Get("/users/{userID}/urls", admin.NewUserURLs(log, urlStorage))
+	})
+}
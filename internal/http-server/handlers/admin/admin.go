@@ -0,0 +1,143 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/lib/api"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+type Response struct {
+	resp.Response
+	Reports []models.Report `json:"reports,omitempty"`
+	URLs    []models.URL    `json:"urls,omitempty"`
+	Stats   *models.Stats   `json:"stats,omitempty"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=AbuseStorage
+type AbuseStorage interface {
+	GetReports(ctx context.Context, status string) ([]models.Report, error)
+	SetURLQuarantined(ctx context.Context, domain string, alias string, quarantined bool) error
+	PurgeURL(ctx context.Context, domain string, alias string) error
+}
+
+// NewListReports returns abuse reports, newest first. The "status" query
+// parameter filters them by status, all reports are returned without it.
+func NewListReports(log *slog.Logger, abuseStorage AbuseStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.NewListReports"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		status := r.URL.Query().Get("status")
+		if status != "" && status != models.ReportStatusOpen && status != models.ReportStatusResolved {
+			log.Info("invalid report status", slog.String("status", status))
+			resp.RenderError(w, r, http.StatusBadRequest, resp.Error("invalid status"))
+			return
+		}
+
+		reports, err := abuseStorage.GetReports(r.Context(), status)
+		if err != nil {
+			log.Error("failed to get reports", sl.Err(err))
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("failed to get reports"))
+			return
+		}
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Reports:  reports,
+		})
+	}
+}
+
+// NewQuarantine disables the link until it is restored and resolves its open reports.
+// Links on custom domains are addressed with the "domain" query parameter.
+func NewQuarantine(log *slog.Logger, abuseStorage AbuseStorage) http.HandlerFunc {
+	return newSetQuarantined(log, abuseStorage, true)
+}
+
+// NewRestore lifts the quarantine of the link and resolves its open reports.
+func NewRestore(log *slog.Logger, abuseStorage AbuseStorage) http.HandlerFunc {
+	return newSetQuarantined(log, abuseStorage, false)
+}
+
+func newSetQuarantined(log *slog.Logger, abuseStorage AbuseStorage, quarantined bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.newSetQuarantined"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		domain := api.DomainParam(r)
+		alias := chi.URLParam(r, "alias")
+
+		err := abuseStorage.SetURLQuarantined(r.Context(), domain, alias, quarantined)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("url not found", slog.String("alias", alias))
+			resp.RenderError(w, r, http.StatusNotFound, resp.Error("url not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to update url quarantine", sl.Err(err))
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("failed to update url"))
+			return
+		}
+
+		log.Info("url quarantine updated", slog.String("alias", alias), slog.Bool("quarantined", quarantined))
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+		})
+	}
+}
+
+// NewDelete permanently removes a link regardless of its owner, together with
+// its reports. Unlike user deletes it skips the trash.
+func NewDelete(log *slog.Logger, abuseStorage AbuseStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.NewDelete"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, _ := r.Context().Value(auth.UserIDContextKey).(int64)
+		domain := api.DomainParam(r)
+		alias := chi.URLParam(r, "alias")
+
+		err := abuseStorage.PurgeURL(r.Context(), domain, alias)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("url not found", slog.String("alias", alias))
+			resp.RenderError(w, r, http.StatusNotFound, resp.Error("url not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to delete url", sl.Err(err))
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("failed to delete url"))
+			return
+		}
+
+		log.Info("url deleted by admin", slog.String("alias", alias), slog.Int64("admin_id", userID))
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package admin_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/admin"
+	"url-shortener/internal/http-server/handlers/admin/mocks"
+	"url-shortener/internal/lib/audit"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+func TestQuarantineHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		mockError error
+		code      int
+	}{
+		{
+			name: "Success",
+			code: http.StatusOK,
+		},
+		{
+			name:      "Not found",
+			mockError: storage.ErrURLNotFound,
+			code:      http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			abuseStorageMock := mocks.NewAbuseStorage(t)
+			abuseStorageMock.On("SetURLQuarantined", mock.Anything, "", "bad", true).
+				Return(tc.mockError).Once()
+
+			r := chi.NewRouter()
+			r.Post("/admin/urls/{alias}/quarantine", admin.NewQuarantine(slogdiscard.NewDiscardLogger(), abuseStorageMock))
+
+			req := httptest.NewRequest(http.MethodPost, "/admin/urls/bad/quarantine", nil)
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.code, rr.Code)
+		})
+	}
+}
+
+func TestTransferHandler(t *testing.T) {
+	cases := []struct {
+		name string
+		body string
+		ids  []int64
+		code int
+	}{
+		{
+			name: "All links",
+			body: `{"from_user_id": 1, "to_user_id": 2}`,
+			code: http.StatusOK,
+		},
+		{
+			name: "Selected links",
+			body: `{"from_user_id": 1, "to_user_id": 2, "ids": [3, 4]}`,
+			ids:  []int64{3, 4},
+			code: http.StatusOK,
+		},
+		{
+			name: "Same user",
+			body: `{"from_user_id": 1, "to_user_id": 1}`,
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "Missing target",
+			body: `{"from_user_id": 1}`,
+			code: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			urlStorageMock := mocks.NewURLStorage(t)
+			if tc.code == http.StatusOK {
+				urlStorageMock.On("AdminTransferURLs", mock.Anything, int64(1), int64(2), tc.ids).
+					Return(int64(2), nil).Once()
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/admin/urls/transfer", strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+			admin.NewTransfer(slogdiscard.NewDiscardLogger(), urlStorageMock).ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.code, rr.Code)
+			if tc.code == http.StatusOK {
+				assert.JSONEq(t, `{"status": "OK", "affected": 2}`, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestAuditLogExport(t *testing.T) {
+	auditStorageMock := mocks.NewAuditStorage(t)
+	auditStorageMock.On("ExportAuditLog", mock.Anything, models.AuditFilter{Alias: "a", UserID: 7}, mock.Anything).
+		Run(func(args mock.Arguments) {
+			fn := args.Get(2).(func(models.AuditEntry) error)
+			_ = fn(models.AuditEntry{ID: 2, Action: audit.ActionURLDelete, Alias: "a"})
+			_ = fn(models.AuditEntry{ID: 1, Action: audit.ActionURLCreate, Alias: "a"})
+		}).
+		Return(nil).Once()
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/audit?alias=a&user_id=7", nil)
+	req.Header.Set("Accept", "application/x-ndjson")
+	rr := httptest.NewRecorder()
+	admin.NewAuditLog(slogdiscard.NewDiscardLogger(), auditStorageMock).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/x-ndjson", rr.Header().Get("Content-Type"))
+
+	lines := strings.Split(strings.TrimSpace(rr.Body.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `"action":"url.delete"`)
+	assert.Contains(t, lines[1], `"action":"url.create"`)
+}
+
+func TestAuditLogInvalidFilter(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/admin/audit?from=yesterday", nil)
+	rr := httptest.NewRecorder()
+	admin.NewAuditLog(slogdiscard.NewDiscardLogger(), mocks.NewAuditStorage(t)).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
@@ -0,0 +1,147 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	"url-shortener/internal/lib/api"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/models"
+)
+
+const ndjsonContentType = "application/x-ndjson"
+
+type AuditResponse struct {
+	resp.Response
+	Entries []models.AuditEntry `json:"entries"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=AuditStorage
+type AuditStorage interface {
+	GetAuditLog(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error)
+	ExportAuditLog(ctx context.Context, filter models.AuditFilter, fn func(models.AuditEntry) error) error
+}
+
+// NewAuditLog returns audit records, newest first. They are filtered with the
+// "user_id", "action", "domain", "alias", "from" and "to" (RFC 3339) query
+// parameters and paged with "limit" and "offset".
+//
+// With "format=ndjson" or an "Accept: application/x-ndjson" header all
+// matching records are streamed one JSON object per line, ignoring paging.
+func NewAuditLog(log *slog.Logger, auditStorage AuditStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.NewAuditLog"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		filter, err := parseAuditFilter(r)
+		if err != nil {
+			log.Info("invalid audit filter", sl.Err(err))
+			resp.RenderError(w, r, http.StatusBadRequest, resp.Error(err.Error()))
+			return
+		}
+
+		if wantsNDJSON(r) {
+			exportAuditLog(w, r, log, auditStorage, filter)
+			return
+		}
+
+		var ok bool
+		if filter.Limit, filter.Offset, ok = api.ParsePage(w, r, log); !ok {
+			return
+		}
+
+		entries, err := auditStorage.GetAuditLog(r.Context(), filter)
+		if err != nil {
+			log.Error("failed to get audit log", sl.Err(err))
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("failed to get audit log"))
+			return
+		}
+
+		render.JSON(w, r, AuditResponse{
+			Response: resp.OK(),
+			Entries:  entries,
+		})
+	}
+}
+
+func exportAuditLog(w http.ResponseWriter, r *http.Request, log *slog.Logger, auditStorage AuditStorage, filter models.AuditFilter) {
+	w.Header().Set("Content-Type", ndjsonContentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="audit.ndjson"`)
+
+	enc := json.NewEncoder(w)
+	written := 0
+
+	err := auditStorage.ExportAuditLog(r.Context(), filter, func(e models.AuditEntry) error {
+		written++
+		return enc.Encode(e)
+	})
+	if err != nil {
+		// once the stream has started the status can't be changed, the
+		// client sees a truncated export
+		log.Error("failed to export audit log", sl.Err(err), slog.Int("written", written))
+		if written == 0 {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Del("Content-Disposition")
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("failed to export audit log"))
+		}
+		return
+	}
+
+	log.Info("audit log exported", slog.Int("entries", written))
+}
+
+func wantsNDJSON(r *http.Request) bool {
+	if r.URL.Query().Get("format") == "ndjson" {
+		return true
+	}
+
+	return strings.Contains(r.Header.Get("Accept"), ndjsonContentType)
+}
+
+func parseAuditFilter(r *http.Request) (models.AuditFilter, error) {
+	query := r.URL.Query()
+
+	filter := models.AuditFilter{
+		Action: query.Get("action"),
+		Domain: api.DomainParam(r),
+		Alias:  query.Get("alias"),
+	}
+
+	if v := query.Get("user_id"); v != "" {
+		userID, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return filter, errors.New("invalid user_id")
+		}
+		filter.UserID = userID
+	}
+	if v := query.Get("from"); v != "" {
+		from, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return filter, errors.New("invalid from")
+		}
+		filter.From = from
+	}
+	if v := query.Get("to"); v != "" {
+		to, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return filter, errors.New("invalid to")
+		}
+		filter.To = to
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,238 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+
+	"url-shortener/internal/lib/api"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/models"
+)
+
+type BulkDeleteRequest struct {
+	IDs []int64 `json:"ids" validate:"required,min=1,max=1000,dive,gt=0"`
+}
+
+// TransferRequest moves links between users. All links of FromUserID are
+// moved when IDs is empty.
+type TransferRequest struct {
+	FromUserID int64   `json:"from_user_id" validate:"required,gt=0"`
+	ToUserID   int64   `json:"to_user_id" validate:"required,gt=0,nefield=FromUserID"`
+	IDs        []int64 `json:"ids,omitempty" validate:"max=1000,dive,gt=0"`
+}
+
+type BulkResponse struct {
+	resp.Response
+	// Affected is the number of links changed by the operation.
+	Affected int64 `json:"affected"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=URLStorage
+type URLStorage interface {
+	AdminSearchURLs(ctx context.Context, filter models.URLFilter) ([]models.URL, error)
+	AdminDeleteURLs(ctx context.Context, ids []int64) (int64, error)
+	AdminTransferURLs(ctx context.Context, fromUserID int64, toUserID int64, ids []int64) (int64, error)
+	AdminStats(ctx context.Context) (models.Stats, error)
+}
+
+// NewSearchURLs lists links of all users. The "alias" and "url" query
+// parameters match substrings, "user_id" the owner. Results are paged with
+// "limit" and "offset".
+func NewSearchURLs(log *slog.Logger, urlStorage URLStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.NewSearchURLs"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		query := r.URL.Query()
+		filter := models.URLFilter{
+			Alias: query.Get("alias"),
+			URL:   query.Get("url"),
+		}
+
+		if v := query.Get("user_id"); v != "" {
+			userID, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				log.Info("invalid user id", sl.Err(err))
+				resp.RenderError(w, r, http.StatusBadRequest, resp.Error("invalid user_id"))
+				return
+			}
+			filter.UserID = userID
+		}
+
+		var ok bool
+		if filter.Limit, filter.Offset, ok = api.ParsePage(w, r, log); !ok {
+			return
+		}
+
+		searchURLs(w, r, log, urlStorage, filter)
+	}
+}
+
+// NewUserURLs lists links of the user from the path.
+func NewUserURLs(log *slog.Logger, urlStorage URLStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.NewUserURLs"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+		if err != nil || userID <= 0 {
+			log.Info("invalid user id", slog.String("user_id", chi.URLParam(r, "userID")))
+			resp.RenderError(w, r, http.StatusBadRequest, resp.Error("invalid user id"))
+			return
+		}
+
+		filter := models.URLFilter{UserID: userID}
+		var ok bool
+		if filter.Limit, filter.Offset, ok = api.ParsePage(w, r, log); !ok {
+			return
+		}
+
+		searchURLs(w, r, log, urlStorage, filter)
+	}
+}
+
+// NewBulkDelete moves links to the trash by id regardless of their owner.
+func NewBulkDelete(log *slog.Logger, urlStorage URLStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.NewBulkDelete"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req BulkDeleteRequest
+		if !decode(w, r, log, &req) {
+			return
+		}
+
+		n, err := urlStorage.AdminDeleteURLs(r.Context(), req.IDs)
+		if err != nil {
+			log.Error("failed to delete urls", sl.Err(err))
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("failed to delete urls"))
+			return
+		}
+
+		log.Info("urls deleted by admin", slog.Int64("deleted", n))
+
+		render.JSON(w, r, BulkResponse{
+			Response: resp.OK(),
+			Affected: n,
+		})
+	}
+}
+
+// NewTransfer moves links from one user to another.
+func NewTransfer(log *slog.Logger, urlStorage URLStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.NewTransfer"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req TransferRequest
+		if !decode(w, r, log, &req) {
+			return
+		}
+
+		n, err := urlStorage.AdminTransferURLs(r.Context(), req.FromUserID, req.ToUserID, req.IDs)
+		if err != nil {
+			log.Error("failed to transfer urls", sl.Err(err))
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("failed to transfer urls"))
+			return
+		}
+
+		log.Info("urls transferred",
+			slog.Int64("from_user_id", req.FromUserID),
+			slog.Int64("to_user_id", req.ToUserID),
+			slog.Int64("transferred", n),
+		)
+
+		render.JSON(w, r, BulkResponse{
+			Response: resp.OK(),
+			Affected: n,
+		})
+	}
+}
+
+// NewStats returns system-wide counters.
+func NewStats(log *slog.Logger, urlStorage URLStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.NewStats"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		stats, err := urlStorage.AdminStats(r.Context())
+		if err != nil {
+			log.Error("failed to get stats", sl.Err(err))
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("failed to get stats"))
+			return
+		}
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Stats:    &stats,
+		})
+	}
+}
+
+func searchURLs(w http.ResponseWriter, r *http.Request, log *slog.Logger, urlStorage URLStorage, filter models.URLFilter) {
+	urls, err := urlStorage.AdminSearchURLs(r.Context(), filter)
+	if err != nil {
+		log.Error("failed to search urls", sl.Err(err))
+		resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("failed to search urls"))
+		return
+	}
+
+	render.JSON(w, r, Response{
+		Response: resp.OK(),
+		URLs:     urls,
+	})
+}
+
+func decode(w http.ResponseWriter, r *http.Request, log *slog.Logger, req any) bool {
+	err := render.DecodeJSON(r.Body, req)
+	if errors.Is(err, io.EOF) {
+		log.Error("request body is empty")
+		resp.RenderError(w, r, http.StatusBadRequest, resp.Error("empty request"))
+		return false
+	}
+	if err != nil {
+		log.Error("failed to decode request body", sl.Err(err))
+		resp.RenderError(w, r, http.StatusBadRequest, resp.Error("failed to decode request"))
+		return false
+	}
+
+	if err := validator.New().Struct(req); err != nil {
+		var validateErr validator.ValidationErrors
+		errors.As(err, &validateErr)
+		log.Error("invalid request", sl.Err(err))
+		resp.RenderError(w, r, http.StatusBadRequest, resp.ValidationError(validateErr))
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,275 @@
+package domains
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+
+	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/lib/api"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/dnsverify"
+	"url-shortener/internal/lib/hostname"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/random"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+const tokenLength = 32
+
+// claimTTL is how long an unverified domain is reserved for the user who
+// added it. Afterwards another user can claim the hostname.
+const claimTTL = 24 * time.Hour
+
+type Request struct {
+	Hostname string `json:"hostname" validate:"required,fqdn"`
+}
+
+// Verification describes the DNS TXT record proving ownership of a domain.
+type Verification struct {
+	RecordType  string `json:"record_type"`
+	RecordName  string `json:"record_name"`
+	RecordValue string `json:"record_value"`
+}
+
+type Response struct {
+	resp.Response
+	Domain       *models.Domain  `json:"domain,omitempty"`
+	Domains      []models.Domain `json:"domains,omitempty"`
+	Verification *Verification   `json:"verification,omitempty"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=DomainStorage
+type DomainStorage interface {
+	SaveDomain(ctx context.Context, hostname string, userID int64, token string, staleBefore time.Time) (int64, error)
+	GetDomain(ctx context.Context, hostname string) (models.Domain, error)
+	GetUserDomains(ctx context.Context, userID int64) ([]models.Domain, error)
+	MarkDomainVerified(ctx context.Context, hostname string, userID int64) error
+	DeleteDomain(ctx context.Context, hostname string, userID int64) error
+}
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=DomainVerifier
+type DomainVerifier interface {
+	Verify(ctx context.Context, hostname string, token string) error
+}
+
+// NewCreate registers a custom domain and returns the TXT record to verify it with.
+func NewCreate(log *slog.Logger, domainStorage DomainStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.domains.NewCreate"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := r.Context().Value(auth.UserIDContextKey).(int64)
+		if !ok {
+			log.Error("user_id not found in context")
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("user_id not found in token"))
+			return
+		}
+
+		var req Request
+
+		err := render.DecodeJSON(r.Body, &req)
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+			resp.RenderError(w, r, http.StatusBadRequest, resp.Error("empty request"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			resp.RenderError(w, r, http.StatusBadRequest, resp.Error("failed to decode request"))
+			return
+		}
+
+		req.Hostname = hostname.Normalize(req.Hostname)
+
+		if err := validator.New().Struct(req); err != nil {
+			var validateErr validator.ValidationErrors
+			errors.As(err, &validateErr)
+			log.Error("invalid request", sl.Err(err))
+			resp.RenderError(w, r, http.StatusBadRequest, resp.ValidationError(validateErr))
+			return
+		}
+
+		token := random.NewRandomString(tokenLength)
+
+		id, err := domainStorage.SaveDomain(r.Context(), req.Hostname, userID, token, time.Now().Add(-claimTTL))
+		if errors.Is(err, storage.ErrDomainExists) {
+			log.Info("domain already exists", slog.String("hostname", req.Hostname))
+			resp.RenderError(w, r, http.StatusConflict, resp.Error("domain already exists"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to add domain", sl.Err(err))
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("failed to add domain"))
+			return
+		}
+
+		log.Info("domain added", slog.Int64("id", id))
+
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Domain: &models.Domain{
+				ID:                id,
+				Hostname:          req.Hostname,
+				UserID:            userID,
+				VerificationToken: token,
+			},
+			Verification: verification(req.Hostname, token),
+		})
+	}
+}
+
+// NewList returns custom domains of the user.
+func NewList(log *slog.Logger, domainStorage DomainStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.domains.NewList"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := r.Context().Value(auth.UserIDContextKey).(int64)
+		if !ok {
+			log.Error("user_id not found in context")
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("user_id not found in token"))
+			return
+		}
+
+		domains, err := domainStorage.GetUserDomains(r.Context(), userID)
+		if err != nil {
+			log.Error("failed to get domains", sl.Err(err))
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("failed to get domains"))
+			return
+		}
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Domains:  domains,
+		})
+	}
+}
+
+// NewVerify checks the DNS TXT record of the domain and marks it verified.
+func NewVerify(log *slog.Logger, domainStorage DomainStorage, verifier DomainVerifier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.domains.NewVerify"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := r.Context().Value(auth.UserIDContextKey).(int64)
+		if !ok {
+			log.Error("user_id not found in context")
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("user_id not found in token"))
+			return
+		}
+
+		hostname := hostnameParam(r)
+
+		d, err := domainStorage.GetDomain(r.Context(), hostname)
+		if errors.Is(err, storage.ErrDomainNotFound) || (err == nil && d.UserID != userID) {
+			log.Info("domain not found", slog.String("hostname", hostname))
+			resp.RenderError(w, r, http.StatusNotFound, resp.Error("domain not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get domain", sl.Err(err))
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("failed to get domain"))
+			return
+		}
+
+		if err := verifier.Verify(r.Context(), d.Hostname, d.VerificationToken); err != nil {
+			log.Info("domain verification failed", sl.Err(err))
+			resp.RenderError(w, r, http.StatusUnprocessableEntity, Response{
+				Response:     resp.Error("verification record not found"),
+				Verification: verification(d.Hostname, d.VerificationToken),
+			})
+			return
+		}
+
+		if err := domainStorage.MarkDomainVerified(r.Context(), d.Hostname, userID); err != nil {
+			log.Error("failed to mark domain verified", sl.Err(err))
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("failed to verify domain"))
+			return
+		}
+
+		log.Info("domain verified", slog.String("hostname", d.Hostname))
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+		})
+	}
+}
+
+// NewDelete removes a custom domain without links.
+func NewDelete(log *slog.Logger, domainStorage DomainStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.domains.NewDelete"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := r.Context().Value(auth.UserIDContextKey).(int64)
+		if !ok {
+			log.Error("user_id not found in context")
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("user_id not found in token"))
+			return
+		}
+
+		hostname := hostnameParam(r)
+
+		err := domainStorage.DeleteDomain(r.Context(), hostname, userID)
+		if errors.Is(err, storage.ErrDomainNotFound) {
+			log.Info("domain not found", slog.String("hostname", hostname))
+			resp.RenderError(w, r, http.StatusNotFound, resp.Error("domain not found"))
+			return
+		}
+		if errors.Is(err, storage.ErrDomainInUse) {
+			log.Info("domain in use", slog.String("hostname", hostname))
+			resp.RenderError(w, r, http.StatusConflict, resp.Error("domain has links"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to delete domain", sl.Err(err))
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("failed to delete domain"))
+			return
+		}
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+		})
+	}
+}
+
+func verification(hostname string, token string) *Verification {
+	return &Verification{
+		RecordType:  "TXT",
+		RecordName:  dnsverify.RecordName(hostname),
+		RecordValue: dnsverify.RecordValue(token),
+	}
+}
+
+// hostnameParam returns the hostname path parameter. middleware.URLFormat
+// takes the top-level domain at the end of /domains/{hostname} for a format
+// extension, api.URLParam puts it back.
+func hostnameParam(r *http.Request) string {
+	return hostname.Normalize(api.URLParam(r, "hostname"))
+}
@@ -28,7 +28,7 @@ func GetLogin(log *slog.Logger, cfg *config.AppConfig) http.HandlerFunc {
 		cookie, err := r.Cookie("auth_token")
 		if err != nil {
 			log.Error("failed to get auth token from cookie", sl.Err(err))
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			resp.RenderStatusError(w, r, http.StatusUnauthorized)
 			return
 		}
 		
@@ -37,7 +37,7 @@ func GetLogin(log *slog.Logger, cfg *config.AppConfig) http.HandlerFunc {
 		})
 		if err != nil {
 			log.Error("failed to parse auth token", sl.Err(err))
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			resp.RenderStatusError(w, r, http.StatusUnauthorized)
 			return
 		}
 		render.Status(r, http.StatusOK)
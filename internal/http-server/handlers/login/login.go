@@ -1,15 +1,19 @@
 package login
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"io"
 	"log/slog"
 	"net/http"
 	"url-shortener/internal/config"
+	"url-shortener/internal/lib/audit"
 	"url-shortener/internal/lib/logger/sl"
 
 	ssoGrpc "url-shortener/internal/clients/sso/grpc"
 	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/models"
 
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
@@ -23,7 +27,14 @@ type Request struct {
 	Password string `json:"password" validate:"required"`
 }
 
-func New(log *slog.Logger, ssoClient *ssoGrpc.Client, cfg *config.AppConfig) http.HandlerFunc {
+// AuditRecorder records login attempts in the audit log.
+//
+//go:generate go run github.com/vektra/mockery/v2@latest --name=AuditRecorder
+type AuditRecorder interface {
+	RecordAudit(ctx context.Context, entry models.AuditEntry) error
+}
+
+func New(log *slog.Logger, ssoClient *ssoGrpc.Client, cfg *config.AppConfig, auditRecorder AuditRecorder) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.login.New"
 		log := log.With(
@@ -37,21 +48,18 @@ func New(log *slog.Logger, ssoClient *ssoGrpc.Client, cfg *config.AppConfig) htt
 		if err != nil {
 			if errors.Is(err, io.EOF) {
 				log.Error("request body is empty")
-				render.Status(r, http.StatusBadRequest)
-				render.JSON(w, r, resp.Error("empty request"))
+				resp.RenderError(w, r, http.StatusBadRequest, resp.Error("empty request"))
 				return
 			}
 			log.Error("failed to decode request body", sl.Err(err))
-			render.Status(r, http.StatusBadRequest)
-			render.JSON(w, r, resp.Error("failed to decode request"))
+			resp.RenderError(w, r, http.StatusBadRequest, resp.Error("failed to decode request"))
 			return
 		}
 
 		if err := validator.New().Struct(req); err != nil {
 			validateErr := err.(validator.ValidationErrors)
 			log.Error("invalid request", sl.Err(err))
-			render.Status(r, http.StatusBadRequest) 
-			render.JSON(w, r, resp.ValidationError(validateErr))
+			resp.RenderError(w, r, http.StatusBadRequest, resp.ValidationError(validateErr))
 			return
 		}
 
@@ -60,16 +68,18 @@ func New(log *slog.Logger, ssoClient *ssoGrpc.Client, cfg *config.AppConfig) htt
 			st, ok := status.FromError(err)
 			if ok && st.Code() == codes.Unauthenticated {
 				log.Error("invalid credentials")
-				render.Status(r, http.StatusUnauthorized)
-				render.JSON(w, r, resp.Error("invalid credentials"))
+				recordAttempt(r.Context(), log, auditRecorder, req.Email, "invalid credentials")
+				resp.RenderError(w, r, http.StatusUnauthorized, resp.Error("invalid credentials"))
 				return
 			}
 			log.Error("failed to login", sl.Err(err))
-			render.Status(r, http.StatusInternalServerError)
-			render.JSON(w, r, resp.Error("failed to login"))
+			recordAttempt(r.Context(), log, auditRecorder, req.Email, "sso error")
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("failed to login"))
 			return
 		}
 
+		recordAttempt(r.Context(), log, auditRecorder, req.Email, "")
+
 		// Set token in cookie
 		http.SetCookie(w, &http.Cookie{
 			Name:     "auth_token",
@@ -84,4 +94,21 @@ func New(log *slog.Logger, ssoClient *ssoGrpc.Client, cfg *config.AppConfig) htt
 		render.Status(r, http.StatusOK)
 		render.JSON(w, r, resp.OK())
 	}
-}
\ No newline at end of file
+}
+
+// recordAttempt writes the login attempt to the audit log. Failures are only
+// logged so that auditing can't lock users out.
+func recordAttempt(ctx context.Context, log *slog.Logger, auditRecorder AuditRecorder, email string, reason string) {
+	entry := models.AuditEntry{
+		Email:  email,
+		Action: audit.ActionLogin,
+	}
+	if reason != "" {
+		entry.Action = audit.ActionLoginFailed
+		entry.After, _ = json.Marshal(map[string]string{"reason": reason})
+	}
+
+	if err := auditRecorder.RecordAudit(ctx, entry); err != nil {
+		log.Error("failed to record audit", sl.Err(err))
+	}
+}
@@ -0,0 +1,87 @@
+// Package openapi serves the OpenAPI document of the HTTP API and a Swagger UI
+// page for it.
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"gopkg.in/yaml.v3"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+)
+
+// specYAML is the OpenAPI 3 document. It is kept in sync with the router by
+// TestRouterMatchesSpec in the httpserver package.
+//
+//go:embed openapi.yaml
+var specYAML []byte
+
+//go:embed swagger.html
+var swaggerHTML string
+
+var swaggerPage = template.Must(template.New("swagger").Parse(swaggerHTML))
+
+// Spec returns the OpenAPI document as JSON.
+func Spec() ([]byte, error) {
+	const op = "handlers.openapi.Spec"
+
+	var doc map[string]any
+	if err := yaml.Unmarshal(specYAML, &doc); err != nil {
+		return nil, fmt.Errorf("%s: parse spec: %w", op, err)
+	}
+
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("%s: encode spec: %w", op, err)
+	}
+
+	return b, nil
+}
+
+// New serves the OpenAPI document as JSON.
+func New(log *slog.Logger) http.HandlerFunc {
+	spec, specErr := Spec()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.openapi.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		if specErr != nil {
+			log.Error("failed to load spec", sl.Err(specErr))
+			resp.RenderStatusError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(spec)
+	}
+}
+
+// NewSwaggerUI serves a Swagger UI page rendering the document at specURL.
+// The UI assets are loaded from a CDN by the browser.
+func NewSwaggerUI(log *slog.Logger, specURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.openapi.NewSwaggerUI"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := swaggerPage.Execute(w, struct{ SpecURL string }{SpecURL: specURL}); err != nil {
+			log.Error("failed to render swagger ui", sl.Err(err))
+		}
+	}
+}
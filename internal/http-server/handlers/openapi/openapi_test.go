@@ -0,0 +1,40 @@
+package openapi_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/openapi"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+func TestSpecHandler(t *testing.T) {
+	rr := httptest.NewRecorder()
+	openapi.New(slogdiscard.NewDiscardLogger()).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+	var doc struct {
+		OpenAPI string                     `json:"openapi"`
+		Paths   map[string]json.RawMessage `json:"paths"`
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &doc))
+	assert.Equal(t, "3.0.3", doc.OpenAPI)
+	assert.Contains(t, doc.Paths, "/api/v1/url/{alias}")
+}
+
+func TestSwaggerUIHandler(t *testing.T) {
+	rr := httptest.NewRecorder()
+	openapi.NewSwaggerUI(slogdiscard.NewDiscardLogger(), "/openapi.json").
+		ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/openapi", nil))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/html; charset=utf-8", rr.Header().Get("Content-Type"))
+	assert.Contains(t, rr.Body.String(), `url: "/openapi.json"`)
+}
@@ -2,16 +2,20 @@
 
 package mocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+)
 
 // URLGetter is an autogenerated mock type for the URLGetter type
 type URLGetter struct {
 	mock.Mock
 }
 
-// GetURL provides a mock function with given fields: alias
-func (_m *URLGetter) GetURL(alias string) (string, error) {
-	ret := _m.Called(alias)
+// GetURL provides a mock function with given fields: ctx, domain, alias
+func (_m *URLGetter) GetURL(ctx context.Context, domain string, alias string) (string, error) {
+	ret := _m.Called(ctx, domain, alias)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GetURL")
@@ -19,17 +23,17 @@ func (_m *URLGetter) GetURL(alias string) (string, error) {
 
 	var r0 string
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string) (string, error)); ok {
-		return rf(alias)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (string, error)); ok {
+		return rf(ctx, domain, alias)
 	}
-	if rf, ok := ret.Get(0).(func(string) string); ok {
-		r0 = rf(alias)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) string); ok {
+		r0 = rf(ctx, domain, alias)
 	} else {
 		r0 = ret.Get(0).(string)
 	}
 
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(alias)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, domain, alias)
 	} else {
 		r1 = ret.Error(1)
 	}
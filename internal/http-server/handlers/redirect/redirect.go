@@ -3,16 +3,26 @@ package redirect
 import (
 	"context"
 	"errors"
+	"html/template"
 	"net/http"
 
 	"log/slog"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
-	"github.com/go-chi/render"
 
+	mwDomain "url-shortener/internal/http-server/middleware/domain"
 	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/botdetect"
+	"url-shortener/internal/lib/events"
 	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/random"
+	"url-shortener/internal/lib/referrer"
+	"url-shortener/internal/lib/split"
+	"url-shortener/internal/lib/targeting"
+	"url-shortener/internal/lib/useragent"
+	"url-shortener/internal/lib/visitors"
+	"url-shortener/internal/models"
 	"url-shortener/internal/storage"
 )
 
@@ -20,10 +30,72 @@ import (
 //
 //go:generate go run github.com/vektra/mockery/v2@latest --name=URLGetter
 type URLGetter interface {
-	GetURL(ctx context.Context, alias string) (string, error)
+	GetURL(ctx context.Context, domain string, alias string) (string, error)
 }
 
-func New(log *slog.Logger, urlGetter URLGetter) http.HandlerFunc {
+// RulesGetter is an interface for getting targeting rules of the url.
+//
+//go:generate go run github.com/vektra/mockery/v2@latest --name=RulesGetter
+type RulesGetter interface {
+	GetURLRules(ctx context.Context, domain string, alias string) ([]models.Rule, error)
+}
+
+// VariantsGetter is an interface for getting split destinations of the url.
+//
+//go:generate go run github.com/vektra/mockery/v2@latest --name=VariantsGetter
+type VariantsGetter interface {
+	GetURLVariants(ctx context.Context, domain string, alias string) ([]models.Variant, error)
+}
+
+// ClickRecorder is an interface for recording served redirects.
+//
+//go:generate go run github.com/vektra/mockery/v2@latest --name=ClickRecorder
+type ClickRecorder interface {
+	RecordClick(ctx context.Context, click models.Click) error
+}
+
+// PreviewGetter is an interface for getting what preview pages show about the url.
+//
+//go:generate go run github.com/vektra/mockery/v2@latest --name=PreviewGetter
+type PreviewGetter interface {
+	GetURLPreview(ctx context.Context, domain string, alias string) (models.URL, error)
+}
+
+// EventPublisher publishes link events to webhooks and live streams.
+//
+//go:generate go run github.com/vektra/mockery/v2@latest --name=EventPublisher
+type EventPublisher interface {
+	Publish(ctx context.Context, event events.Event) error
+}
+
+const (
+	visitorCookie       = "vid"
+	visitorIDLength     = 16
+	visitorCookieMaxAge = 3600 * 24 * 365 // 1 year
+)
+
+// New redirects to the first matching targeting rule of the alias. Without a
+// matching rule a split variant is picked for the visitor, and the default url
+// is used when the link has no variants. The alias is looked up on the domain
+// resolved by the domain middleware. Quarantined links serve a warning page
+// instead. countryResolver may be nil if no GeoIP database is configured.
+//
+// Every click is recorded with its class of traffic (see botdetect). Link
+// preview fetchers get a page with OpenGraph tags of the link instead of the
+// redirect, unless previewGetter is nil. Visitors without the visitor cookie
+// are counted by a fingerprint keyed with fingerprintKey, see
+// visitors.Fingerprint.
+func New(
+	log *slog.Logger,
+	urlGetter URLGetter,
+	rulesGetter RulesGetter,
+	variantsGetter VariantsGetter,
+	clickRecorder ClickRecorder,
+	eventPublisher EventPublisher,
+	countryResolver targeting.CountryResolver,
+	previewGetter PreviewGetter,
+	fingerprintKey []byte,
+) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.url.redirect.New"
 
@@ -33,24 +105,251 @@ func New(log *slog.Logger, urlGetter URLGetter) http.HandlerFunc {
 		)
 
 		alias := chi.URLParam(r, "alias")
+		domain, _ := r.Context().Value(mwDomain.DomainContextKey).(string)
 
-		resURL, err := urlGetter.GetURL(r.Context(), alias)
+		resURL, err := urlGetter.GetURL(r.Context(), domain, alias)
 		if errors.Is(err, storage.ErrURLNotFound) {
 			log.Info("url not found", "alias", alias)
-			render.Status(r, http.StatusNotFound)
-			render.JSON(w, r, resp.Error("not found"))
+			resp.RenderError(w, r, http.StatusNotFound, resp.Error("not found"))
+			return
+		}
+		if errors.Is(err, storage.ErrURLQuarantined) {
+			log.Info("url quarantined", "alias", alias)
+			renderQuarantined(w, log)
 			return
 		}
 		if err != nil {
 			log.Error("failed to get url", sl.Err(err))
-			render.Status(r, http.StatusInternalServerError)
-			render.JSON(w, r, resp.Error("internal error"))
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("internal error"))
 			return
 		}
 
+		class := botdetect.Classify(r)
+		if class == botdetect.Preview && previewGetter != nil {
+			link, err := previewGetter.GetURLPreview(r.Context(), domain, alias)
+			if err == nil {
+				recordClick(r.Context(), log, clickRecorder, eventPublisher, newClick(r, domain, alias, class), "")
+				renderPreview(w, r, log, link)
+				return
+			}
+			// the redirect is still a useful answer
+			log.Error("failed to get preview", sl.Err(err))
+		}
+
+		rules, err := rulesGetter.GetURLRules(r.Context(), domain, alias)
+		if err != nil {
+			// targeting is best effort, the default url is still valid
+			log.Error("failed to get rules", sl.Err(err))
+		}
+		matched := false
+		if len(rules) > 0 {
+			if target, ok := targeting.Match(rules, targeting.NewVisitor(r, countryResolver)); ok {
+				resURL = target
+				matched = true
+			}
+		}
+
+		visitorID, returning := visitorIDFromCookie(w, r)
+		click := newClick(r, domain, alias, class)
+		click.VisitorID = visitorID
+
+		// a cookie issued now may never come back
+		visitorKey := visitorID
+		if !returning {
+			visitorKey = visitors.Fingerprint(fingerprintKey, clientIP(r), r.UserAgent())
+		}
+
+		if !matched {
+			variants, err := variantsGetter.GetURLVariants(r.Context(), domain, alias)
+			if err != nil {
+				log.Error("failed to get variants", sl.Err(err))
+			}
+			if variant, ok := split.Pick(variants, visitorID+domain+"/"+alias); ok {
+				resURL = variant.URL
+				click.VariantID = variant.ID
+			}
+		}
+
+		recordClick(r.Context(), log, clickRecorder, eventPublisher, click, visitorKey)
+
 		log.Info("got url", slog.String("url", resURL))
 
 		// redirect to found url
 		http.Redirect(w, r, resURL, http.StatusFound)
 	}
 }
+
+var quarantinedPage = template.Must(template.New("quarantined").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex">
+<title>Link disabled</title>
+</head>
+<body>
+<h1>This link has been disabled</h1>
+<p>The short link was reported as malicious and is under review. It does not redirect anywhere until the review is finished.</p>
+</body>
+</html>
+`))
+
+// renderQuarantined serves the warning page shown instead of redirecting to a
+// quarantined link. The destination is deliberately not disclosed.
+func renderQuarantined(w http.ResponseWriter, log *slog.Logger) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusForbidden)
+
+	if err := quarantinedPage.Execute(w, nil); err != nil {
+		log.Error("failed to render quarantine page", sl.Err(err))
+	}
+}
+
+// newClick describes the click with where the visitor came from and what
+// they use.
+func newClick(r *http.Request, domain string, alias string, class string) models.Click {
+	agent := useragent.Parse(r.UserAgent())
+
+	return models.Click{
+		Domain:         domain,
+		Alias:          alias,
+		Class:          class,
+		Referrer:       referrer.Domain(r.Referer()),
+		Browser:        agent.Browser,
+		BrowserVersion: agent.BrowserVersion,
+		OS:             agent.OS,
+		Device:         agent.Device,
+	}
+}
+
+// recordClick stores the click and publishes it with the key the visitor is
+// counted by. Failures are only logged, stats must not break redirects.
+func recordClick(
+	ctx context.Context, log *slog.Logger, clickRecorder ClickRecorder, eventPublisher EventPublisher, click models.Click,
+	visitorKey string,
+) {
+	if err := clickRecorder.RecordClick(ctx, click); err != nil {
+		log.Error("failed to record click", sl.Err(err))
+	}
+
+	event := events.New(events.LinkClicked, click.Domain, click.Alias, events.ClickData{
+		VariantID:  click.VariantID,
+		VisitorID:  click.VisitorID,
+		VisitorKey: visitorKey,
+		Class:      click.Class,
+	})
+	if err := eventPublisher.Publish(ctx, event); err != nil {
+		log.Error("failed to publish event", sl.Err(err))
+	}
+}
+
+var previewPage = template.Must(template.New("preview").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex">
+<title>{{.Title}}</title>
+<meta property="og:type" content="website">
+<meta property="og:title" content="{{.Title}}">
+<meta property="og:url" content="{{.ShortURL}}">
+{{- if .Description}}
+<meta property="og:description" content="{{.Description}}">
+<meta name="description" content="{{.Description}}">
+{{- end}}
+{{- if .SiteName}}
+<meta property="og:site_name" content="{{.SiteName}}">
+{{- end}}
+{{- if .Image}}
+<meta property="og:image" content="{{.Image}}">
+<meta name="twitter:card" content="summary_large_image">
+{{- else}}
+<meta name="twitter:card" content="summary">
+{{- end}}
+</head>
+<body>
+<p><a href="{{.URL}}">{{.Title}}</a></p>
+</body>
+</html>
+`))
+
+// renderPreview serves the page shown to link preview fetchers. Title and
+// description set on the link win over those fetched from the destination.
+func renderPreview(w http.ResponseWriter, r *http.Request, log *slog.Logger, link models.URL) {
+	data := struct {
+		Title       string
+		Description string
+		SiteName    string
+		Image       string
+		URL         string
+		ShortURL    string
+	}{
+		Title:       link.Title,
+		Description: link.Description,
+		URL:         link.URL,
+		ShortURL:    shortURL(r, link),
+	}
+	if meta := link.Metadata; meta != nil {
+		if data.Title == "" {
+			data.Title = meta.Title
+		}
+		if data.Description == "" {
+			data.Description = meta.Description
+		}
+		data.SiteName = meta.SiteName
+		data.Image = meta.Image
+	}
+	if data.Title == "" {
+		data.Title = link.URL
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	if err := previewPage.Execute(w, data); err != nil {
+		log.Error("failed to render preview page", sl.Err(err))
+	}
+}
+
+// shortURL returns the short link, custom domains always use https.
+func shortURL(r *http.Request, link models.URL) string {
+	if link.Domain != "" {
+		return "https://" + link.Domain + "/" + link.Alias
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	return scheme + "://" + r.Host + "/" + link.Alias
+}
+
+// visitorIDFromCookie returns the sticky visitor id and whether the visitor
+// sent it, issuing a new one if the visitor has none yet.
+func visitorIDFromCookie(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if cookie, err := r.Cookie(visitorCookie); err == nil && cookie.Value != "" {
+		return cookie.Value, true
+	}
+
+	visitorID := random.NewRandomString(visitorIDLength)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     visitorCookie,
+		Value:    visitorID,
+		Path:     "/",
+		MaxAge:   visitorCookieMaxAge,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return visitorID, false
+}
+
+// clientIP is the address visitors without cookie are fingerprinted by.
+func clientIP(r *http.Request) string {
+	if ip := targeting.ClientIP(r); ip != nil {
+		return ip.String()
+	}
+
+	return r.RemoteAddr
+}
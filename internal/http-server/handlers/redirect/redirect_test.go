@@ -1,18 +1,23 @@
 package redirect_test
 
 import (
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
 	"url-shortener/internal/http-server/handlers/redirect"
 	"url-shortener/internal/http-server/handlers/redirect/mocks"
 	"url-shortener/internal/lib/api"
+	"url-shortener/internal/lib/botdetect"
+	"url-shortener/internal/lib/events"
 	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/models"
 	"url-shortener/internal/storage"
 )
 
@@ -25,6 +30,9 @@ func TestSaveHandler(t *testing.T) {
 		name      string
 		alias     string
 		url       string
+		rules     []models.Rule
+		variants  []models.Variant
+		expected  string
 		mockError error
 		respError string
 		code      int
@@ -42,6 +50,46 @@ func TestSaveHandler(t *testing.T) {
 			mockError: storage.ErrURLNotFound,
 			code:      http.StatusNotFound,
 		},
+		{
+			name:  "Matching rule",
+			alias: "test_alias",
+			url:   "https://www.google.com/",
+			rules: []models.Rule{
+				{Platform: "ios", TargetURL: "https://apps.apple.com/"},
+				{Platform: "other", TargetURL: "https://example.com/"},
+			},
+			expected: "https://example.com/",
+		},
+		{
+			name:  "No matching rule",
+			alias: "test_alias",
+			url:   "https://www.google.com/",
+			rules: []models.Rule{
+				{Platform: "android", TargetURL: "https://play.google.com/"},
+			},
+		},
+		{
+			name:  "Split variant",
+			alias: "test_alias",
+			url:   "https://www.google.com/",
+			variants: []models.Variant{
+				{ID: 1, URL: "https://example.com/a", Weight: 1},
+				{ID: 2, URL: "https://example.com/b", Weight: 0},
+			},
+			expected: "https://example.com/a",
+		},
+		{
+			name:  "Rule wins over split",
+			alias: "test_alias",
+			url:   "https://www.google.com/",
+			rules: []models.Rule{
+				{Platform: "other", TargetURL: "https://example.com/rule"},
+			},
+			variants: []models.Variant{
+				{ID: 1, URL: "https://example.com/a", Weight: 1},
+			},
+			expected: "https://example.com/rule",
+		},
 	}
 
 	for _, tc := range cases {
@@ -49,13 +97,33 @@ func TestSaveHandler(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			urlGetterMock := mocks.NewURLGetter(t)
 
+			rulesGetterMock := mocks.NewRulesGetter(t)
+			variantsGetterMock := mocks.NewVariantsGetter(t)
+			clickRecorderMock := mocks.NewClickRecorder(t)
+			eventPublisherMock := mocks.NewEventPublisher(t)
+
 			if tc.code != http.StatusBadRequest {
-				urlGetterMock.On("GetURL", tc.alias).
+				urlGetterMock.On("GetURL", mock.Anything, "", tc.alias).
 					Return(tc.url, tc.mockError).Once()
 			}
+			if tc.mockError == nil {
+				rulesGetterMock.On("GetURLRules", mock.Anything, "", tc.alias).
+					Return(tc.rules, nil).Once()
+				variantsGetterMock.On("GetURLVariants", mock.Anything, "", tc.alias).
+					Return(tc.variants, nil).Maybe()
+				clickRecorderMock.On("RecordClick", mock.Anything, mock.MatchedBy(func(c models.Click) bool {
+					return c.Alias == tc.alias && c.VisitorID != ""
+				})).Return(nil).Once()
+				eventPublisherMock.On("Publish", mock.Anything, mock.MatchedBy(func(e events.Event) bool {
+					return e.Type == events.LinkClicked && e.Alias == tc.alias
+				})).Return(nil).Once()
+			}
 
 			r := chi.NewRouter()
-			r.Get("/{alias}", redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock))
+			r.Get("/{alias}", redirect.New(
+				slogdiscard.NewDiscardLogger(), urlGetterMock, rulesGetterMock, variantsGetterMock, clickRecorderMock,
+				eventPublisherMock, nil, nil, nil,
+			))
 
 			ts := httptest.NewServer(r)
 			defer ts.Close()
@@ -69,8 +137,138 @@ func TestSaveHandler(t *testing.T) {
 				require.NoError(t, err)
 			}
 
+			expected := tc.expected
+			if expected == "" {
+				expected = tc.url
+			}
+
 			// Check the final URL after redirection.
-			assert.Equal(t, tc.url, redirectedToURL)
+			assert.Equal(t, expected, redirectedToURL)
 		})
 	}
 }
+
+func TestRedirectQuarantined(t *testing.T) {
+	urlGetterMock := mocks.NewURLGetter(t)
+	urlGetterMock.On("GetURL", mock.Anything, "", "bad").
+		Return("", fmt.Errorf("wrapped: %w", storage.ErrURLQuarantined)).Once()
+
+	r := chi.NewRouter()
+	r.Get("/{alias}", redirect.New(
+		slogdiscard.NewDiscardLogger(), urlGetterMock,
+		mocks.NewRulesGetter(t), mocks.NewVariantsGetter(t), mocks.NewClickRecorder(t),
+		mocks.NewEventPublisher(t), nil, mocks.NewPreviewGetter(t), nil,
+	))
+
+	req := httptest.NewRequest(http.MethodGet, "/bad", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.Empty(t, rr.Header().Get("Location"))
+	assert.Contains(t, rr.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, rr.Body.String(), "This link has been disabled")
+}
+
+func TestRedirectPreview(t *testing.T) {
+	urlGetterMock := mocks.NewURLGetter(t)
+	urlGetterMock.On("GetURL", mock.Anything, "", "abc").Return("https://example.com/post", nil).Twice()
+
+	previewGetterMock := mocks.NewPreviewGetter(t)
+	previewGetterMock.On("GetURLPreview", mock.Anything, "", "abc").Return(models.URL{
+		URL:   "https://example.com/post",
+		Alias: "abc",
+		Title: "Release notes",
+		Metadata: &models.PageMetadata{
+			Title:       "Fetched title",
+			Description: "What changed <this> week",
+			Image:       "https://example.com/cover.png",
+		},
+	}, nil).Once()
+
+	rulesGetterMock := mocks.NewRulesGetter(t)
+	rulesGetterMock.On("GetURLRules", mock.Anything, "", "abc").Return(nil, nil).Once()
+	variantsGetterMock := mocks.NewVariantsGetter(t)
+	variantsGetterMock.On("GetURLVariants", mock.Anything, "", "abc").Return(nil, nil).Once()
+
+	clickRecorderMock := mocks.NewClickRecorder(t)
+	clickRecorderMock.On("RecordClick", mock.Anything, mock.MatchedBy(func(c models.Click) bool {
+		return c.Class == botdetect.Preview
+	})).Return(nil).Once()
+	clickRecorderMock.On("RecordClick", mock.Anything, mock.MatchedBy(func(c models.Click) bool {
+		return c.Class == botdetect.Human
+	})).Return(nil).Once()
+
+	eventPublisherMock := mocks.NewEventPublisher(t)
+	eventPublisherMock.On("Publish", mock.Anything, mock.Anything).Return(nil).Twice()
+
+	r := chi.NewRouter()
+	r.Get("/{alias}", redirect.New(
+		slogdiscard.NewDiscardLogger(), urlGetterMock, rulesGetterMock, variantsGetterMock, clickRecorderMock,
+		eventPublisherMock, nil, previewGetterMock, nil,
+	))
+
+	req := httptest.NewRequest(http.MethodGet, "/abc", nil)
+	req.Header.Set("User-Agent", "Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Empty(t, rr.Header().Get("Location"))
+	body := rr.Body.String()
+	assert.Contains(t, body, `<meta property="og:title" content="Release notes">`)
+	assert.Contains(t, body, `<meta property="og:description" content="What changed &lt;this&gt; week">`)
+	assert.Contains(t, body, `<meta property="og:image" content="https://example.com/cover.png">`)
+
+	// people are still redirected
+	req = httptest.NewRequest(http.MethodGet, "/abc", nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64; rv:120.0) Gecko/20100101 Firefox/120.0")
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.Equal(t, "https://example.com/post", rr.Header().Get("Location"))
+}
+
+func TestRedirectVisitorKey(t *testing.T) {
+	urlGetterMock := mocks.NewURLGetter(t)
+	rulesGetterMock := mocks.NewRulesGetter(t)
+	variantsGetterMock := mocks.NewVariantsGetter(t)
+	clickRecorderMock := mocks.NewClickRecorder(t)
+	eventPublisherMock := mocks.NewEventPublisher(t)
+
+	urlGetterMock.On("GetURL", mock.Anything, "", "abc").Return("https://example.com/", nil)
+	rulesGetterMock.On("GetURLRules", mock.Anything, "", "abc").Return(nil, nil)
+	variantsGetterMock.On("GetURLVariants", mock.Anything, "", "abc").Return(nil, nil)
+	clickRecorderMock.On("RecordClick", mock.Anything, mock.Anything).Return(nil)
+
+	var keys []string
+	eventPublisherMock.On("Publish", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		keys = append(keys, args.Get(1).(events.Event).Data.(events.ClickData).VisitorKey)
+	}).Return(nil)
+
+	r := chi.NewRouter()
+	r.Get("/{alias}", redirect.New(
+		slogdiscard.NewDiscardLogger(), urlGetterMock, rulesGetterMock, variantsGetterMock, clickRecorderMock,
+		eventPublisherMock, nil, nil, []byte("key"),
+	))
+
+	click := func(cookie string) {
+		req := httptest.NewRequest(http.MethodGet, "/abc", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		req.Header.Set("User-Agent", "Mozilla/5.0")
+		if cookie != "" {
+			req.AddCookie(&http.Cookie{Name: "vid", Value: cookie})
+		}
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// cookieless clicks get a new visitor id each time but count once
+	click("")
+	click("")
+	click("returning")
+
+	require.Len(t, keys, 3)
+	assert.Equal(t, keys[0], keys[1])
+	assert.Equal(t, "returning", keys[2])
+}
@@ -1,13 +1,17 @@
 package register
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"io"
 	"log/slog"
 	"net/http"
 	ssoGrpc "url-shortener/internal/clients/sso/grpc"
 	"url-shortener/internal/config"
+	"url-shortener/internal/lib/audit"
 	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/models"
 	"url-shortener/internal/lib/logger/sl"
 
 	"github.com/go-chi/chi/v5/middleware"
@@ -23,7 +27,14 @@ type Request struct {
 	Password string `json:"password" validate:"required"`
 }
 
-func New(log *slog.Logger, ssoClient *ssoGrpc.Client, cfg *config.AppConfig) http.HandlerFunc {
+// AuditRecorder records register attempts in the audit log.
+//
+//go:generate go run github.com/vektra/mockery/v2@latest --name=AuditRecorder
+type AuditRecorder interface {
+	RecordAudit(ctx context.Context, entry models.AuditEntry) error
+}
+
+func New(log *slog.Logger, ssoClient *ssoGrpc.Client, cfg *config.AppConfig, auditRecorder AuditRecorder) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.register.New"
 		log := log.With(
@@ -37,21 +48,18 @@ func New(log *slog.Logger, ssoClient *ssoGrpc.Client, cfg *config.AppConfig) htt
 		if err != nil {
 			if errors.Is(err, io.EOF) {
 				log.Error("request body is empty")
-				render.Status(r, http.StatusBadRequest)
-				render.JSON(w, r, resp.Error("empty request"))
+				resp.RenderError(w, r, http.StatusBadRequest, resp.Error("empty request"))
 				return
 			}
 			log.Error("failed to decode request body", sl.Err(err))
-			render.Status(r, http.StatusBadRequest)
-			render.JSON(w, r, resp.Error("failed to decode request"))
+			resp.RenderError(w, r, http.StatusBadRequest, resp.Error("failed to decode request"))
 			return
 		}
 
 		if err := validator.New().Struct(req); err != nil {
 			validateErr := err.(validator.ValidationErrors)
 			log.Error("invalid request", sl.Err(err))
-			render.Status(r, http.StatusBadRequest) 
-			render.JSON(w, r, resp.ValidationError(validateErr))
+			resp.RenderError(w, r, http.StatusBadRequest, resp.ValidationError(validateErr))
 			return
 		}
 
@@ -60,21 +68,22 @@ func New(log *slog.Logger, ssoClient *ssoGrpc.Client, cfg *config.AppConfig) htt
 			st, ok := status.FromError(err)
 			if ok && st.Code() == codes.AlreadyExists {
 				log.Error("user already exists")
-				render.Status(r, http.StatusConflict)
-				render.JSON(w, r, resp.Error("user already exists"))
+				recordAttempt(r.Context(), log, auditRecorder, req.Email, "user already exists")
+				resp.RenderError(w, r, http.StatusConflict, resp.Error("user already exists"))
 				return
 			}
 			log.Error("failed to register", sl.Err(err))
-			render.Status(r, http.StatusInternalServerError)
-			render.JSON(w, r, resp.Error("failed to register"))
+			recordAttempt(r.Context(), log, auditRecorder, req.Email, "sso error")
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("failed to register"))
 			return
 		}
 
+		recordAttempt(r.Context(), log, auditRecorder, req.Email, "")
+
 		token, err := ssoClient.Login(r.Context(), req.Email, req.Password, cfg.Clients.SSO.AppId)
 		if err != nil {
 			log.Error("failed to login", sl.Err(err))
-			render.Status(r, http.StatusInternalServerError)
-			render.JSON(w, r, resp.Error("failed to login"))
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("failed to login"))
 			return
 		}
 
@@ -92,4 +101,21 @@ func New(log *slog.Logger, ssoClient *ssoGrpc.Client, cfg *config.AppConfig) htt
 		render.Status(r, http.StatusOK)
 		render.JSON(w, r, resp.OK())
 	}
-}
\ No newline at end of file
+}
+
+// recordAttempt writes the register attempt to the audit log. Failures are only
+// logged so that auditing can't lock users out.
+func recordAttempt(ctx context.Context, log *slog.Logger, auditRecorder AuditRecorder, email string, reason string) {
+	entry := models.AuditEntry{
+		Email:  email,
+		Action: audit.ActionRegister,
+	}
+	if reason != "" {
+		entry.Action = audit.ActionRegisterFailed
+		entry.After, _ = json.Marshal(map[string]string{"reason": reason})
+	}
+
+	if err := auditRecorder.RecordAudit(ctx, entry); err != nil {
+		log.Error("failed to record audit", sl.Err(err))
+	}
+}
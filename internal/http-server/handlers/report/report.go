@@ -0,0 +1,107 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+
+	mwDomain "url-shortener/internal/http-server/middleware/domain"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/targeting"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+type Request struct {
+	Reason  string `json:"reason" validate:"required,oneof=phishing malware spam other"`
+	Details string `json:"details,omitempty" validate:"max=2000"`
+	Email   string `json:"email,omitempty" validate:"omitempty,email"`
+}
+
+type Response struct {
+	resp.Response
+	ID int64 `json:"id,omitempty"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=ReportSaver
+type ReportSaver interface {
+	SaveReport(ctx context.Context, domain string, alias string, report models.Report) (int64, error)
+}
+
+// New files an abuse report against the alias. It is public, the alias is
+// looked up on the domain resolved by the domain middleware.
+func New(log *slog.Logger, reportSaver ReportSaver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.report.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		alias := chi.URLParam(r, "alias")
+		domain, _ := r.Context().Value(mwDomain.DomainContextKey).(string)
+
+		var req Request
+
+		err := render.DecodeJSON(r.Body, &req)
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+			resp.RenderError(w, r, http.StatusBadRequest, resp.Error("empty request"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			resp.RenderError(w, r, http.StatusBadRequest, resp.Error("failed to decode request"))
+			return
+		}
+
+		req.Reason = strings.ToLower(strings.TrimSpace(req.Reason))
+
+		if err := validator.New().Struct(req); err != nil {
+			var validateErr validator.ValidationErrors
+			errors.As(err, &validateErr)
+			log.Error("invalid request", sl.Err(err))
+			resp.RenderError(w, r, http.StatusBadRequest, resp.ValidationError(validateErr))
+			return
+		}
+
+		report := models.Report{
+			Reason:        req.Reason,
+			Details:       req.Details,
+			ReporterEmail: req.Email,
+		}
+		if ip := targeting.ClientIP(r); ip != nil {
+			report.ReporterIP = ip.String()
+		}
+
+		id, err := reportSaver.SaveReport(r.Context(), domain, alias, report)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("url not found", slog.String("alias", alias))
+			resp.RenderError(w, r, http.StatusNotFound, resp.Error("not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to save report", sl.Err(err))
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("failed to save report"))
+			return
+		}
+
+		log.Info("url reported", slog.Int64("id", id), slog.String("alias", alias), slog.String("reason", req.Reason))
+
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			ID:       id,
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package settings
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+
+	"url-shortener/internal/http-server/middleware/auth"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/models"
+)
+
+// Request replaces the settings of the user.
+type Request struct {
+	ReuseExisting *bool `json:"reuse_existing" validate:"required"`
+}
+
+type Response struct {
+	resp.Response
+	Settings models.UserSettings `json:"settings"`
+}
+
+type SettingsStorage interface {
+	GetUserSettings(ctx context.Context, userID int64) (models.UserSettings, error)
+	SaveUserSettings(ctx context.Context, settings models.UserSettings) error
+}
+
+// NewGet returns the settings of the user.
+func NewGet(log *slog.Logger, settingsStorage SettingsStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.settings.NewGet"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := r.Context().Value(auth.UserIDContextKey).(int64)
+		if !ok {
+			log.Error("user_id not found in context")
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("user_id not found in token"))
+			return
+		}
+
+		settings, err := settingsStorage.GetUserSettings(r.Context(), userID)
+		if err != nil {
+			log.Error("failed to get settings", sl.Err(err))
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("failed to get settings"))
+			return
+		}
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Settings: settings,
+		})
+	}
+}
+
+// NewUpdate replaces the settings of the user.
+func NewUpdate(log *slog.Logger, settingsStorage SettingsStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.settings.NewUpdate"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := r.Context().Value(auth.UserIDContextKey).(int64)
+		if !ok {
+			log.Error("user_id not found in context")
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("user_id not found in token"))
+			return
+		}
+
+		var req Request
+		err := render.DecodeJSON(r.Body, &req)
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+			resp.RenderError(w, r, http.StatusBadRequest, resp.Error("empty request"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			resp.RenderError(w, r, http.StatusBadRequest, resp.Error("failed to decode request"))
+			return
+		}
+
+		if err := validator.New().Struct(req); err != nil {
+			var validateErr validator.ValidationErrors
+			errors.As(err, &validateErr)
+			log.Error("invalid request", sl.Err(err))
+			resp.RenderError(w, r, http.StatusBadRequest, resp.ValidationError(validateErr))
+			return
+		}
+
+		settings := models.UserSettings{UserID: userID, ReuseExisting: *req.ReuseExisting}
+		if err := settingsStorage.SaveUserSettings(r.Context(), settings); err != nil {
+			log.Error("failed to save settings", sl.Err(err))
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("failed to save settings"))
+			return
+		}
+
+		log.Info("settings saved", slog.Bool("reuse_existing", settings.ReuseExisting))
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Settings: settings,
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package tags
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+
+	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/lib/api"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/tags"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+// RenameRequest is the new name of a tag.
+type RenameRequest struct {
+	Name string `json:"name" validate:"required,max=50"`
+}
+
+// MergeRequest replaces Tags with Into on all links of the user.
+type MergeRequest struct {
+	Tags []string `json:"tags" validate:"required,min=1,max=20,dive,required,max=50"`
+	Into string   `json:"into" validate:"required,max=50"`
+}
+
+type Response struct {
+	resp.Response
+	Tags    []models.Tag    `json:"tags,omitempty"`
+	Folders []models.Folder `json:"folders,omitempty"`
+	// Affected is the number of tag assignments changed by a rename or merge.
+	Affected int64 `json:"affected,omitempty"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=TagStorage
+type TagStorage interface {
+	GetUserTags(ctx context.Context, userID int64) ([]models.Tag, error)
+	GetUserFolders(ctx context.Context, userID int64) ([]models.Folder, error)
+	RenameTag(ctx context.Context, userID int64, from string, to string) (int64, error)
+	MergeTags(ctx context.Context, userID int64, from []string, into string) (int64, error)
+}
+
+// NewList returns tags used on links of the user.
+func NewList(log *slog.Logger, tagStorage TagStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.tags.NewList"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := userIDFrom(w, r, log)
+		if !ok {
+			return
+		}
+
+		userTags, err := tagStorage.GetUserTags(r.Context(), userID)
+		if err != nil {
+			log.Error("failed to get tags", sl.Err(err))
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("failed to get tags"))
+			return
+		}
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Tags:     userTags,
+		})
+	}
+}
+
+// NewListFolders returns folders holding links of the user.
+func NewListFolders(log *slog.Logger, tagStorage TagStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.tags.NewListFolders"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := userIDFrom(w, r, log)
+		if !ok {
+			return
+		}
+
+		folders, err := tagStorage.GetUserFolders(r.Context(), userID)
+		if err != nil {
+			log.Error("failed to get folders", sl.Err(err))
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("failed to get folders"))
+			return
+		}
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Folders:  folders,
+		})
+	}
+}
+
+// NewRename renames the tag in the path on all links of the user.
+func NewRename(log *slog.Logger, tagStorage TagStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.tags.NewRename"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := userIDFrom(w, r, log)
+		if !ok {
+			return
+		}
+
+		var req RenameRequest
+		if !decode(w, r, log, &req) {
+			return
+		}
+
+		// URLFormat takes the end of dotted names (node.js) for an extension
+		from := tags.Name(api.URLParam(r, "name"))
+		to := tags.Name(req.Name)
+
+		if from == to {
+			render.JSON(w, r, Response{Response: resp.OK()})
+			return
+		}
+
+		affected, err := tagStorage.RenameTag(r.Context(), userID, from, to)
+		if errors.Is(err, storage.ErrTagExists) {
+			log.Info("tag exists", slog.String("tag", to))
+			resp.RenderError(w, r, http.StatusConflict, resp.Error("tag already exists, merge the tags instead"))
+			return
+		}
+		if err != nil {
+			renderStorageError(w, r, log, err, "failed to rename tag")
+			return
+		}
+
+		log.Info("tag renamed", slog.String("from", from), slog.String("to", to), slog.Int64("affected", affected))
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Affected: affected,
+		})
+	}
+}
+
+// NewMerge replaces the given tags with a single one on all links of the user.
+func NewMerge(log *slog.Logger, tagStorage TagStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.tags.NewMerge"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := userIDFrom(w, r, log)
+		if !ok {
+			return
+		}
+
+		var req MergeRequest
+		if !decode(w, r, log, &req) {
+			return
+		}
+
+		into := tags.Name(req.Into)
+
+		affected, err := tagStorage.MergeTags(r.Context(), userID, tags.Normalize(req.Tags), into)
+		if err != nil {
+			renderStorageError(w, r, log, err, "failed to merge tags")
+			return
+		}
+
+		log.Info("tags merged", slog.Any("tags", req.Tags), slog.String("into", into), slog.Int64("affected", affected))
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Affected: affected,
+		})
+	}
+}
+
+func userIDFrom(w http.ResponseWriter, r *http.Request, log *slog.Logger) (int64, bool) {
+	userID, ok := r.Context().Value(auth.UserIDContextKey).(int64)
+	if !ok {
+		log.Error("user_id not found in context")
+		resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("user_id not found in token"))
+	}
+
+	return userID, ok
+}
+
+func decode(w http.ResponseWriter, r *http.Request, log *slog.Logger, req any) bool {
+	err := render.DecodeJSON(r.Body, req)
+	if errors.Is(err, io.EOF) {
+		log.Error("request body is empty")
+		resp.RenderError(w, r, http.StatusBadRequest, resp.Error("empty request"))
+		return false
+	}
+	if err != nil {
+		log.Error("failed to decode request body", sl.Err(err))
+		resp.RenderError(w, r, http.StatusBadRequest, resp.Error("failed to decode request"))
+		return false
+	}
+
+	if err := validator.New().Struct(req); err != nil {
+		var validateErr validator.ValidationErrors
+		errors.As(err, &validateErr)
+		log.Error("invalid request", sl.Err(err))
+		resp.RenderError(w, r, http.StatusBadRequest, resp.ValidationError(validateErr))
+		return false
+	}
+
+	return true
+}
+
+func renderStorageError(w http.ResponseWriter, r *http.Request, log *slog.Logger, err error, msg string) {
+	switch {
+	case errors.Is(err, storage.ErrTagNotFound):
+		log.Info("tag not found", sl.Err(err))
+		resp.RenderError(w, r, http.StatusNotFound, resp.Error("tag not found"))
+	default:
+		log.Error(msg, sl.Err(err))
+		resp.RenderError(w, r, http.StatusInternalServerError, resp.Error(msg))
+	}
+}
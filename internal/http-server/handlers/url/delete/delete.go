@@ -13,7 +13,9 @@ import (
 
 	ssoGrpc "url-shortener/internal/clients/sso/grpc"
 	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/lib/api"
 	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/events"
 	"url-shortener/internal/lib/logger/sl"
 	"url-shortener/internal/storage"
 )
@@ -24,10 +26,17 @@ type Response struct {
 
 //go:generate go run github.com/vektra/mockery/v2@latest --name=URLDeleter
 type URLDeleter interface {
-	DeleteURL(ctx context.Context, alias string, userID int64, isAdmin bool) error
+	DeleteURL(ctx context.Context, domain string, alias string, userID int64, isAdmin bool) error
 }
 
-func New(log *slog.Logger, urlDeleter URLDeleter, ssoClient *ssoGrpc.Client) http.HandlerFunc {
+// EventPublisher publishes link events to webhooks.
+//
+//go:generate go run github.com/vektra/mockery/v2@latest --name=EventPublisher
+type EventPublisher interface {
+	Publish(ctx context.Context, event events.Event) error
+}
+
+func New(log *slog.Logger, urlDeleter URLDeleter, ssoClient *ssoGrpc.Client, eventPublisher EventPublisher) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.url.delete.New"
 
@@ -37,43 +46,43 @@ func New(log *slog.Logger, urlDeleter URLDeleter, ssoClient *ssoGrpc.Client) htt
 		)
 
 		alias := chi.URLParam(r, "alias")
+		domain := api.DomainParam(r)
 
 		userID, ok := r.Context().Value(auth.UserIDContextKey).(int64)
 		if !ok {
 			log.Error("user_id not found in context")
-			render.Status(r, http.StatusInternalServerError)
-			render.JSON(w, r, resp.Error("user_id not found in token"))
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("user_id not found in token"))
 			return
 		}
 
 		isAdmin, err := ssoClient.IsAdmin(r.Context(), userID)
 		if err != nil {
 			log.Error("failed to check if user is admin", sl.Err(err))
-			render.Status(r, http.StatusInternalServerError)
-			render.JSON(w, r, resp.Error("failed to check if user is admin"))
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("failed to check if user is admin"))
 			return
 		}
 
-		err = urlDeleter.DeleteURL(r.Context(), alias, userID, isAdmin)
+		err = urlDeleter.DeleteURL(r.Context(), domain, alias, userID, isAdmin)
 		if err != nil {
 			if errors.Is(err, storage.ErrURLNotFound) {
 				log.Error("url not found", sl.Err(err))
-				render.Status(r, http.StatusNotFound)
-				render.JSON(w, r, resp.Error("url not found"))
+				resp.RenderError(w, r, http.StatusNotFound, resp.Error("url not found"))
 				return
 			}
 			if errors.Is(err, storage.ErrURLNotOwned) {
 				log.Error("url not owned", sl.Err(err))
-				render.Status(r, http.StatusForbidden)
-				render.JSON(w, r, resp.Error("url not owned"))
+				resp.RenderError(w, r, http.StatusForbidden, resp.Error("url not owned"))
 				return
 			}
 			log.Error("failed to delete url", sl.Err(err))
-			render.Status(r, http.StatusInternalServerError)
-			render.JSON(w, r, resp.Error("failed to delete url"))
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("failed to delete url"))
 			return
 		}
 
+		if err := eventPublisher.Publish(r.Context(), events.New(events.LinkDeleted, domain, alias, nil)); err != nil {
+			log.Error("failed to publish event", sl.Err(err))
+		}
+
 		render.JSON(w, r, Response{
 			Response: resp.OK(),
 		})
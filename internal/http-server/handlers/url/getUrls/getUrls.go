@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"log/slog"
 
@@ -13,6 +15,7 @@ import (
 	"url-shortener/internal/http-server/middleware/auth"
 	resp "url-shortener/internal/lib/api/response"
 	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/tags"
 	"url-shortener/internal/models"
 	"url-shortener/internal/storage"
 )
@@ -23,7 +26,7 @@ type Response struct {
 }
 
 type URLsGetter interface {
-	GetUserURLs(ctx context.Context, userID int64) ([]models.URL, error)
+	GetUserURLs(ctx context.Context, userID int64, filter models.URLFilter) ([]models.URL, error)
 }
 
 func New(log *slog.Logger, urlsGetter URLsGetter) http.HandlerFunc {
@@ -38,13 +41,27 @@ func New(log *slog.Logger, urlsGetter URLsGetter) http.HandlerFunc {
 		userID, ok := r.Context().Value(auth.UserIDContextKey).(int64)
 		if !ok {
 			log.Error("user_id not found in context")
-			render.Status(r, http.StatusInternalServerError)
-			render.JSON(w, r, resp.Error("user_id not found in token"))
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("user_id not found in token"))
 			return
 		}
 
 
-		urls, err := urlsGetter.GetUserURLs(r.Context(), userID)
+		// ?tag=, ?folder= and ?broken=true narrow the list down
+		filter := models.URLFilter{
+			Tag:    tags.Name(r.URL.Query().Get("tag")),
+			Folder: strings.TrimSpace(r.URL.Query().Get("folder")),
+		}
+		if v := r.URL.Query().Get("broken"); v != "" {
+			broken, err := strconv.ParseBool(v)
+			if err != nil {
+				log.Info("invalid broken filter", slog.String("broken", v))
+				resp.RenderError(w, r, http.StatusBadRequest, resp.Error("broken must be true or false"))
+				return
+			}
+			filter.Broken = broken
+		}
+
+		urls, err := urlsGetter.GetUserURLs(r.Context(), userID, filter)
 		if err != nil {
 			if errors.Is(err, storage.ErrUserURLsNotFound) {
 				log.Info("user urls not found")
@@ -55,8 +72,7 @@ func New(log *slog.Logger, urlsGetter URLsGetter) http.HandlerFunc {
 				return
 			}
 			log.Error("failed to get user urls", sl.Err(err))
-			render.Status(r, http.StatusInternalServerError)
-			render.JSON(w, r, resp.Error("failed to get user urls"))
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("failed to get user urls"))
 			return
 		}
 
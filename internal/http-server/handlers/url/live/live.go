@@ -0,0 +1,221 @@
+package live
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+
+	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/lib/api"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/events"
+	"url-shortener/internal/lib/hub"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+// Click is the data of a click event on the stream.
+type Click struct {
+	Domain     string    `json:"domain,omitempty"`
+	Alias      string    `json:"alias"`
+	VariantID  int64     `json:"variant_id,omitempty"`
+	VisitorID  string    `json:"visitor_id,omitempty"`
+	Class      string    `json:"class,omitempty"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// Dropped is the data of a dropped event, sent when the client didn't keep
+// up and Count clicks were skipped.
+type Dropped struct {
+	Count int64 `json:"count"`
+}
+
+// Subscriber hands out subscriptions to link events.
+type Subscriber interface {
+	Subscribe(filter hub.Filter) *hub.Subscription
+}
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=OwnerGetter
+type OwnerGetter interface {
+	GetURLOwner(ctx context.Context, domain string, alias string) (int64, error)
+}
+
+// Options control the streams.
+type Options struct {
+	// Heartbeat is how often a heartbeat event is sent while there are no
+	// clicks, keeping proxies from closing the connection.
+	Heartbeat time.Duration
+	// MaxDuration closes the stream after this long, clients are expected
+	// to reconnect. Zero means no limit.
+	MaxDuration time.Duration
+}
+
+// New streams clicks on the alias as server-sent events. Links on custom
+// domains are addressed with the "domain" query parameter.
+func New(log *slog.Logger, subscriber Subscriber, ownerGetter OwnerGetter, opts Options) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.live.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := r.Context().Value(auth.UserIDContextKey).(int64)
+		if !ok {
+			log.Error("user_id not found in context")
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("user_id not found in token"))
+			return
+		}
+
+		domain := api.DomainParam(r)
+		alias := chi.URLParam(r, "alias")
+
+		ownerID, err := ownerGetter.GetURLOwner(r.Context(), domain, alias)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("url not found", slog.String("alias", alias))
+			resp.RenderError(w, r, http.StatusNotFound, resp.Error("url not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get url owner", sl.Err(err))
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("internal error"))
+			return
+		}
+		if ownerID != userID {
+			log.Info("url not owned", slog.String("alias", alias))
+			resp.RenderError(w, r, http.StatusForbidden, resp.Error("url not owned"))
+			return
+		}
+
+		sub := subscriber.Subscribe(hub.Filter{Type: events.LinkClicked, Domain: domain, Alias: alias})
+		defer sub.Close()
+
+		stream(log, w, r, sub, opts)
+	}
+}
+
+// NewUser streams clicks on all links of the user as server-sent events.
+func NewUser(log *slog.Logger, subscriber Subscriber, opts Options) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.live.NewUser"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := r.Context().Value(auth.UserIDContextKey).(int64)
+		if !ok {
+			log.Error("user_id not found in context")
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("user_id not found in token"))
+			return
+		}
+
+		sub := subscriber.Subscribe(hub.Filter{Type: events.LinkClicked, UserID: userID})
+		defer sub.Close()
+
+		stream(log, w, r, sub, opts)
+	}
+}
+
+// stream writes events of sub until the client goes away, the hub is closed
+// on shutdown or MaxDuration passes. The server's write timeout is pushed back before every write, so
+// only a client that stops reading gets cut off.
+func stream(log *slog.Logger, w http.ResponseWriter, r *http.Request, sub *hub.Subscription, opts Options) {
+	heartbeat := opts.Heartbeat
+	if heartbeat <= 0 {
+		heartbeat = 15 * time.Second
+	}
+
+	ctx := r.Context()
+	if opts.MaxDuration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.MaxDuration)
+		defer cancel()
+	}
+
+	rc := http.NewResponseController(w)
+
+	send := func(event string, data any) error {
+		if err := api.ExtendWriteDeadline(rc, 2*heartbeat); err != nil {
+			return err
+		}
+
+		b, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, b); err != nil {
+			return err
+		}
+
+		return rc.Flush()
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// keeps nginx from buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if err := send("heartbeat", struct{}{}); err != nil {
+		log.Info("stream closed", sl.Err(err))
+		return
+	}
+
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+
+	for {
+		var err error
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-sub.Done():
+			return
+		case event := <-sub.Events():
+			if n := sub.Dropped(); n > 0 {
+				if err = send("dropped", Dropped{Count: n}); err != nil {
+					break
+				}
+			}
+			err = send("click", clickOf(event))
+		case <-ticker.C:
+			if n := sub.Dropped(); n > 0 {
+				err = send("dropped", Dropped{Count: n})
+			} else {
+				err = send("heartbeat", struct{}{})
+			}
+		}
+
+		if err != nil {
+			log.Info("stream closed", sl.Err(err))
+			return
+		}
+	}
+}
+
+func clickOf(event events.Event) Click {
+	click := Click{
+		Domain:     event.Domain,
+		Alias:      event.Alias,
+		OccurredAt: event.OccurredAt,
+	}
+	if data, ok := event.Data.(events.ClickData); ok {
+		click.VariantID = data.VariantID
+		click.VisitorID = data.VisitorID
+		click.Class = data.Class
+	}
+
+	return click
+}
@@ -0,0 +1,138 @@
+package qr
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+
+	mwDomain "url-shortener/internal/http-server/middleware/domain"
+	"url-shortener/internal/lib/api"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/qr"
+	"url-shortener/internal/storage"
+)
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=URLGetter
+type URLGetter interface {
+	GetURL(ctx context.Context, domain string, alias string) (string, error)
+}
+
+// New renders a QR code pointing to the short url of the alias.
+//
+// The format is taken from the "format" query parameter or from the path
+// extension (/{alias}.svg) and defaults to PNG. Size, margin and error
+// correction level are set with the "size", "margin" and "level" parameters.
+// Links on custom domains are taken from the "domain" query parameter or the
+// request host and always use https. For the default domain the short url is
+// built from baseURL, or from the request host if baseURL is empty.
+func New(log *slog.Logger, urlGetter URLGetter, baseURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.qr.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		alias := chi.URLParam(r, "alias")
+		domain := api.DomainParam(r)
+		if domain == "" {
+			domain, _ = r.Context().Value(mwDomain.DomainContextKey).(string)
+		}
+
+		format, opts, err := parseOptions(r)
+		if err != nil {
+			log.Info("invalid qr options", sl.Err(err))
+			resp.RenderError(w, r, http.StatusBadRequest, resp.Error(err.Error()))
+			return
+		}
+
+		_, err = urlGetter.GetURL(r.Context(), domain, alias)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("url not found", slog.String("alias", alias))
+			resp.RenderError(w, r, http.StatusNotFound, resp.Error("not found"))
+			return
+		}
+		if errors.Is(err, storage.ErrURLQuarantined) {
+			log.Info("url quarantined", slog.String("alias", alias))
+			resp.RenderError(w, r, http.StatusForbidden, resp.Error("url quarantined"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get url", sl.Err(err))
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("internal error"))
+			return
+		}
+
+		// render into a buffer first so encoding errors still produce a JSON response
+		var buf bytes.Buffer
+		if err := qr.Render(&buf, shortURL(r, baseURL, domain, alias), format, opts); err != nil {
+			log.Error("failed to render qr code", sl.Err(err))
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("failed to render qr code"))
+			return
+		}
+
+		w.Header().Set("Content-Type", qr.ContentType(format))
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+		_, _ = w.Write(buf.Bytes())
+	}
+}
+
+func parseOptions(r *http.Request) (string, qr.Options, error) {
+	query := r.URL.Query()
+	opts := qr.DefaultOptions()
+
+	format := strings.ToLower(query.Get("format"))
+	if format == "" {
+		format, _ = r.Context().Value(middleware.URLFormatCtxKey).(string)
+	}
+	if format == "" {
+		format = qr.FormatPNG
+	}
+	if format != qr.FormatPNG && format != qr.FormatSVG {
+		return "", opts, qr.ErrUnknownFormat
+	}
+
+	if v := query.Get("size"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil {
+			return "", opts, qr.ErrInvalidSize
+		}
+		opts.Size = size
+	}
+	if v := query.Get("margin"); v != "" {
+		margin, err := strconv.Atoi(v)
+		if err != nil {
+			return "", opts, qr.ErrInvalidMargin
+		}
+		opts.Margin = margin
+	}
+	if v := query.Get("level"); v != "" {
+		opts.Level = strings.ToUpper(v)
+	}
+
+	return format, opts, opts.Validate()
+}
+
+func shortURL(r *http.Request, baseURL string, domain string, alias string) string {
+	if domain != "" {
+		return "https://" + domain + "/" + alias
+	}
+	if baseURL == "" {
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		baseURL = scheme + "://" + r.Host
+	}
+
+	return strings.TrimSuffix(baseURL, "/") + "/" + alias
+}
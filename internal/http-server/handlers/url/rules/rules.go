@@ -0,0 +1,273 @@
+package rules
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+
+	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/lib/api"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/urlpolicy"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+type Request struct {
+	Position  int    `json:"position" validate:"gte=0"`
+	Platform  string `json:"platform,omitempty" validate:"omitempty,oneof=ios android windows macos linux other"`
+	Language  string `json:"language,omitempty" validate:"omitempty,bcp47_language_tag"`
+	Country   string `json:"country,omitempty" validate:"omitempty,iso3166_1_alpha2"`
+	TimeFrom  string `json:"time_from,omitempty" validate:"omitempty,datetime=15:04"`
+	TimeTo    string `json:"time_to,omitempty" validate:"omitempty,datetime=15:04"`
+	TargetURL string `json:"target_url" validate:"required,url"`
+}
+
+type Response struct {
+	resp.Response
+	ID    int64         `json:"id,omitempty"`
+	Rules []models.Rule `json:"rules,omitempty"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=RulesStorage
+type RulesStorage interface {
+	GetUserURLRules(ctx context.Context, domain string, alias string, userID int64) ([]models.Rule, error)
+	SaveURLRule(ctx context.Context, domain string, alias string, userID int64, rule models.Rule) (int64, error)
+	UpdateURLRule(ctx context.Context, domain string, alias string, userID int64, rule models.Rule) error
+	DeleteURLRule(ctx context.Context, domain string, alias string, userID int64, ruleID int64) error
+}
+
+// URLChecker checks destination urls against the url policy.
+//
+//go:generate go run github.com/vektra/mockery/v2@latest --name=URLChecker
+type URLChecker interface {
+	Check(rawURL string) []urlpolicy.Violation
+}
+
+// NewList returns targeting rules of the link in evaluation order.
+// Links on custom domains are addressed with the "domain" query parameter.
+func NewList(log *slog.Logger, rulesStorage RulesStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.rules.NewList"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := r.Context().Value(auth.UserIDContextKey).(int64)
+		if !ok {
+			log.Error("user_id not found in context")
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("user_id not found in token"))
+			return
+		}
+
+		domain := api.DomainParam(r)
+		alias := chi.URLParam(r, "alias")
+
+		rules, err := rulesStorage.GetUserURLRules(r.Context(), domain, alias, userID)
+		if err != nil {
+			renderStorageError(w, r, log, err, "failed to get rules")
+			return
+		}
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Rules:    rules,
+		})
+	}
+}
+
+// NewCreate adds a targeting rule to the link.
+func NewCreate(log *slog.Logger, rulesStorage RulesStorage, urlChecker URLChecker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.rules.NewCreate"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := r.Context().Value(auth.UserIDContextKey).(int64)
+		if !ok {
+			log.Error("user_id not found in context")
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("user_id not found in token"))
+			return
+		}
+
+		domain := api.DomainParam(r)
+		alias := chi.URLParam(r, "alias")
+
+		rule, ok := decodeRule(w, r, log, urlChecker)
+		if !ok {
+			return
+		}
+
+		id, err := rulesStorage.SaveURLRule(r.Context(), domain, alias, userID, rule)
+		if err != nil {
+			renderStorageError(w, r, log, err, "failed to add rule")
+			return
+		}
+
+		log.Info("rule added", slog.Int64("id", id))
+
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			ID:       id,
+		})
+	}
+}
+
+// NewUpdate replaces an existing targeting rule of the link.
+func NewUpdate(log *slog.Logger, rulesStorage RulesStorage, urlChecker URLChecker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.rules.NewUpdate"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := r.Context().Value(auth.UserIDContextKey).(int64)
+		if !ok {
+			log.Error("user_id not found in context")
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("user_id not found in token"))
+			return
+		}
+
+		domain := api.DomainParam(r)
+		alias := chi.URLParam(r, "alias")
+
+		ruleID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			log.Error("invalid rule id", sl.Err(err))
+			resp.RenderError(w, r, http.StatusBadRequest, resp.Error("invalid rule id"))
+			return
+		}
+
+		rule, ok := decodeRule(w, r, log, urlChecker)
+		if !ok {
+			return
+		}
+		rule.ID = ruleID
+
+		if err := rulesStorage.UpdateURLRule(r.Context(), domain, alias, userID, rule); err != nil {
+			renderStorageError(w, r, log, err, "failed to update rule")
+			return
+		}
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			ID:       ruleID,
+		})
+	}
+}
+
+// NewDelete removes a targeting rule from the link.
+func NewDelete(log *slog.Logger, rulesStorage RulesStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.rules.NewDelete"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := r.Context().Value(auth.UserIDContextKey).(int64)
+		if !ok {
+			log.Error("user_id not found in context")
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("user_id not found in token"))
+			return
+		}
+
+		domain := api.DomainParam(r)
+		alias := chi.URLParam(r, "alias")
+
+		ruleID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			log.Error("invalid rule id", sl.Err(err))
+			resp.RenderError(w, r, http.StatusBadRequest, resp.Error("invalid rule id"))
+			return
+		}
+
+		if err := rulesStorage.DeleteURLRule(r.Context(), domain, alias, userID, ruleID); err != nil {
+			renderStorageError(w, r, log, err, "failed to delete rule")
+			return
+		}
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+		})
+	}
+}
+
+func decodeRule(w http.ResponseWriter, r *http.Request, log *slog.Logger, urlChecker URLChecker) (models.Rule, bool) {
+	var req Request
+
+	err := render.DecodeJSON(r.Body, &req)
+	if errors.Is(err, io.EOF) {
+		log.Error("request body is empty")
+		resp.RenderError(w, r, http.StatusBadRequest, resp.Error("empty request"))
+		return models.Rule{}, false
+	}
+	if err != nil {
+		log.Error("failed to decode request body", sl.Err(err))
+		resp.RenderError(w, r, http.StatusBadRequest, resp.Error("failed to decode request"))
+		return models.Rule{}, false
+	}
+
+	req.Platform = strings.ToLower(req.Platform)
+	req.Country = strings.ToUpper(req.Country)
+
+	if err := validator.New().Struct(req); err != nil {
+		var validateErr validator.ValidationErrors
+		errors.As(err, &validateErr)
+		log.Error("invalid request", sl.Err(err))
+		resp.RenderError(w, r, http.StatusBadRequest, resp.ValidationError(validateErr))
+		return models.Rule{}, false
+	}
+
+	if violations := urlChecker.Check(req.TargetURL); len(violations) > 0 {
+		log.Info("url rejected by policy", slog.String("url", req.TargetURL), slog.Any("violations", violations))
+		resp.RenderError(w, r, http.StatusBadRequest, resp.FieldErrors(urlpolicy.FieldErrors("TargetURL", violations)))
+		return models.Rule{}, false
+	}
+
+	return models.Rule{
+		Position:  req.Position,
+		Platform:  req.Platform,
+		Language:  req.Language,
+		Country:   req.Country,
+		TimeFrom:  req.TimeFrom,
+		TimeTo:    req.TimeTo,
+		TargetURL: req.TargetURL,
+	}, true
+}
+
+func renderStorageError(w http.ResponseWriter, r *http.Request, log *slog.Logger, err error, msg string) {
+	switch {
+	case errors.Is(err, storage.ErrURLNotFound):
+		log.Info("url not found", sl.Err(err))
+		resp.RenderError(w, r, http.StatusNotFound, resp.Error("url not found"))
+	case errors.Is(err, storage.ErrURLNotOwned):
+		log.Info("url not owned", sl.Err(err))
+		resp.RenderError(w, r, http.StatusForbidden, resp.Error("url not owned"))
+	case errors.Is(err, storage.ErrRuleNotFound):
+		log.Info("rule not found", sl.Err(err))
+		resp.RenderError(w, r, http.StatusNotFound, resp.Error("rule not found"))
+	default:
+		log.Error(msg, sl.Err(err))
+		resp.RenderError(w, r, http.StatusInternalServerError, resp.Error(msg))
+	}
+}
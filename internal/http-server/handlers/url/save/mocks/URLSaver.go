@@ -2,16 +2,84 @@
 
 package mocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	context "context"
+	models "url-shortener/internal/models"
+
+	mock "github.com/stretchr/testify/mock"
+)
 
 // URLSaver is an autogenerated mock type for the URLSaver type
 type URLSaver struct {
 	mock.Mock
 }
 
-// SaveURL provides a mock function with given fields: urlToSave, alias
-func (_m *URLSaver) SaveURL(urlToSave string, alias string) (int64, error) {
-	ret := _m.Called(urlToSave, alias)
+// GetUserSettings provides a mock function with given fields: ctx, userID
+func (_m *URLSaver) GetUserSettings(ctx context.Context, userID int64) (models.UserSettings, error) {
+	ret := _m.Called(ctx, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUserSettings")
+	}
+
+	var r0 models.UserSettings
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (models.UserSettings, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) models.UserSettings); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		r0 = ret.Get(0).(models.UserSettings)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SaveOrReuseURL provides a mock function with given fields: ctx, urlToSave, domain, alias, userID, attrs
+func (_m *URLSaver) SaveOrReuseURL(ctx context.Context, urlToSave string, domain string, alias string, userID int64, attrs models.URLAttrs) (int64, string, error) {
+	ret := _m.Called(ctx, urlToSave, domain, alias, userID, attrs)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveOrReuseURL")
+	}
+
+	var r0 int64
+	var r1 string
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, int64, models.URLAttrs) (int64, string, error)); ok {
+		return rf(ctx, urlToSave, domain, alias, userID, attrs)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, int64, models.URLAttrs) int64); ok {
+		r0 = rf(ctx, urlToSave, domain, alias, userID, attrs)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string, int64, models.URLAttrs) string); ok {
+		r1 = rf(ctx, urlToSave, domain, alias, userID, attrs)
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, string, string, string, int64, models.URLAttrs) error); ok {
+		r2 = rf(ctx, urlToSave, domain, alias, userID, attrs)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// SaveURL provides a mock function with given fields: ctx, urlToSave, domain, alias, userID, attrs
+func (_m *URLSaver) SaveURL(ctx context.Context, urlToSave string, domain string, alias string, userID int64, attrs models.URLAttrs) (int64, error) {
+	ret := _m.Called(ctx, urlToSave, domain, alias, userID, attrs)
 
 	if len(ret) == 0 {
 		panic("no return value specified for SaveURL")
@@ -19,17 +87,17 @@ func (_m *URLSaver) SaveURL(urlToSave string, alias string) (int64, error) {
 
 	var r0 int64
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string, string) (int64, error)); ok {
-		return rf(urlToSave, alias)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, int64, models.URLAttrs) (int64, error)); ok {
+		return rf(ctx, urlToSave, domain, alias, userID, attrs)
 	}
-	if rf, ok := ret.Get(0).(func(string, string) int64); ok {
-		r0 = rf(urlToSave, alias)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, int64, models.URLAttrs) int64); ok {
+		r0 = rf(ctx, urlToSave, domain, alias, userID, attrs)
 	} else {
 		r0 = ret.Get(0).(int64)
 	}
 
-	if rf, ok := ret.Get(1).(func(string, string) error); ok {
-		r1 = rf(urlToSave, alias)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string, int64, models.URLAttrs) error); ok {
+		r1 = rf(ctx, urlToSave, domain, alias, userID, attrs)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"io"
 	"net/http"
+	"strings"
 
 	"log/slog"
 
@@ -13,20 +14,44 @@ import (
 	"github.com/go-playground/validator/v10"
 
 	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/lib/aliases"
 	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/events"
+	"url-shortener/internal/lib/hostname"
 	"url-shortener/internal/lib/logger/sl"
 	"url-shortener/internal/lib/random"
+	"url-shortener/internal/lib/tags"
+	"url-shortener/internal/lib/urlpolicy"
+	"url-shortener/internal/models"
 	"url-shortener/internal/storage"
 )
 
 type Request struct {
 	URL   string `json:"url" validate:"required,url"`
 	Alias string `json:"alias,omitempty"`
+	// Domain is a verified custom domain of the user, the default domain is used when empty.
+	Domain string `json:"domain,omitempty"`
+	// Tags are trimmed and lowercased before saving.
+	Tags   []string `json:"tags,omitempty" validate:"max=20,dive,max=50"`
+	Folder string   `json:"folder,omitempty" validate:"max=100"`
+	// Title and Description are shown instead of the page's own metadata when set.
+	Title       string `json:"title,omitempty" validate:"max=300"`
+	Description string `json:"description,omitempty" validate:"max=1000"`
+	// Notes are private to the owner.
+	Notes string `json:"notes,omitempty" validate:"max=5000"`
+	// ReuseExisting returns the alias of a link of the user to the same
+	// normalized url on the domain instead of creating one. The existing link
+	// keeps its own tags, folder, title, description and notes, those of the
+	// request only apply to a new link. The user's default is used when nil.
+	// It is ignored when Alias is set.
+	ReuseExisting *bool `json:"reuse_existing,omitempty"`
 }
 
 type Response struct {
 	resp.Response
 	Alias string `json:"alias,omitempty"`
+	// Reused is set when Alias belongs to an existing link.
+	Reused bool `json:"reused,omitempty"`
 }
 
 // TODO: move to config if needed
@@ -34,10 +59,30 @@ const aliasLength = 6
 
 //go:generate go run github.com/vektra/mockery/v2@latest --name=URLSaver
 type URLSaver interface {
-	SaveURL(ctx context.Context, urlToSave string, alias string, userID int64) (int64, error)
+	SaveURL(
+		ctx context.Context, urlToSave string, domain string, alias string, userID int64, attrs models.URLAttrs,
+	) (int64, error)
+	SaveOrReuseURL(
+		ctx context.Context, urlToSave string, domain string, alias string, userID int64, attrs models.URLAttrs,
+	) (int64, string, error)
+	GetUserSettings(ctx context.Context, userID int64) (models.UserSettings, error)
 }
 
-func New(log *slog.Logger, urlSaver URLSaver) http.HandlerFunc {
+// URLChecker checks destination urls against the url policy.
+//
+//go:generate go run github.com/vektra/mockery/v2@latest --name=URLChecker
+type URLChecker interface {
+	Check(rawURL string) []urlpolicy.Violation
+}
+
+// EventPublisher publishes link events to webhooks.
+//
+//go:generate go run github.com/vektra/mockery/v2@latest --name=EventPublisher
+type EventPublisher interface {
+	Publish(ctx context.Context, event events.Event) error
+}
+
+func New(log *slog.Logger, urlSaver URLSaver, urlChecker URLChecker, eventPublisher EventPublisher) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.url.save.New"
 
@@ -62,25 +107,36 @@ func New(log *slog.Logger, urlSaver URLSaver) http.HandlerFunc {
 			// Такую ошибку встретим, если получили запрос с пустым телом.
 			// Обработаем её отдельно
 			log.Error("request body is empty")
-			render.Status(r, http.StatusBadRequest)
-			render.JSON(w, r, resp.Error("empty request"))
+			resp.RenderError(w, r, http.StatusBadRequest, resp.Error("empty request"))
 			return
 		}
 		if err != nil {
 			log.Error("failed to decode request body", sl.Err(err))
-			render.Status(r, http.StatusBadRequest)
-			render.JSON(w, r, resp.Error("failed to decode request"))
+			resp.RenderError(w, r, http.StatusBadRequest, resp.Error("failed to decode request"))
 			return
 		}
 
 		log.Info("request body decoded", slog.Any("request", req))
 
+		req.Domain = hostname.Normalize(req.Domain)
+
 		if err := validator.New().Struct(req); err != nil {
 			// лучше использовать errors.As(err, &validateErr)
 			validateErr := err.(validator.ValidationErrors)
 			log.Error("invalid request", sl.Err(err))
-			render.Status(r, http.StatusBadRequest) 
-			render.JSON(w, r, resp.ValidationError(validateErr))
+			resp.RenderError(w, r, http.StatusBadRequest, resp.ValidationError(validateErr))
+			return
+		}
+
+		if violations := urlChecker.Check(req.URL); len(violations) > 0 {
+			log.Info("url rejected by policy", slog.String("url", req.URL), slog.Any("violations", violations))
+			resp.RenderError(w, r, http.StatusBadRequest, resp.FieldErrors(urlpolicy.FieldErrors("URL", violations)))
+			return
+		}
+
+		if errs := aliases.Check("Alias", req.Alias); len(errs) > 0 {
+			log.Info("alias is reserved", slog.String("alias", req.Alias))
+			resp.RenderError(w, r, http.StatusBadRequest, resp.FieldErrors(errs))
 			return
 		}
 
@@ -92,35 +148,108 @@ func New(log *slog.Logger, urlSaver URLSaver) http.HandlerFunc {
 		userID, ok := r.Context().Value(auth.UserIDContextKey).(int64)
 		if !ok {
 			log.Error("user_id not found in context")
-			render.Status(r, http.StatusInternalServerError)
-			render.JSON(w, r, resp.Error("user_id not found in token"))
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("user_id not found in token"))
 			return
 		}
 
-		id, err := urlSaver.SaveURL(r.Context(), req.URL, alias, userID)
+		reuse := false
+		if req.Alias == "" {
+			reuse, err = reuseExisting(r.Context(), urlSaver, req, userID)
+			if err != nil {
+				log.Error("failed to get user settings", sl.Err(err))
+				resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("failed to add url"))
+				return
+			}
+		}
+
+		attrs := models.URLAttrs{
+			Tags:        tags.Normalize(req.Tags),
+			Folder:      strings.TrimSpace(req.Folder),
+			Title:       strings.TrimSpace(req.Title),
+			Description: strings.TrimSpace(req.Description),
+			Notes:       req.Notes,
+		}
+
+		var (
+			id       int64
+			existing string
+		)
+		if reuse {
+			id, existing, err = urlSaver.SaveOrReuseURL(r.Context(), req.URL, req.Domain, alias, userID, attrs)
+		} else {
+			id, err = urlSaver.SaveURL(r.Context(), req.URL, req.Domain, alias, userID, attrs)
+		}
 		if errors.Is(err, storage.ErrURLExists) {
 			log.Info("url already exists", slog.String("url", req.URL), slog.String("alias", alias))
 
-			render.Status(r, http.StatusConflict) 
-			render.JSON(w, r, resp.Error("url already exists"))
+			resp.RenderError(w, r, http.StatusConflict, resp.Error("url already exists"))
+
+			return
+		}
+		if errors.Is(err, storage.ErrDomainNotFound) {
+			log.Info("domain not found", slog.String("domain", req.Domain))
+
+			resp.RenderError(w, r, http.StatusBadRequest, resp.Error("domain not found"))
+
+			return
+		}
+		if errors.Is(err, storage.ErrDomainNotOwned) || errors.Is(err, storage.ErrDomainNotVerified) {
+			log.Info("domain not available", slog.String("domain", req.Domain), sl.Err(err))
+
+			resp.RenderError(w, r, http.StatusForbidden, resp.Error("domain not available"))
 
 			return
 		}
 		if err != nil {
 			log.Error("failed to add url", sl.Err(err))
 
-			render.Status(r, http.StatusInternalServerError)
-			render.JSON(w, r, resp.Error("failed to add url"))
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("failed to add url"))
+
+			return
+		}
 
+		if existing != "" {
+			log.Info("reusing existing url", slog.Int64("id", id), slog.String("alias", existing))
+			render.JSON(w, r, Response{
+				Response: resp.OK(),
+				Alias:    existing,
+				Reused:   true,
+			})
 			return
 		}
 
 		log.Info("url added", slog.Int64("id", id))
 
+		event := events.New(events.LinkCreated, req.Domain, alias, events.LinkData{
+			URL:         req.URL,
+			Tags:        attrs.Tags,
+			Folder:      attrs.Folder,
+			Title:       attrs.Title,
+			Description: attrs.Description,
+		})
+		if err := eventPublisher.Publish(r.Context(), event); err != nil {
+			log.Error("failed to publish event", sl.Err(err))
+		}
+
 		responseOK(w, r, alias)
 	}
 }
 
+// reuseExisting tells whether the request asks for an existing link, falling
+// back to the user's default.
+func reuseExisting(ctx context.Context, urlSaver URLSaver, req Request, userID int64) (bool, error) {
+	if req.ReuseExisting != nil {
+		return *req.ReuseExisting, nil
+	}
+
+	settings, err := urlSaver.GetUserSettings(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return settings.ReuseExisting, nil
+}
+
 func responseOK(w http.ResponseWriter, r *http.Request, alias string) {
 	render.JSON(w, r, Response{
 		Response: resp.OK(),
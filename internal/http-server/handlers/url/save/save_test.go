@@ -2,11 +2,13 @@ package save_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/mock"
@@ -14,7 +16,11 @@ import (
 
 	"url-shortener/internal/http-server/handlers/url/save"
 	"url-shortener/internal/http-server/handlers/url/save/mocks"
+	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/lib/events"
 	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/lib/urlpolicy"
+	"url-shortener/internal/models"
 )
 
 func Ptr[T any](v T) *T {
@@ -23,12 +29,13 @@ func Ptr[T any](v T) *T {
 
 func TestSaveHandler(t *testing.T) {
 	cases := []struct {
-		name      string
-		alias     string
-		url       string
-		respError string
-		mockError error
-		code      *int
+		name       string
+		alias      string
+		url        string
+		respError  string
+		mockError  error
+		violations []urlpolicy.Violation
+		code       *int
 	}{
 		{
 			name:  "Success",
@@ -62,6 +69,24 @@ func TestSaveHandler(t *testing.T) {
 			mockError: errors.New("unexpected error"),
 			code:      Ptr(http.StatusInternalServerError),
 		},
+		{
+			name:      "Denied URL",
+			alias:     "test_alias",
+			url:       "https://evil.example.com",
+			respError: "field URL: domain evil.example.com is not allowed",
+			violations: []urlpolicy.Violation{
+				{Code: urlpolicy.CodeDomainDenied, Message: "domain evil.example.com is not allowed"},
+			},
+			code: Ptr(http.StatusBadRequest),
+		},
+		{
+			name:       "Reserved alias",
+			alias:      "tags",
+			url:        "https://google.com",
+			respError:  "field Alias is reserved",
+			violations: []urlpolicy.Violation{},
+			code:       Ptr(http.StatusBadRequest),
+		},
 	}
 
 	for _, tc := range cases {
@@ -71,20 +96,37 @@ func TestSaveHandler(t *testing.T) {
 			t.Parallel()
 
 			urlSaverMock := mocks.NewURLSaver(t)
+			urlCheckerMock := mocks.NewURLChecker(t)
+			eventPublisherMock := mocks.NewEventPublisher(t)
 
+			if tc.respError == "" || tc.mockError != nil || tc.violations != nil {
+				urlCheckerMock.On("Check", tc.url).
+					Return(tc.violations).
+					Once()
+			}
+			urlSaverMock.On("GetUserSettings", mock.Anything, int64(1)).
+				Return(models.UserSettings{}, nil).
+				Maybe()
 			if tc.respError == "" || tc.mockError != nil {
-				urlSaverMock.On("SaveURL", tc.url, mock.AnythingOfType("string")).
+				urlSaverMock.On("SaveURL", mock.Anything, tc.url, "", mock.AnythingOfType("string"), int64(1), models.URLAttrs{Tags: []string{}}).
 					Return(int64(1), tc.mockError).
 					Once()
 			}
+			if tc.respError == "" {
+				eventPublisherMock.On("Publish", mock.Anything, mock.MatchedBy(func(e events.Event) bool {
+					return e.Type == events.LinkCreated && e.Alias != ""
+				})).Return(nil).Once()
+			}
 
-			handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock)
+			handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock, urlCheckerMock, eventPublisherMock)
 
 			input := fmt.Sprintf(`{"url": "%s", "alias": "%s"}`, tc.url, tc.alias)
 
 			req, err := http.NewRequest(http.MethodPost, "/save", bytes.NewReader([]byte(input)))
 			require.NoError(t, err)
 
+			req = req.WithContext(context.WithValue(req.Context(), auth.UserIDContextKey, int64(1)))
+
 			rr := httptest.NewRecorder()
 			handler.ServeHTTP(rr, req)
 
@@ -106,3 +148,129 @@ func TestSaveHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestSaveHandlerNormalizesDomain(t *testing.T) {
+	urlSaverMock := mocks.NewURLSaver(t)
+	urlCheckerMock := mocks.NewURLChecker(t)
+	eventPublisherMock := mocks.NewEventPublisher(t)
+
+	urlCheckerMock.On("Check", "https://google.com").Return(nil).Once()
+	urlSaverMock.On("GetUserSettings", mock.Anything, int64(1)).Return(models.UserSettings{}, nil).Once()
+	urlSaverMock.On("SaveURL", mock.Anything, "https://google.com", "links.example.com", mock.AnythingOfType("string"), int64(1), models.URLAttrs{Tags: []string{}}).
+		Return(int64(1), nil).
+		Once()
+	eventPublisherMock.On("Publish", mock.Anything, mock.MatchedBy(func(e events.Event) bool {
+		return e.Domain == "links.example.com"
+	})).Return(nil).Once()
+
+	handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock, urlCheckerMock, eventPublisherMock)
+
+	input := `{"url": "https://google.com", "domain": "Links.Example.com."}`
+	req, err := http.NewRequest(http.MethodPost, "/save", strings.NewReader(input))
+	require.NoError(t, err)
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserIDContextKey, int64(1)))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+}
+
+func TestSaveHandlerReuse(t *testing.T) {
+	const url = "https://google.com"
+
+	cases := []struct {
+		name        string
+		input       string
+		userDefault *bool
+		// reuse expects SaveOrReuseURL to be called, returning existing
+		reuse    bool
+		existing string
+		alias    string
+		reused   bool
+	}{
+		{
+			name:     "Requested",
+			input:    `{"url": "https://google.com", "reuse_existing": true}`,
+			reuse:    true,
+			existing: "abc123",
+			alias:    "abc123",
+			reused:   true,
+		},
+		{
+			name:        "User default",
+			input:       `{"url": "https://google.com"}`,
+			userDefault: Ptr(true),
+			reuse:       true,
+			existing:    "abc123",
+			alias:       "abc123",
+			reused:      true,
+		},
+		{
+			name:        "Request overrides user default",
+			input:       `{"url": "https://google.com", "reuse_existing": false}`,
+			userDefault: Ptr(true),
+		},
+		{
+			name:  "No existing url",
+			input: `{"url": "https://google.com", "reuse_existing": true}`,
+			reuse: true,
+		},
+		{
+			name:  "Custom alias",
+			input: `{"url": "https://google.com", "alias": "custom", "reuse_existing": true}`,
+			alias: "custom",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlSaverMock := mocks.NewURLSaver(t)
+			urlCheckerMock := mocks.NewURLChecker(t)
+			eventPublisherMock := mocks.NewEventPublisher(t)
+
+			urlCheckerMock.On("Check", url).Return(nil).Once()
+			if tc.userDefault != nil && !strings.Contains(tc.input, "reuse_existing") {
+				urlSaverMock.On("GetUserSettings", mock.Anything, int64(1)).
+					Return(models.UserSettings{UserID: 1, ReuseExisting: *tc.userDefault}, nil).
+					Once()
+			}
+			attrs := models.URLAttrs{Tags: []string{}}
+			if tc.reuse {
+				urlSaverMock.On("SaveOrReuseURL", mock.Anything, url, "", mock.AnythingOfType("string"), int64(1), attrs).
+					Return(int64(1), tc.existing, nil).
+					Once()
+			} else {
+				urlSaverMock.On("SaveURL", mock.Anything, url, "", mock.AnythingOfType("string"), int64(1), attrs).
+					Return(int64(1), nil).
+					Once()
+			}
+			if !tc.reused {
+				eventPublisherMock.On("Publish", mock.Anything, mock.Anything).Return(nil).Once()
+			}
+
+			handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock, urlCheckerMock, eventPublisherMock)
+
+			req, err := http.NewRequest(http.MethodPost, "/save", strings.NewReader(tc.input))
+			require.NoError(t, err)
+			req = req.WithContext(context.WithValue(req.Context(), auth.UserIDContextKey, int64(1)))
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var resp save.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.reused, resp.Reused)
+			if tc.alias != "" {
+				require.Equal(t, tc.alias, resp.Alias)
+			}
+		})
+	}
+}
@@ -0,0 +1,397 @@
+package stats
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/parquet-go/parquet-go"
+
+	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/lib/api"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+// Export formats.
+const (
+	FormatCSV     = "csv"
+	FormatNDJSON  = "ndjson"
+	FormatParquet = "parquet"
+)
+
+// GranularityRaw exports every click, the other granularities are
+// models.PeriodHour and models.PeriodDay.
+const GranularityRaw = "raw"
+
+const (
+	// parquetRowGroupRows bounds the rows buffered in memory before a row
+	// group is written out.
+	parquetRowGroupRows = 10000
+	// exportWriteTimeout is how long writing a batch of deadlineRows rows
+	// may take. The deadline of the server is extended batch by batch, so
+	// long exports are not cut off while stalled clients still are.
+	exportWriteTimeout = time.Minute
+	deadlineRows       = 1000
+)
+
+var contentTypes = map[string]string{
+	FormatCSV:     "text/csv; charset=utf-8",
+	FormatNDJSON:  "application/x-ndjson",
+	FormatParquet: "application/vnd.apache.parquet",
+}
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=ClickExporter
+type ClickExporter interface {
+	ExportClicks(ctx context.Context, filter models.StatsFilter, fn func(models.Click) error) error
+	ExportClickCounts(ctx context.Context, filter models.StatsFilter, period string, fn func(models.ClickCount) error) error
+}
+
+// NewExport streams the clicks of the alias as a file. The "format" query
+// parameter is csv (default), ndjson or parquet. With "granularity=raw"
+// (default) every click is exported, "hour" and "day" export the number of
+// clicks per period, class of traffic and split variant. Range and traffic
+// are set as for New. Raw clicks and hourly counts are only kept for the
+// retention period, daily counts are kept for good.
+func NewExport(log *slog.Logger, clickExporter ClickExporter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.stats.NewExport"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		serveExport(log, w, r, clickExporter, api.DomainParam(r), chi.URLParam(r, "alias"))
+	}
+}
+
+// NewUserExport streams the clicks of all links of the user as NewExport does.
+func NewUserExport(log *slog.Logger, clickExporter ClickExporter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.stats.NewUserExport"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		serveExport(log, w, r, clickExporter, "", "")
+	}
+}
+
+func serveExport(
+	log *slog.Logger, w http.ResponseWriter, r *http.Request, clickExporter ClickExporter, domain string, alias string,
+) {
+	userID, ok := r.Context().Value(auth.UserIDContextKey).(int64)
+	if !ok {
+		log.Error("user_id not found in context")
+		resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("user_id not found in token"))
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = FormatCSV
+	}
+	if _, ok := contentTypes[format]; !ok {
+		log.Info("invalid format", slog.String("format", format))
+		resp.RenderError(w, r, http.StatusBadRequest, resp.Error("format must be csv, ndjson or parquet"))
+		return
+	}
+
+	granularity := r.URL.Query().Get("granularity")
+	switch granularity {
+	case "":
+		granularity = GranularityRaw
+	case GranularityRaw, models.PeriodHour, models.PeriodDay:
+	default:
+		log.Info("invalid granularity", slog.String("granularity", granularity))
+		resp.RenderError(w, r, http.StatusBadRequest, resp.Error("granularity must be raw, hour or day"))
+		return
+	}
+
+	from, to, err := parseRange(r, time.Now())
+	if err != nil {
+		log.Info("invalid range", sl.Err(err))
+		resp.RenderError(w, r, http.StatusBadRequest, resp.Error(err.Error()))
+		return
+	}
+
+	traffic, err := parseTraffic(r.URL.Query().Get("traffic"))
+	if err != nil {
+		log.Info("invalid traffic", sl.Err(err))
+		resp.RenderError(w, r, http.StatusBadRequest, resp.Error(err.Error()))
+		return
+	}
+
+	filter := models.StatsFilter{
+		UserID:  userID,
+		Domain:  domain,
+		Alias:   alias,
+		From:    from,
+		To:      to,
+		Traffic: traffic,
+	}
+
+	name := "clicks"
+	if alias != "" {
+		name += "-" + alias
+	}
+	filename := fmt.Sprintf("%s-%s-%s-%s.%s", name, granularity, from.Format(dayFormat), to.Format(dayFormat), format)
+
+	w.Header().Set("Content-Type", contentTypes[format])
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	var written int
+	if granularity == GranularityRaw {
+		written, err = export(w, format, clickColumns, func(fn func(clickRow) error) error {
+			return clickExporter.ExportClicks(r.Context(), filter, func(c models.Click) error {
+				return fn(newClickRow(c))
+			})
+		})
+	} else {
+		written, err = export(w, format, countColumns, func(fn func(countRow) error) error {
+			return clickExporter.ExportClickCounts(r.Context(), filter, granularity, func(c models.ClickCount) error {
+				return fn(newCountRow(c))
+			})
+		})
+	}
+	if err != nil {
+		// once rows are written the status can't be changed, the client
+		// sees a truncated export
+		log.Error("failed to export clicks", sl.Err(err), slog.Int("written", written))
+		if written > 0 {
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Del("Content-Disposition")
+		switch {
+		case errors.Is(err, storage.ErrURLNotFound):
+			resp.RenderError(w, r, http.StatusNotFound, resp.Error("url not found"))
+		case errors.Is(err, storage.ErrURLNotOwned):
+			resp.RenderError(w, r, http.StatusForbidden, resp.Error("url not owned"))
+		default:
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("failed to export clicks"))
+		}
+		return
+	}
+
+	log.Info("clicks exported", slog.String("format", format), slog.String("granularity", granularity),
+		slog.Int("rows", written))
+}
+
+// export writes the rows produced by run to w in the format and returns how
+// many were written. Nothing is written to w before the first row, so the
+// response can still report an error that happens earlier.
+func export[T record](w http.ResponseWriter, format string, columns []string, run func(func(T) error) error) (int, error) {
+	rc := http.NewResponseController(w)
+	var (
+		out     rowWriter[T]
+		written int
+	)
+	err := run(func(row T) error {
+		if out == nil {
+			out = newRowWriter[T](w, format, columns)
+		}
+		if written%deadlineRows == 0 {
+			if err := api.ExtendWriteDeadline(rc, exportWriteTimeout); err != nil {
+				return err
+			}
+		}
+
+		if err := out.Write(row); err != nil {
+			return err
+		}
+		written++
+
+		return nil
+	})
+	if err != nil {
+		return written, err
+	}
+
+	if out == nil {
+		// an empty export is still a valid file with the header or schema
+		out = newRowWriter[T](w, format, columns)
+	}
+	if err := api.ExtendWriteDeadline(rc, exportWriteTimeout); err != nil {
+		return written, err
+	}
+
+	return written, out.Close()
+}
+
+// record is a row of an export, CSV gets the values of its columns.
+type record interface {
+	record() []string
+}
+
+// rowWriter encodes the rows of an export in one of the formats.
+type rowWriter[T record] interface {
+	Write(row T) error
+	// Close writes out everything buffered and ends the file.
+	Close() error
+}
+
+func newRowWriter[T record](w io.Writer, format string, columns []string) rowWriter[T] {
+	switch format {
+	case FormatNDJSON:
+		bw := bufio.NewWriter(w)
+		return &ndjsonWriter[T]{w: bw, enc: json.NewEncoder(bw)}
+	case FormatParquet:
+		return &parquetWriter[T]{w: parquet.NewGenericWriter[T](w,
+			parquet.Compression(&parquet.Zstd),
+			parquet.MaxRowsPerRowGroup(parquetRowGroupRows),
+		)}
+	default:
+		return &csvWriter[T]{w: csv.NewWriter(w), columns: columns}
+	}
+}
+
+type csvWriter[T record] struct {
+	w       *csv.Writer
+	columns []string
+	started bool
+}
+
+func (c *csvWriter[T]) Write(row T) error {
+	if err := c.header(); err != nil {
+		return err
+	}
+
+	return c.w.Write(row.record())
+}
+
+func (c *csvWriter[T]) Close() error {
+	if err := c.header(); err != nil {
+		return err
+	}
+	c.w.Flush()
+
+	return c.w.Error()
+}
+
+func (c *csvWriter[T]) header() error {
+	if c.started {
+		return nil
+	}
+	c.started = true
+
+	return c.w.Write(c.columns)
+}
+
+type ndjsonWriter[T record] struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func (n *ndjsonWriter[T]) Write(row T) error {
+	return n.enc.Encode(row)
+}
+
+func (n *ndjsonWriter[T]) Close() error {
+	return n.w.Flush()
+}
+
+type parquetWriter[T record] struct {
+	w *parquet.GenericWriter[T]
+}
+
+func (p *parquetWriter[T]) Write(row T) error {
+	_, err := p.w.Write([]T{row})
+	return err
+}
+
+func (p *parquetWriter[T]) Close() error {
+	return p.w.Close()
+}
+
+var clickColumns = []string{
+	"id", "time", "domain", "alias", "variant_id", "visitor_id", "class",
+	"referrer", "browser", "browser_version", "os", "device",
+}
+
+// clickRow is a single click in an export.
+type clickRow struct {
+	ID             int64     `json:"id" parquet:"id"`
+	Time           time.Time `json:"time" parquet:"time,timestamp(millisecond)"`
+	Domain         string    `json:"domain" parquet:"domain,dict"`
+	Alias          string    `json:"alias" parquet:"alias,dict"`
+	VariantID      int64     `json:"variant_id" parquet:"variant_id"`
+	VisitorID      string    `json:"visitor_id" parquet:"visitor_id"`
+	Class          string    `json:"class" parquet:"class,dict"`
+	Referrer       string    `json:"referrer" parquet:"referrer,dict"`
+	Browser        string    `json:"browser" parquet:"browser,dict"`
+	BrowserVersion string    `json:"browser_version" parquet:"browser_version,dict"`
+	OS             string    `json:"os" parquet:"os,dict"`
+	Device         string    `json:"device" parquet:"device,dict"`
+}
+
+func newClickRow(c models.Click) clickRow {
+	return clickRow{
+		ID:             c.ID,
+		Time:           c.CreatedAt.UTC(),
+		Domain:         c.Domain,
+		Alias:          c.Alias,
+		VariantID:      c.VariantID,
+		VisitorID:      c.VisitorID,
+		Class:          c.Class,
+		Referrer:       c.Referrer,
+		Browser:        c.Browser,
+		BrowserVersion: c.BrowserVersion,
+		OS:             c.OS,
+		Device:         c.Device,
+	}
+}
+
+func (c clickRow) record() []string {
+	return []string{
+		strconv.FormatInt(c.ID, 10), c.Time.Format(time.RFC3339), c.Domain, c.Alias,
+		strconv.FormatInt(c.VariantID, 10), c.VisitorID, c.Class,
+		c.Referrer, c.Browser, c.BrowserVersion, c.OS, c.Device,
+	}
+}
+
+var countColumns = []string{"period", "domain", "alias", "class", "variant_id", "clicks"}
+
+// countRow is the number of clicks in an hour or a day in an export.
+type countRow struct {
+	Period    time.Time `json:"period" parquet:"period,timestamp(millisecond)"`
+	Domain    string    `json:"domain" parquet:"domain,dict"`
+	Alias     string    `json:"alias" parquet:"alias,dict"`
+	Class     string    `json:"class" parquet:"class,dict"`
+	VariantID int64     `json:"variant_id" parquet:"variant_id"`
+	Clicks    int64     `json:"clicks" parquet:"clicks"`
+}
+
+func newCountRow(c models.ClickCount) countRow {
+	return countRow{
+		Period:    c.Period.UTC(),
+		Domain:    c.Domain,
+		Alias:     c.Alias,
+		Class:     c.Class,
+		VariantID: c.VariantID,
+		Clicks:    c.Clicks,
+	}
+}
+
+func (c countRow) record() []string {
+	return []string{
+		c.Period.Format(time.RFC3339), c.Domain, c.Alias, c.Class,
+		strconv.FormatInt(c.VariantID, 10), strconv.FormatInt(c.Clicks, 10),
+	}
+}
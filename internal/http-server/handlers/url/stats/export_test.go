@@ -0,0 +1,192 @@
+package stats_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/stats"
+	"url-shortener/internal/http-server/handlers/url/stats/mocks"
+	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+var testClicks = []models.Click{
+	{ID: 1, Alias: "abc", Class: "human", Referrer: "news.ycombinator.com", Browser: "Firefox", CreatedAt: time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)},
+	{ID: 2, Alias: "abc", Class: "human", VariantID: 7, Browser: "Chrome", CreatedAt: time.Date(2026, 3, 2, 11, 30, 0, 0, time.UTC)},
+}
+
+func serveExport(t *testing.T, exporter stats.ClickExporter, target string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	r := chi.NewRouter()
+	r.Get("/url/clicks/export", stats.NewUserExport(slogdiscard.NewDiscardLogger(), exporter))
+	r.Get("/url/{alias}/clicks/export", stats.NewExport(slogdiscard.NewDiscardLogger(), exporter))
+
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserIDContextKey, int64(1)))
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	return rr
+}
+
+func exportClicks(clicks []models.Click) func(mock.Arguments) {
+	return func(args mock.Arguments) {
+		fn := args.Get(2).(func(models.Click) error)
+		for _, c := range clicks {
+			if err := fn(c); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func TestExportCSV(t *testing.T) {
+	exporter := mocks.NewClickExporter(t)
+	exporter.On("ExportClicks", mock.Anything, mock.MatchedBy(func(f models.StatsFilter) bool {
+		return f.UserID == 1 && f.Alias == "abc" && f.From.Format("2006-01-02") == "2026-03-01" &&
+			f.To.Format("2006-01-02") == "2026-03-31" && len(f.Traffic) == 1
+	}), mock.Anything).Run(exportClicks(testClicks)).Return(nil).Once()
+
+	rr := serveExport(t, exporter, "/url/abc/clicks/export?from=2026-03-01&to=2026-03-31")
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", rr.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="clicks-abc-raw-2026-03-01-2026-03-31.csv"`, rr.Header().Get("Content-Disposition"))
+
+	records, err := csv.NewReader(rr.Body).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, "id", records[0][0])
+	assert.Equal(t, []string{
+		"1", "2026-03-01T10:00:00Z", "", "abc", "0", "", "human", "news.ycombinator.com", "Firefox", "", "", "",
+	}, records[1])
+	assert.Equal(t, "7", records[2][4])
+}
+
+func TestExportEmptyCSV(t *testing.T) {
+	exporter := mocks.NewClickExporter(t)
+	exporter.On("ExportClicks", mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+
+	rr := serveExport(t, exporter, "/url/clicks/export")
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.True(t, strings.HasPrefix(rr.Body.String(), "id,time,domain,alias,"))
+	assert.Equal(t, 1, strings.Count(rr.Body.String(), "\n"))
+}
+
+func TestExportNDJSONCounts(t *testing.T) {
+	exporter := mocks.NewClickExporter(t)
+	exporter.On("ExportClickCounts", mock.Anything, mock.Anything, models.PeriodDay, mock.Anything).
+		Run(func(args mock.Arguments) {
+			fn := args.Get(3).(func(models.ClickCount) error)
+			_ = fn(models.ClickCount{Alias: "abc", Period: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), Class: "human", Clicks: 5})
+			_ = fn(models.ClickCount{Alias: "def", Period: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), Class: "bot", Clicks: 2})
+		}).Return(nil).Once()
+
+	rr := serveExport(t, exporter, "/url/clicks/export?format=ndjson&granularity=day&traffic=all")
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/x-ndjson", rr.Header().Get("Content-Type"))
+
+	lines := strings.Split(strings.TrimSpace(rr.Body.String()), "\n")
+	require.Len(t, lines, 2)
+
+	var row map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &row))
+	assert.Equal(t, "2026-03-01T00:00:00Z", row["period"])
+	assert.Equal(t, "abc", row["alias"])
+	assert.Equal(t, float64(5), row["clicks"])
+}
+
+func TestExportParquet(t *testing.T) {
+	exporter := mocks.NewClickExporter(t)
+	exporter.On("ExportClicks", mock.Anything, mock.Anything, mock.Anything).
+		Run(exportClicks(testClicks)).Return(nil).Once()
+
+	rr := serveExport(t, exporter, "/url/abc/clicks/export?format=parquet")
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/vnd.apache.parquet", rr.Header().Get("Content-Type"))
+
+	type row struct {
+		ID        int64     `parquet:"id"`
+		Time      time.Time `parquet:"time,timestamp(millisecond)"`
+		Alias     string    `parquet:"alias"`
+		VariantID int64     `parquet:"variant_id"`
+		Browser   string    `parquet:"browser"`
+	}
+	body := rr.Body.Bytes()
+	rows, err := parquet.Read[row](bytes.NewReader(body), int64(len(body)))
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, int64(1), rows[0].ID)
+	assert.True(t, testClicks[0].CreatedAt.Equal(rows[0].Time))
+	assert.Equal(t, "abc", rows[1].Alias)
+	assert.Equal(t, int64(7), rows[1].VariantID)
+	assert.Equal(t, "Chrome", rows[1].Browser)
+}
+
+func TestExportErrors(t *testing.T) {
+	cases := []struct {
+		name      string
+		target    string
+		mockError error
+		code      int
+	}{
+		{
+			name:   "Unknown format",
+			target: "/url/abc/clicks/export?format=xlsx",
+			code:   http.StatusBadRequest,
+		},
+		{
+			name:   "Unknown granularity",
+			target: "/url/abc/clicks/export?granularity=minute",
+			code:   http.StatusBadRequest,
+		},
+		{
+			name:      "Not owned",
+			target:    "/url/abc/clicks/export",
+			mockError: fmt.Errorf("wrapped: %w", storage.ErrURLNotOwned),
+			code:      http.StatusForbidden,
+		},
+		{
+			name:      "Not found",
+			target:    "/url/abc/clicks/export?format=parquet",
+			mockError: fmt.Errorf("wrapped: %w", storage.ErrURLNotFound),
+			code:      http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			exporter := mocks.NewClickExporter(t)
+			if tc.mockError != nil {
+				exporter.On("ExportClicks", mock.Anything, mock.Anything, mock.Anything).Return(tc.mockError).Once()
+			}
+
+			rr := serveExport(t, exporter, tc.target)
+
+			assert.Equal(t, tc.code, rr.Code)
+			assert.Contains(t, rr.Header().Get("Content-Type"), "application/json")
+			assert.Empty(t, rr.Header().Get("Content-Disposition"))
+		})
+	}
+}
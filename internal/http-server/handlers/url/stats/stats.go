@@ -0,0 +1,195 @@
+package stats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/lib/api"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/botdetect"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+const (
+	dayFormat   = "2006-01-02"
+	defaultDays = 30
+	maxDays     = 366
+	defaultTop  = 10
+	maxTop      = 100
+)
+
+type Response struct {
+	resp.Response
+	Stats *models.LinkStats `json:"stats,omitempty"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=StatsGetter
+type StatsGetter interface {
+	GetLinkStats(ctx context.Context, filter models.StatsFilter) (models.LinkStats, error)
+}
+
+// New returns clicks and unique visitors of the alias per day. The range is
+// set with the "from" and "to" query parameters (YYYY-MM-DD, UTC, inclusive)
+// and defaults to the last 30 days. Only human traffic is counted unless the
+// "traffic" parameter lists other classes (human, bot, preview) or is "all".
+// The "top" parameter sets how many values of each breakdown (referrer,
+// browser, browser version, OS and device) are returned, 10 by default and
+// none when 0. Links on custom domains are addressed with the "domain" query
+// parameter.
+func New(log *slog.Logger, statsGetter StatsGetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.stats.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		serveStats(log, w, r, statsGetter, api.DomainParam(r), chi.URLParam(r, "alias"))
+	}
+}
+
+// NewUser returns clicks and unique visitors over all links of the user, a
+// visitor of several links is counted once. Range, traffic and breakdowns are
+// set as for New.
+func NewUser(log *slog.Logger, statsGetter StatsGetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.stats.NewUser"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		serveStats(log, w, r, statsGetter, "", "")
+	}
+}
+
+func serveStats(log *slog.Logger, w http.ResponseWriter, r *http.Request, statsGetter StatsGetter, domain string, alias string) {
+	userID, ok := r.Context().Value(auth.UserIDContextKey).(int64)
+	if !ok {
+		log.Error("user_id not found in context")
+		resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("user_id not found in token"))
+		return
+	}
+
+	from, to, err := parseRange(r, time.Now())
+	if err != nil {
+		log.Info("invalid range", sl.Err(err))
+		resp.RenderError(w, r, http.StatusBadRequest, resp.Error(err.Error()))
+		return
+	}
+
+	traffic, err := parseTraffic(r.URL.Query().Get("traffic"))
+	if err != nil {
+		log.Info("invalid traffic", sl.Err(err))
+		resp.RenderError(w, r, http.StatusBadRequest, resp.Error(err.Error()))
+		return
+	}
+
+	top := defaultTop
+	if v := r.URL.Query().Get("top"); v != "" {
+		top, err = strconv.Atoi(v)
+		if err != nil || top < 0 || top > maxTop {
+			log.Info("invalid top", slog.String("top", v))
+			resp.RenderError(w, r, http.StatusBadRequest, resp.Error(fmt.Sprintf("top must be a number from 0 to %d", maxTop)))
+			return
+		}
+	}
+
+	stats, err := statsGetter.GetLinkStats(r.Context(), models.StatsFilter{
+		UserID:  userID,
+		Domain:  domain,
+		Alias:   alias,
+		From:    from,
+		To:      to,
+		Traffic: traffic,
+		Top:     top,
+	})
+	if errors.Is(err, storage.ErrURLNotFound) {
+		log.Info("url not found", slog.String("alias", alias))
+		resp.RenderError(w, r, http.StatusNotFound, resp.Error("url not found"))
+		return
+	}
+	if errors.Is(err, storage.ErrURLNotOwned) {
+		log.Info("url not owned", slog.String("alias", alias))
+		resp.RenderError(w, r, http.StatusForbidden, resp.Error("url not owned"))
+		return
+	}
+	if err != nil {
+		log.Error("failed to get stats", sl.Err(err))
+		resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("failed to get stats"))
+		return
+	}
+
+	render.JSON(w, r, Response{
+		Response: resp.OK(),
+		Stats:    &stats,
+	})
+}
+
+func parseRange(r *http.Request, now time.Time) (time.Time, time.Time, error) {
+	to := now.UTC().Truncate(24 * time.Hour)
+	if v := r.URL.Query().Get("to"); v != "" {
+		t, err := time.Parse(dayFormat, v)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("to must be a date (YYYY-MM-DD)")
+		}
+		to = t
+	}
+
+	from := to.AddDate(0, 0, -(defaultDays - 1))
+	if v := r.URL.Query().Get("from"); v != "" {
+		t, err := time.Parse(dayFormat, v)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("from must be a date (YYYY-MM-DD)")
+		}
+		from = t
+	}
+
+	if from.After(to) {
+		return time.Time{}, time.Time{}, errors.New("from must not be after to")
+	}
+	if to.Sub(from) >= maxDays*24*time.Hour {
+		return time.Time{}, time.Time{}, fmt.Errorf("range must not exceed %d days", maxDays)
+	}
+
+	return from, to, nil
+}
+
+// parseTraffic parses a comma separated list of traffic classes.
+func parseTraffic(v string) ([]string, error) {
+	switch v {
+	case "":
+		return []string{botdetect.Human}, nil
+	case "all":
+		return botdetect.Classes, nil
+	}
+
+	var traffic []string
+	for _, class := range strings.Split(v, ",") {
+		class = strings.TrimSpace(class)
+		if !slices.Contains(botdetect.Classes, class) {
+			return nil, fmt.Errorf("unknown traffic class %q", class)
+		}
+		if !slices.Contains(traffic, class) {
+			traffic = append(traffic, class)
+		}
+	}
+
+	return traffic, nil
+}
@@ -0,0 +1,124 @@
+package trash
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/lib/api"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+// DeletedURL is a link in the trash with the time it will be purged at.
+type DeletedURL struct {
+	models.URL
+	PurgeAt time.Time `json:"purge_at"`
+}
+
+type Response struct {
+	resp.Response
+	URLs []DeletedURL `json:"urls,omitempty"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=TrashStorage
+type TrashStorage interface {
+	GetUserDeletedURLs(ctx context.Context, userID int64) ([]models.URL, error)
+	RestoreURL(ctx context.Context, domain string, alias string, userID int64) error
+}
+
+// NewList returns deleted links of the user that have not been purged yet.
+// gracePeriod is how long links stay in the trash.
+func NewList(log *slog.Logger, trashStorage TrashStorage, gracePeriod time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.trash.NewList"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := r.Context().Value(auth.UserIDContextKey).(int64)
+		if !ok {
+			log.Error("user_id not found in context")
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("user_id not found in token"))
+			return
+		}
+
+		urls, err := trashStorage.GetUserDeletedURLs(r.Context(), userID)
+		if err != nil {
+			log.Error("failed to get deleted urls", sl.Err(err))
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("failed to get deleted urls"))
+			return
+		}
+
+		deleted := make([]DeletedURL, 0, len(urls))
+		for _, u := range urls {
+			d := DeletedURL{URL: u}
+			if u.DeletedAt != nil {
+				d.PurgeAt = u.DeletedAt.Add(gracePeriod)
+			}
+			deleted = append(deleted, d)
+		}
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			URLs:     deleted,
+		})
+	}
+}
+
+// NewRestore takes a link out of the trash. Links on custom domains are
+// addressed with the "domain" query parameter.
+func NewRestore(log *slog.Logger, trashStorage TrashStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.trash.NewRestore"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := r.Context().Value(auth.UserIDContextKey).(int64)
+		if !ok {
+			log.Error("user_id not found in context")
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("user_id not found in token"))
+			return
+		}
+
+		domain := api.DomainParam(r)
+		alias := chi.URLParam(r, "alias")
+
+		err := trashStorage.RestoreURL(r.Context(), domain, alias, userID)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("deleted url not found", slog.String("alias", alias))
+			resp.RenderError(w, r, http.StatusNotFound, resp.Error("url not found in trash"))
+			return
+		}
+		if errors.Is(err, storage.ErrURLNotOwned) {
+			log.Info("url not owned", slog.String("alias", alias))
+			resp.RenderError(w, r, http.StatusForbidden, resp.Error("url not owned"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to restore url", sl.Err(err))
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("failed to restore url"))
+			return
+		}
+
+		log.Info("url restored", slog.String("alias", alias))
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+		})
+	}
+}
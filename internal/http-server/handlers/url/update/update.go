@@ -0,0 +1,199 @@
+package update
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+
+	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/lib/api"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/events"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/tags"
+	"url-shortener/internal/lib/urlpolicy"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+// Request changes only the fields that are present. An empty tags list
+// removes all tags and an empty folder takes the link out of its folder.
+type Request struct {
+	URL    *string   `json:"url,omitempty" validate:"omitempty,url"`
+	Tags   *[]string `json:"tags,omitempty" validate:"omitempty,max=20,dive,max=50"`
+	Folder *string   `json:"folder,omitempty" validate:"omitempty,max=100"`
+	// Title, Description and Notes are cleared by an empty string.
+	Title       *string `json:"title,omitempty" validate:"omitempty,max=300"`
+	Description *string `json:"description,omitempty" validate:"omitempty,max=1000"`
+	Notes       *string `json:"notes,omitempty" validate:"omitempty,max=5000"`
+}
+
+type Response struct {
+	resp.Response
+}
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=URLUpdater
+type URLUpdater interface {
+	UpdateURL(ctx context.Context, domain string, alias string, userID int64, update models.URLUpdate) error
+}
+
+// URLChecker checks destination urls against the url policy.
+//
+//go:generate go run github.com/vektra/mockery/v2@latest --name=URLChecker
+type URLChecker interface {
+	Check(rawURL string) []urlpolicy.Violation
+}
+
+// EventPublisher publishes link events to webhooks.
+//
+//go:generate go run github.com/vektra/mockery/v2@latest --name=EventPublisher
+type EventPublisher interface {
+	Publish(ctx context.Context, event events.Event) error
+}
+
+// New updates the destination, labels or details of a link of the user. Links on
+// custom domains are addressed with the "domain" query parameter.
+func New(log *slog.Logger, urlUpdater URLUpdater, urlChecker URLChecker, eventPublisher EventPublisher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.update.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := r.Context().Value(auth.UserIDContextKey).(int64)
+		if !ok {
+			log.Error("user_id not found in context")
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("user_id not found in token"))
+			return
+		}
+
+		var req Request
+
+		err := render.DecodeJSON(r.Body, &req)
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+			resp.RenderError(w, r, http.StatusBadRequest, resp.Error("empty request"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			resp.RenderError(w, r, http.StatusBadRequest, resp.Error("failed to decode request"))
+			return
+		}
+
+		if err := validator.New().Struct(req); err != nil {
+			var validateErr validator.ValidationErrors
+			errors.As(err, &validateErr)
+			log.Error("invalid request", sl.Err(err))
+			resp.RenderError(w, r, http.StatusBadRequest, resp.ValidationError(validateErr))
+			return
+		}
+
+		if req == (Request{}) {
+			log.Info("nothing to update")
+			resp.RenderError(w, r, http.StatusBadRequest, resp.Error("nothing to update"))
+			return
+		}
+
+		update := models.URLUpdate{
+			URL:   req.URL,
+			Notes: req.Notes,
+		}
+
+		if req.URL != nil {
+			if violations := urlChecker.Check(*req.URL); len(violations) > 0 {
+				log.Info("url rejected by policy", slog.String("url", *req.URL), slog.Any("violations", violations))
+				resp.RenderError(w, r, http.StatusBadRequest, resp.FieldErrors(urlpolicy.FieldErrors("URL", violations)))
+				return
+			}
+		}
+		if req.Tags != nil {
+			normalized := tags.Normalize(*req.Tags)
+			update.Tags = &normalized
+		}
+		if req.Folder != nil {
+			folder := strings.TrimSpace(*req.Folder)
+			update.Folder = &folder
+		}
+		if req.Title != nil {
+			title := strings.TrimSpace(*req.Title)
+			update.Title = &title
+		}
+		if req.Description != nil {
+			description := strings.TrimSpace(*req.Description)
+			update.Description = &description
+		}
+
+		domain := api.DomainParam(r)
+		alias := chi.URLParam(r, "alias")
+
+		err = urlUpdater.UpdateURL(r.Context(), domain, alias, userID, update)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("url not found", slog.String("alias", alias))
+			resp.RenderError(w, r, http.StatusNotFound, resp.Error("url not found"))
+			return
+		}
+		if errors.Is(err, storage.ErrURLNotOwned) {
+			log.Info("url not owned", slog.String("alias", alias))
+			resp.RenderError(w, r, http.StatusForbidden, resp.Error("url not owned"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to update url", sl.Err(err))
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("failed to update url"))
+			return
+		}
+
+		log.Info("url updated", slog.String("alias", alias))
+
+		if err := eventPublisher.Publish(r.Context(), events.New(events.LinkUpdated, domain, alias, changes(update))); err != nil {
+			log.Error("failed to publish event", sl.Err(err))
+		}
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+		})
+	}
+}
+
+// changes describes the update for the link.updated event.
+func changes(update models.URLUpdate) events.LinkData {
+	var data events.LinkData
+
+	if update.URL != nil {
+		data.URL = *update.URL
+		data.Changed = append(data.Changed, "url")
+	}
+	if update.Tags != nil {
+		data.Tags = *update.Tags
+		data.Changed = append(data.Changed, "tags")
+	}
+	if update.Folder != nil {
+		data.Folder = *update.Folder
+		data.Changed = append(data.Changed, "folder")
+	}
+	if update.Title != nil {
+		data.Title = *update.Title
+		data.Changed = append(data.Changed, "title")
+	}
+	if update.Description != nil {
+		data.Description = *update.Description
+		data.Changed = append(data.Changed, "description")
+	}
+	// notes are private and stay out of webhooks
+	if update.Notes != nil {
+		data.Changed = append(data.Changed, "notes")
+	}
+
+	return data
+}
@@ -0,0 +1,141 @@
+package update_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/update"
+	"url-shortener/internal/http-server/handlers/url/update/mocks"
+	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/lib/events"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/lib/urlpolicy"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+func Ptr[T any](v T) *T {
+	return &v
+}
+
+func TestUpdateHandler(t *testing.T) {
+	cases := []struct {
+		name       string
+		body       string
+		update     *models.URLUpdate
+		violations []urlpolicy.Violation
+		mockError  error
+		code       int
+		respError  string
+	}{
+		{
+			name:   "Tags and folder",
+			body:   `{"tags": [" Go", "news", "go"], "folder": " Reading "}`,
+			update: &models.URLUpdate{Tags: Ptr([]string{"go", "news"}), Folder: Ptr("Reading")},
+			code:   http.StatusOK,
+		},
+		{
+			name:   "Clear tags",
+			body:   `{"tags": []}`,
+			update: &models.URLUpdate{Tags: Ptr([]string{})},
+			code:   http.StatusOK,
+		},
+		{
+			name:   "URL",
+			body:   `{"url": "https://example.com"}`,
+			update: &models.URLUpdate{URL: Ptr("https://example.com")},
+			code:   http.StatusOK,
+		},
+		{
+			name:   "Details",
+			body:   `{"title": " Docs ", "notes": "check later"}`,
+			update: &models.URLUpdate{Title: Ptr("Docs"), Notes: Ptr("check later")},
+			code:   http.StatusOK,
+		},
+		{
+			name:      "Nothing to update",
+			body:      `{}`,
+			code:      http.StatusBadRequest,
+			respError: "nothing to update",
+		},
+		{
+			name:      "Invalid URL",
+			body:      `{"url": ""}`,
+			code:      http.StatusBadRequest,
+			respError: "field URL is not a valid URL",
+		},
+		{
+			name: "Denied URL",
+			body: `{"url": "https://evil.example.com"}`,
+			violations: []urlpolicy.Violation{
+				{Code: urlpolicy.CodeDomainDenied, Message: "domain evil.example.com is not allowed"},
+			},
+			code:      http.StatusBadRequest,
+			respError: "field URL: domain evil.example.com is not allowed",
+		},
+		{
+			name:      "Not owned",
+			body:      `{"folder": ""}`,
+			update:    &models.URLUpdate{Folder: Ptr("")},
+			mockError: storage.ErrURLNotOwned,
+			code:      http.StatusForbidden,
+			respError: "url not owned",
+		},
+		{
+			name:      "Storage error",
+			body:      `{"folder": "x"}`,
+			update:    &models.URLUpdate{Folder: Ptr("x")},
+			mockError: errors.New("unexpected error"),
+			code:      http.StatusInternalServerError,
+			respError: "failed to update url",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlUpdaterMock := mocks.NewURLUpdater(t)
+			urlCheckerMock := mocks.NewURLChecker(t)
+			eventPublisherMock := mocks.NewEventPublisher(t)
+
+			if strings.Contains(tc.body, "https://") {
+				urlCheckerMock.On("Check", mock.AnythingOfType("string")).Return(tc.violations).Once()
+			}
+			if tc.update != nil {
+				urlUpdaterMock.On("UpdateURL", mock.Anything, "", "abc", int64(1), *tc.update).
+					Return(tc.mockError).
+					Once()
+			}
+			if tc.update != nil && tc.mockError == nil {
+				eventPublisherMock.On("Publish", mock.Anything, mock.MatchedBy(func(e events.Event) bool {
+					return e.Type == events.LinkUpdated && e.Alias == "abc"
+				})).Return(nil).Once()
+			}
+
+			router := chi.NewRouter()
+			router.Patch("/url/{alias}", update.New(slogdiscard.NewDiscardLogger(), urlUpdaterMock, urlCheckerMock, eventPublisherMock))
+
+			req := httptest.NewRequest(http.MethodPatch, "/url/abc", strings.NewReader(tc.body))
+			req = req.WithContext(context.WithValue(req.Context(), auth.UserIDContextKey, int64(1)))
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.code, rr.Code)
+
+			var resp update.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+		})
+	}
+}
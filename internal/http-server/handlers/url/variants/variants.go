@@ -0,0 +1,175 @@
+package variants
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+
+	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/lib/api"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/urlpolicy"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+type Variant struct {
+	ID     int64  `json:"id,omitempty"`
+	URL    string `json:"url" validate:"required,url"`
+	Weight int    `json:"weight" validate:"required,min=1,max=10000"`
+}
+
+// Request replaces all split destinations of the link. An empty list turns splitting off.
+type Request struct {
+	Variants []Variant `json:"variants" validate:"max=20,dive"`
+}
+
+type Response struct {
+	resp.Response
+	Variants []models.Variant `json:"variants,omitempty"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=VariantsStorage
+type VariantsStorage interface {
+	GetUserURLVariants(ctx context.Context, domain string, alias string, userID int64) ([]models.Variant, error)
+	SetURLVariants(ctx context.Context, domain string, alias string, userID int64, variants []models.Variant) error
+}
+
+// URLChecker checks destination urls against the url policy.
+//
+//go:generate go run github.com/vektra/mockery/v2@latest --name=URLChecker
+type URLChecker interface {
+	Check(rawURL string) []urlpolicy.Violation
+}
+
+// NewList returns split destinations of the link with per-variant click counts.
+func NewList(log *slog.Logger, variantsStorage VariantsStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.variants.NewList"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := r.Context().Value(auth.UserIDContextKey).(int64)
+		if !ok {
+			log.Error("user_id not found in context")
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("user_id not found in token"))
+			return
+		}
+
+		domain := api.DomainParam(r)
+		alias := chi.URLParam(r, "alias")
+
+		variants, err := variantsStorage.GetUserURLVariants(r.Context(), domain, alias, userID)
+		if err != nil {
+			renderStorageError(w, r, log, err, "failed to get variants")
+			return
+		}
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Variants: variants,
+		})
+	}
+}
+
+// NewSet replaces split destinations of the link.
+func NewSet(log *slog.Logger, variantsStorage VariantsStorage, urlChecker URLChecker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.variants.NewSet"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := r.Context().Value(auth.UserIDContextKey).(int64)
+		if !ok {
+			log.Error("user_id not found in context")
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("user_id not found in token"))
+			return
+		}
+
+		domain := api.DomainParam(r)
+		alias := chi.URLParam(r, "alias")
+
+		var req Request
+
+		err := render.DecodeJSON(r.Body, &req)
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+			resp.RenderError(w, r, http.StatusBadRequest, resp.Error("empty request"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			resp.RenderError(w, r, http.StatusBadRequest, resp.Error("failed to decode request"))
+			return
+		}
+
+		if err := validator.New().Struct(req); err != nil {
+			var validateErr validator.ValidationErrors
+			errors.As(err, &validateErr)
+			log.Error("invalid request", sl.Err(err))
+			resp.RenderError(w, r, http.StatusBadRequest, resp.ValidationError(validateErr))
+			return
+		}
+
+		var policyErrs []resp.FieldError
+		for i, v := range req.Variants {
+			if violations := urlChecker.Check(v.URL); len(violations) > 0 {
+				policyErrs = append(policyErrs, urlpolicy.FieldErrors(fmt.Sprintf("Variants[%d].URL", i), violations)...)
+			}
+		}
+		if len(policyErrs) > 0 {
+			log.Info("url rejected by policy", slog.Any("errors", policyErrs))
+			resp.RenderError(w, r, http.StatusBadRequest, resp.FieldErrors(policyErrs))
+			return
+		}
+
+		variants := make([]models.Variant, 0, len(req.Variants))
+		for _, v := range req.Variants {
+			variants = append(variants, models.Variant{ID: v.ID, URL: v.URL, Weight: v.Weight})
+		}
+
+		err = variantsStorage.SetURLVariants(r.Context(), domain, alias, userID, variants)
+		if err != nil {
+			renderStorageError(w, r, log, err, "failed to set variants")
+			return
+		}
+
+		log.Info("variants updated", slog.Int("count", len(variants)))
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+		})
+	}
+}
+
+func renderStorageError(w http.ResponseWriter, r *http.Request, log *slog.Logger, err error, msg string) {
+	switch {
+	case errors.Is(err, storage.ErrURLNotFound):
+		log.Info("url not found", sl.Err(err))
+		resp.RenderError(w, r, http.StatusNotFound, resp.Error("url not found"))
+	case errors.Is(err, storage.ErrURLNotOwned):
+		log.Info("url not owned", sl.Err(err))
+		resp.RenderError(w, r, http.StatusForbidden, resp.Error("url not owned"))
+	case errors.Is(err, storage.ErrVariantNotFound):
+		log.Info("variant not found", sl.Err(err))
+		resp.RenderError(w, r, http.StatusNotFound, resp.Error("variant not found"))
+	default:
+		log.Error(msg, sl.Err(err))
+		resp.RenderError(w, r, http.StatusInternalServerError, resp.Error(msg))
+	}
+}
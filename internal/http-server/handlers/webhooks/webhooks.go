@@ -0,0 +1,284 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+
+	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/lib/api"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/tags"
+	"url-shortener/internal/lib/webhook"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+type Request struct {
+	URL    string   `json:"url" validate:"required,http_url,max=2048"`
+	Events []string `json:"events" validate:"required,min=1,dive,oneof=link.created link.updated link.deleted link.clicked"`
+}
+
+type Response struct {
+	resp.Response
+	Webhook    *models.Webhook          `json:"webhook,omitempty"`
+	Webhooks   []models.Webhook         `json:"webhooks,omitempty"`
+	Deliveries []models.WebhookDelivery `json:"deliveries,omitempty"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=WebhookStorage
+type WebhookStorage interface {
+	SaveWebhook(ctx context.Context, webhook models.Webhook) (int64, error)
+	GetUserWebhooks(ctx context.Context, userID int64) ([]models.Webhook, error)
+	DeleteWebhook(ctx context.Context, userID int64, webhookID int64) error
+	GetWebhookDeliveries(ctx context.Context, userID int64, webhookID int64, limit int, offset int) ([]models.WebhookDelivery, error)
+	RedeliverWebhook(ctx context.Context, userID int64, webhookID int64, deliveryID int64) error
+}
+
+// NewCreate registers a webhook. The signing secret is returned only here.
+func NewCreate(log *slog.Logger, webhookStorage WebhookStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.webhooks.NewCreate"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := userIDFrom(w, r, log)
+		if !ok {
+			return
+		}
+
+		var req Request
+
+		err := render.DecodeJSON(r.Body, &req)
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+			resp.RenderError(w, r, http.StatusBadRequest, resp.Error("empty request"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			resp.RenderError(w, r, http.StatusBadRequest, resp.Error("failed to decode request"))
+			return
+		}
+
+		if err := validator.New().Struct(req); err != nil {
+			var validateErr validator.ValidationErrors
+			errors.As(err, &validateErr)
+			log.Error("invalid request", sl.Err(err))
+			resp.RenderError(w, r, http.StatusBadRequest, resp.ValidationError(validateErr))
+			return
+		}
+
+		secret, err := webhook.NewSecret()
+		if err != nil {
+			log.Error("failed to generate secret", sl.Err(err))
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("failed to create webhook"))
+			return
+		}
+
+		hook := models.Webhook{
+			UserID: userID,
+			URL:    req.URL,
+			// the event names are already lowercase, this only drops duplicates
+			Events: tags.Normalize(req.Events),
+			Secret: secret,
+		}
+
+		hook.ID, err = webhookStorage.SaveWebhook(r.Context(), hook)
+		if err != nil {
+			log.Error("failed to save webhook", sl.Err(err))
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("failed to create webhook"))
+			return
+		}
+
+		log.Info("webhook created", slog.Int64("id", hook.ID))
+
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Webhook:  &hook,
+		})
+	}
+}
+
+// NewList returns webhooks of the user.
+func NewList(log *slog.Logger, webhookStorage WebhookStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.webhooks.NewList"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := userIDFrom(w, r, log)
+		if !ok {
+			return
+		}
+
+		hooks, err := webhookStorage.GetUserWebhooks(r.Context(), userID)
+		if err != nil {
+			log.Error("failed to get webhooks", sl.Err(err))
+			resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("failed to get webhooks"))
+			return
+		}
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Webhooks: hooks,
+		})
+	}
+}
+
+// NewDelete removes a webhook with its delivery log.
+func NewDelete(log *slog.Logger, webhookStorage WebhookStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.webhooks.NewDelete"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := userIDFrom(w, r, log)
+		if !ok {
+			return
+		}
+		webhookID, ok := idParam(w, r, log, "id")
+		if !ok {
+			return
+		}
+
+		if err := webhookStorage.DeleteWebhook(r.Context(), userID, webhookID); err != nil {
+			renderStorageError(w, r, log, err, "failed to delete webhook")
+			return
+		}
+
+		log.Info("webhook deleted", slog.Int64("id", webhookID))
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+		})
+	}
+}
+
+// NewDeliveries returns the delivery log of a webhook, newest first, paged
+// with the "limit" and "offset" query parameters.
+func NewDeliveries(log *slog.Logger, webhookStorage WebhookStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.webhooks.NewDeliveries"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := userIDFrom(w, r, log)
+		if !ok {
+			return
+		}
+		webhookID, ok := idParam(w, r, log, "id")
+		if !ok {
+			return
+		}
+		limit, offset, ok := api.ParsePage(w, r, log)
+		if !ok {
+			return
+		}
+
+		deliveries, err := webhookStorage.GetWebhookDeliveries(r.Context(), userID, webhookID, limit, offset)
+		if err != nil {
+			renderStorageError(w, r, log, err, "failed to get deliveries")
+			return
+		}
+
+		render.JSON(w, r, Response{
+			Response:   resp.OK(),
+			Deliveries: deliveries,
+		})
+	}
+}
+
+// NewRedeliver queues a delivery again, failed or not.
+func NewRedeliver(log *slog.Logger, webhookStorage WebhookStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.webhooks.NewRedeliver"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := userIDFrom(w, r, log)
+		if !ok {
+			return
+		}
+		webhookID, ok := idParam(w, r, log, "id")
+		if !ok {
+			return
+		}
+		deliveryID, ok := idParam(w, r, log, "deliveryID")
+		if !ok {
+			return
+		}
+
+		if err := webhookStorage.RedeliverWebhook(r.Context(), userID, webhookID, deliveryID); err != nil {
+			renderStorageError(w, r, log, err, "failed to redeliver")
+			return
+		}
+
+		log.Info("delivery queued again", slog.Int64("webhook_id", webhookID), slog.Int64("delivery_id", deliveryID))
+
+		render.Status(r, http.StatusAccepted)
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+		})
+	}
+}
+
+func userIDFrom(w http.ResponseWriter, r *http.Request, log *slog.Logger) (int64, bool) {
+	userID, ok := r.Context().Value(auth.UserIDContextKey).(int64)
+	if !ok {
+		log.Error("user_id not found in context")
+		resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("user_id not found in token"))
+	}
+
+	return userID, ok
+}
+
+func idParam(w http.ResponseWriter, r *http.Request, log *slog.Logger, name string) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, name), 10, 64)
+	if err != nil {
+		log.Info("invalid id", slog.String(name, chi.URLParam(r, name)))
+		resp.RenderError(w, r, http.StatusBadRequest, resp.Error("invalid "+name))
+		return 0, false
+	}
+
+	return id, true
+}
+
+func renderStorageError(w http.ResponseWriter, r *http.Request, log *slog.Logger, err error, msg string) {
+	switch {
+	case errors.Is(err, storage.ErrWebhookNotFound):
+		log.Info("webhook not found", sl.Err(err))
+		resp.RenderError(w, r, http.StatusNotFound, resp.Error("webhook not found"))
+	case errors.Is(err, storage.ErrDeliveryNotFound):
+		log.Info("delivery not found", sl.Err(err))
+		resp.RenderError(w, r, http.StatusNotFound, resp.Error("delivery not found"))
+	default:
+		log.Error(msg, sl.Err(err))
+		resp.RenderError(w, r, http.StatusInternalServerError, resp.Error(msg))
+	}
+}
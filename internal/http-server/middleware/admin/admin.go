@@ -0,0 +1,101 @@
+package admin
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+
+	"url-shortener/internal/http-server/middleware/auth"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+)
+
+// AdminChecker is implemented by the SSO client.
+//
+//go:generate go run github.com/vektra/mockery/v2@latest --name=AdminChecker
+type AdminChecker interface {
+	IsAdmin(ctx context.Context, userID int64) (bool, error)
+}
+
+type cacheEntry struct {
+	isAdmin bool
+	expires time.Time
+}
+
+// cache keeps IsAdmin answers for ttl so every admin request does not hit the SSO service.
+type cache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[int64]cacheEntry
+}
+
+func (c *cache) get(userID int64, now time.Time) (bool, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[userID]
+	if !ok || !now.Before(e.expires) {
+		delete(c.entries, userID)
+		return false, false
+	}
+
+	return e.isAdmin, true
+}
+
+func (c *cache) set(userID int64, isAdmin bool, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[userID] = cacheEntry{isAdmin: isAdmin, expires: now.Add(c.ttl)}
+}
+
+// New only lets admins through. It must run after the auth middleware.
+// Answers of adminChecker are cached for ttl, failed checks are not cached.
+func New(log *slog.Logger, adminChecker AdminChecker, ttl time.Duration) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		op := "middleware.admin.New"
+
+		c := &cache{ttl: ttl, entries: make(map[int64]cacheEntry)}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			log := log.With(
+				slog.String("op", op),
+				slog.String("request_id", middleware.GetReqID(r.Context())),
+			)
+
+			userID, ok := r.Context().Value(auth.UserIDContextKey).(int64)
+			if !ok {
+				log.Error("user_id not found in context")
+				resp.RenderStatusError(w, r, http.StatusUnauthorized)
+				return
+			}
+
+			now := time.Now()
+
+			isAdmin, cached := c.get(userID, now)
+			if !cached {
+				var err error
+				isAdmin, err = adminChecker.IsAdmin(r.Context(), userID)
+				if err != nil {
+					log.Error("failed to check if user is admin", sl.Err(err))
+					resp.RenderStatusError(w, r, http.StatusInternalServerError)
+					return
+				}
+				c.set(userID, isAdmin, now)
+			}
+
+			if !isAdmin {
+				log.Info("user is not admin", slog.Int64("user_id", userID))
+				resp.RenderStatusError(w, r, http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
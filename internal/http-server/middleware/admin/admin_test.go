@@ -0,0 +1,65 @@
+package admin_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	mwAdmin "url-shortener/internal/http-server/middleware/admin"
+	"url-shortener/internal/http-server/middleware/admin/mocks"
+	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+func serve(h http.Handler, userID int64) int {
+	req := httptest.NewRequest(http.MethodGet, "/admin/stats", nil)
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserIDContextKey, userID))
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	return rr.Code
+}
+
+func TestAdminMiddleware(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	t.Run("Cached", func(t *testing.T) {
+		checker := mocks.NewAdminChecker(t)
+		checker.On("IsAdmin", mock.Anything, int64(1)).Return(true, nil).Once()
+		checker.On("IsAdmin", mock.Anything, int64(2)).Return(false, nil).Once()
+
+		h := mwAdmin.New(slogdiscard.NewDiscardLogger(), checker, time.Minute)(next)
+
+		assert.Equal(t, http.StatusOK, serve(h, 1))
+		assert.Equal(t, http.StatusOK, serve(h, 1))
+		assert.Equal(t, http.StatusForbidden, serve(h, 2))
+		assert.Equal(t, http.StatusForbidden, serve(h, 2))
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		checker := mocks.NewAdminChecker(t)
+		checker.On("IsAdmin", mock.Anything, int64(1)).Return(true, nil).Twice()
+
+		h := mwAdmin.New(slogdiscard.NewDiscardLogger(), checker, 0)(next)
+
+		assert.Equal(t, http.StatusOK, serve(h, 1))
+		assert.Equal(t, http.StatusOK, serve(h, 1))
+	})
+
+	t.Run("Errors are not cached", func(t *testing.T) {
+		checker := mocks.NewAdminChecker(t)
+		checker.On("IsAdmin", mock.Anything, int64(1)).Return(false, errors.New("unavailable")).Once()
+		checker.On("IsAdmin", mock.Anything, int64(1)).Return(true, nil).Once()
+
+		h := mwAdmin.New(slogdiscard.NewDiscardLogger(), checker, time.Minute)(next)
+
+		assert.Equal(t, http.StatusInternalServerError, serve(h, 1))
+		assert.Equal(t, http.StatusOK, serve(h, 1))
+	})
+}
@@ -0,0 +1,37 @@
+package audit
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+
+	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/lib/audit"
+	"url-shortener/internal/lib/targeting"
+)
+
+// New attaches the audit actor to the request context. It must run after the
+// auth middleware on protected routes, anonymous requests get an actor
+// without a user.
+func New() func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r = r.WithContext(audit.WithActor(r.Context(), FromRequest(r)))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// FromRequest builds the actor from the auth context, request id and client IP.
+func FromRequest(r *http.Request) audit.Actor {
+	actor := audit.Actor{
+		RequestID: middleware.GetReqID(r.Context()),
+	}
+	actor.UserID, _ = r.Context().Value(auth.UserIDContextKey).(int64)
+	actor.Email, _ = r.Context().Value(auth.UserEmailContextKey).(string)
+	if ip := targeting.ClientIP(r); ip != nil {
+		actor.IP = ip.String()
+	}
+
+	return actor
+}
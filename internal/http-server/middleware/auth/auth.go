@@ -2,11 +2,14 @@ package auth
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"strconv"
 
 	"url-shortener/internal/config"
+	resp "url-shortener/internal/lib/api/response"
 	"url-shortener/internal/lib/logger/sl"
 
 	"github.com/go-chi/chi/v5/middleware"
@@ -30,43 +33,15 @@ func New(log *slog.Logger, cfg *config.AppConfig) func(next http.Handler) http.H
 			cookie, err := r.Cookie("auth_token")
 			if err != nil {
 				log.Error("failed to get auth token from cookie", sl.Err(err))
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				resp.RenderStatusError(w, r, http.StatusUnauthorized)
 				return
 			}
 
 			
-			tokenParsed, err := jwt.Parse(cookie.Value, func(cookie *jwt.Token) (interface{}, error) {
-				return []byte(cfg.AppSecret), nil
-			})
+			userId, userEmail, err := ParseToken(cookie.Value, cfg.AppSecret)
 			if err != nil {
 				log.Error("failed to parse auth token", sl.Err(err))
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
-				return
-			}
-			tokenClaims, _ := tokenParsed.Claims.(jwt.MapClaims)
-
-			var userId int64
-			switch v := tokenClaims["uid"].(type) {
-			// go читает числа из json как float64
-			case float64:
-				userId = int64(v)
-			case string:
-				userId, err = strconv.ParseInt(v, 10, 64)
-				if err != nil {
-					log.Error("failed to parse user id", sl.Err(err))
-					http.Error(w, "Unauthorized", http.StatusUnauthorized)
-					return
-				}
-			default:
-				log.Error("unexpected uid type", slog.Any("type", v))
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
-				return
-			}
-			
-			userEmail, ok := tokenClaims["email"].(string)
-			if !ok {
-				log.Error("failed to get user email", sl.Err(err))
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				resp.RenderStatusError(w, r, http.StatusUnauthorized)
 				return
 			}
 
@@ -75,4 +50,37 @@ func New(log *slog.Logger, cfg *config.AppConfig) func(next http.Handler) http.H
 			next.ServeHTTP(w, r)
 		})
 	}
-}
\ No newline at end of file
+}
+
+// ParseToken validates a token issued by the SSO service and returns the id
+// and email of the user it was issued to.
+func ParseToken(token string, secret string) (int64, string, error) {
+	tokenParsed, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	})
+	if err != nil {
+		return 0, "", err
+	}
+	tokenClaims, _ := tokenParsed.Claims.(jwt.MapClaims)
+
+	var userID int64
+	switch v := tokenClaims["uid"].(type) {
+	// go читает числа из json как float64
+	case float64:
+		userID = int64(v)
+	case string:
+		userID, err = strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return 0, "", fmt.Errorf("parse user id: %w", err)
+		}
+	default:
+		return 0, "", fmt.Errorf("unexpected uid type %T", v)
+	}
+
+	userEmail, ok := tokenClaims["email"].(string)
+	if !ok {
+		return 0, "", errors.New("email claim is missing")
+	}
+
+	return userID, userEmail, nil
+}
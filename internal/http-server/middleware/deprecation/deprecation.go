@@ -0,0 +1,32 @@
+// Package deprecation marks responses of deprecated routes.
+package deprecation
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// New adds the Deprecation (RFC 9745) and Sunset (RFC 8594) headers to every
+// response and links the same path under successorPrefix as the successor
+// version. A zero deprecatedAt sends "Deprecation: true", a zero sunset
+// sends no Sunset header.
+func New(deprecatedAt time.Time, sunset time.Time, successorPrefix string) func(next http.Handler) http.Handler {
+	deprecation := "true"
+	if !deprecatedAt.IsZero() {
+		deprecation = "@" + strconv.FormatInt(deprecatedAt.Unix(), 10)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			h.Set("Deprecation", deprecation)
+			if !sunset.IsZero() {
+				h.Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+			}
+			h.Add("Link", "<"+successorPrefix+r.URL.Path+`>; rel="successor-version"`)
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package deprecation_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"url-shortener/internal/http-server/middleware/deprecation"
+)
+
+func TestDeprecation(t *testing.T) {
+	cases := []struct {
+		name         string
+		deprecatedAt time.Time
+		sunset       time.Time
+		deprecation  string
+		sunsetHeader string
+	}{
+		{
+			name:         "Dates",
+			deprecatedAt: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+			sunset:       time.Date(2027, 4, 1, 0, 0, 0, 0, time.UTC),
+			deprecation:  "@1790812800",
+			sunsetHeader: "Thu, 01 Apr 2027 00:00:00 GMT",
+		},
+		{
+			name:        "No dates",
+			deprecation: "true",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusTeapot)
+			})
+
+			rr := httptest.NewRecorder()
+			deprecation.New(tc.deprecatedAt, tc.sunset, "/api/v1")(next).
+				ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/url/abc", nil))
+
+			assert.Equal(t, http.StatusTeapot, rr.Code)
+			assert.Equal(t, tc.deprecation, rr.Header().Get("Deprecation"))
+			assert.Equal(t, tc.sunsetHeader, rr.Header().Get("Sunset"))
+			assert.Equal(t, `</api/v1/url/abc>; rel="successor-version"`, rr.Header().Get("Link"))
+		})
+	}
+}
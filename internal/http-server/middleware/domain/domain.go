@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/hostname"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+// DomainContextKey holds the custom domain the request was made to.
+// It is empty for the default short domain.
+const DomainContextKey = "domain"
+
+//go:generate go run github.com/vektra/mockery/v2@latest --name=DomainGetter
+type DomainGetter interface {
+	GetDomain(ctx context.Context, hostname string) (models.Domain, error)
+}
+
+// New resolves the request Host into a verified custom domain. Unknown and
+// unverified hosts are served from the default domain.
+func New(log *slog.Logger, domainGetter DomainGetter) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		op := "middleware.domain.New"
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			log := log.With(
+				slog.String("op", op),
+				slog.String("request_id", middleware.GetReqID(r.Context())),
+			)
+
+			hostname := Hostname(r.Host)
+
+			var resolved string
+
+			d, err := domainGetter.GetDomain(r.Context(), hostname)
+			switch {
+			case errors.Is(err, storage.ErrDomainNotFound):
+			case err != nil:
+				log.Error("failed to get domain", sl.Err(err))
+				resp.RenderStatusError(w, r, http.StatusInternalServerError)
+				return
+			case d.Verified():
+				resolved = d.Hostname
+			}
+
+			r = r.WithContext(context.WithValue(r.Context(), DomainContextKey, resolved))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Hostname normalizes a Host header value: the port is dropped and the name is lower-cased.
+func Hostname(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	return hostname.Normalize(host)
+}
@@ -0,0 +1,211 @@
+// Package idempotency replays responses to mutating requests retried with the
+// same Idempotency-Key header.
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+
+	"url-shortener/internal/http-server/middleware/auth"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+const (
+	// Header carries the key a client picks for a request and reuses on retries.
+	Header = "Idempotency-Key"
+	// ReplayedHeader is set on responses replayed from a previous request.
+	ReplayedHeader = "Idempotent-Replayed"
+
+	maxKeyLength = 255
+	// maxBodySize bounds the body held in memory to hash it, JSON API
+	// requests are far smaller.
+	maxBodySize = 1 << 20
+	// v1Prefix is the path of the v1 API, which the deprecated root routes
+	// serve as well.
+	v1Prefix = "/api/v1"
+)
+
+// Store keeps the keys with the responses of their requests.
+type Store interface {
+	SaveIdempotencyKey(ctx context.Context, key models.IdempotencyKey, expiredBefore time.Time) error
+	GetIdempotencyKey(ctx context.Context, userID int64, key string) (models.IdempotencyKey, error)
+	CompleteIdempotencyKey(ctx context.Context, key models.IdempotencyKey) error
+	DeleteIdempotencyKey(ctx context.Context, userID int64, key string) error
+}
+
+// New stores the first response to a POST, PUT, PATCH or DELETE request sent
+// with an Idempotency-Key header per user and key for ttl, and replays it to
+// retries. A retry with another method, path or body gets 422, a retry while
+// the first request is in flight gets 409. Bodies over 1 MiB get 413. Server
+// errors are not stored so the request can be retried. It must run after the
+// auth middleware.
+func New(log *slog.Logger, store Store, ttl time.Duration) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		const op = "middleware.idempotency.New"
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(Header)
+			if key == "" || !mutating(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			log := log.With(
+				slog.String("op", op),
+				slog.String("request_id", middleware.GetReqID(r.Context())),
+			)
+
+			if len(key) > maxKeyLength {
+				log.Info("idempotency key too long", slog.Int("length", len(key)))
+				resp.RenderError(w, r, http.StatusBadRequest, resp.Error("idempotency key is too long"))
+				return
+			}
+
+			userID, ok := r.Context().Value(auth.UserIDContextKey).(int64)
+			if !ok {
+				log.Error("user_id not found in context")
+				resp.RenderStatusError(w, r, http.StatusUnauthorized)
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				log.Info("request body too large", slog.Int64("limit", tooLarge.Limit))
+				resp.RenderError(w, r, http.StatusRequestEntityTooLarge, resp.Error("request body is too large"))
+				return
+			}
+			if err != nil {
+				log.Error("failed to read request body", sl.Err(err))
+				resp.RenderError(w, r, http.StatusBadRequest, resp.Error("failed to read request"))
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			k := models.IdempotencyKey{UserID: userID, Key: key, RequestHash: requestHash(r, body)}
+
+			err = store.SaveIdempotencyKey(r.Context(), k, time.Now().Add(-ttl))
+			if errors.Is(err, storage.ErrIdempotencyKeyExists) {
+				replay(w, r, log, store, k)
+				return
+			}
+			if err != nil {
+				log.Error("failed to save idempotency key", sl.Err(err))
+				resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("internal error"))
+				return
+			}
+
+			// the client may be gone by the time the handler is done, the
+			// response is stored for its retry anyway
+			ctx := context.WithoutCancel(r.Context())
+
+			completed := false
+			defer func() {
+				if completed {
+					return
+				}
+				if err := store.DeleteIdempotencyKey(ctx, userID, key); err != nil {
+					log.Error("failed to release idempotency key", sl.Err(err))
+				}
+			}()
+
+			var buf bytes.Buffer
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			ww.Tee(&buf)
+
+			next.ServeHTTP(ww, r)
+
+			k.Status = ww.Status()
+			if k.Status == 0 {
+				k.Status = http.StatusOK
+			}
+			if k.Status >= http.StatusInternalServerError {
+				return
+			}
+			k.ContentType = ww.Header().Get("Content-Type")
+			k.Body = buf.Bytes()
+
+			if err := store.CompleteIdempotencyKey(ctx, k); err != nil {
+				log.Error("failed to store idempotent response", sl.Err(err))
+				return
+			}
+			completed = true
+		})
+	}
+}
+
+// replay answers a request whose key is taken with the stored response.
+func replay(w http.ResponseWriter, r *http.Request, log *slog.Logger, store Store, k models.IdempotencyKey) {
+	stored, err := store.GetIdempotencyKey(r.Context(), k.UserID, k.Key)
+	if errors.Is(err, storage.ErrIdempotencyKeyNotFound) {
+		// released by a failed request since it was claimed
+		log.Info("idempotency key released concurrently")
+		resp.RenderError(w, r, http.StatusConflict, resp.Error("request with this idempotency key is in progress"))
+		return
+	}
+	if err != nil {
+		log.Error("failed to get idempotency key", sl.Err(err))
+		resp.RenderError(w, r, http.StatusInternalServerError, resp.Error("internal error"))
+		return
+	}
+
+	if stored.RequestHash != k.RequestHash {
+		log.Info("idempotency key reused for another request")
+		resp.RenderError(w, r, http.StatusUnprocessableEntity,
+			resp.Error("idempotency key was used for a different request"))
+		return
+	}
+
+	if !stored.Completed() {
+		log.Info("request with idempotency key in progress")
+		resp.RenderError(w, r, http.StatusConflict, resp.Error("request with this idempotency key is in progress"))
+		return
+	}
+
+	log.Info("replaying idempotent response", slog.Int("status", stored.Status))
+
+	if stored.ContentType != "" {
+		w.Header().Set("Content-Type", stored.ContentType)
+	}
+	w.Header().Set(ReplayedHeader, "true")
+	w.WriteHeader(stored.Status)
+	_, _ = w.Write(stored.Body)
+}
+
+// requestHash identifies a request by its method, path with query and body.
+// The v1 prefix is dropped so clients moving off the deprecated root routes
+// can retry on either path.
+func requestHash(r *http.Request, body []byte) string {
+	uri := r.URL.RequestURI()
+	if rest, ok := strings.CutPrefix(uri, v1Prefix+"/"); ok {
+		uri = "/" + rest
+	}
+
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + uri + "\n"))
+	h.Write(body)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func mutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
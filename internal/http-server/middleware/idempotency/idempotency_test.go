@@ -0,0 +1,233 @@
+package idempotency_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/http-server/middleware/idempotency"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+type memStore struct {
+	mu   sync.Mutex
+	keys map[string]models.IdempotencyKey
+}
+
+func newMemStore() *memStore {
+	return &memStore{keys: make(map[string]models.IdempotencyKey)}
+}
+
+func id(userID int64, key string) string {
+	return fmt.Sprintf("%d/%s", userID, key)
+}
+
+func (s *memStore) SaveIdempotencyKey(_ context.Context, key models.IdempotencyKey, expiredBefore time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if k, ok := s.keys[id(key.UserID, key.Key)]; ok && !k.CreatedAt.Before(expiredBefore) {
+		return storage.ErrIdempotencyKeyExists
+	}
+	key.CreatedAt = time.Now()
+	s.keys[id(key.UserID, key.Key)] = key
+
+	return nil
+}
+
+func (s *memStore) GetIdempotencyKey(_ context.Context, userID int64, key string) (models.IdempotencyKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k, ok := s.keys[id(userID, key)]
+	if !ok {
+		return models.IdempotencyKey{}, storage.ErrIdempotencyKeyNotFound
+	}
+
+	return k, nil
+}
+
+func (s *memStore) CompleteIdempotencyKey(_ context.Context, key models.IdempotencyKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := s.keys[id(key.UserID, key.Key)]
+	k.Status, k.ContentType, k.Body = key.Status, key.ContentType, key.Body
+	s.keys[id(key.UserID, key.Key)] = k
+
+	return nil
+}
+
+func (s *memStore) DeleteIdempotencyKey(_ context.Context, userID int64, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.keys, id(userID, key))
+
+	return nil
+}
+
+func serve(h http.Handler, method string, key string, body string) *httptest.ResponseRecorder {
+	return serveAt(h, method, "/url", key, body)
+}
+
+func serveAt(h http.Handler, method string, path string, key string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(idempotency.Header, key)
+	}
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserIDContextKey, int64(1)))
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	return rr
+}
+
+func TestIdempotency(t *testing.T) {
+	log := slogdiscard.NewDiscardLogger()
+
+	// counting handler, every call creates a new link
+	counter := func(status int) (http.Handler, *int) {
+		calls := 0
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			_, _ = fmt.Fprintf(w, `{"alias":"link%d"}`, calls)
+		}), &calls
+	}
+
+	t.Run("Replay", func(t *testing.T) {
+		next, calls := counter(http.StatusOK)
+		h := idempotency.New(log, newMemStore(), time.Hour)(next)
+
+		first := serve(h, http.MethodPost, "k1", `{"url":"https://example.com"}`)
+		retry := serve(h, http.MethodPost, "k1", `{"url":"https://example.com"}`)
+
+		assert.Equal(t, 1, *calls)
+		assert.Equal(t, http.StatusOK, retry.Code)
+		assert.Equal(t, first.Body.String(), retry.Body.String())
+		assert.Equal(t, "application/json", retry.Header().Get("Content-Type"))
+		assert.Equal(t, "true", retry.Header().Get(idempotency.ReplayedHeader))
+		assert.Empty(t, first.Header().Get(idempotency.ReplayedHeader))
+	})
+
+	t.Run("Different payload", func(t *testing.T) {
+		next, calls := counter(http.StatusOK)
+		h := idempotency.New(log, newMemStore(), time.Hour)(next)
+
+		serve(h, http.MethodPost, "k1", `{"url":"https://example.com"}`)
+		rr := serve(h, http.MethodPost, "k1", `{"url":"https://example.org"}`)
+
+		assert.Equal(t, 1, *calls)
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	})
+
+	t.Run("In flight", func(t *testing.T) {
+		store := newMemStore()
+		release := make(chan struct{})
+		started := make(chan struct{})
+		h := idempotency.New(log, store, time.Hour)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-release
+		}))
+
+		done := make(chan struct{})
+		go func() {
+			serve(h, http.MethodPost, "k1", `{}`)
+			close(done)
+		}()
+		<-started
+
+		assert.Equal(t, http.StatusConflict, serve(h, http.MethodPost, "k1", `{}`).Code)
+
+		close(release)
+		<-done
+	})
+
+	t.Run("Server errors are not stored", func(t *testing.T) {
+		next, calls := counter(http.StatusInternalServerError)
+		h := idempotency.New(log, newMemStore(), time.Hour)(next)
+
+		serve(h, http.MethodPost, "k1", `{}`)
+		rr := serve(h, http.MethodPost, "k1", `{}`)
+
+		assert.Equal(t, 2, *calls)
+		assert.Empty(t, rr.Header().Get(idempotency.ReplayedHeader))
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		next, calls := counter(http.StatusOK)
+		h := idempotency.New(log, newMemStore(), -time.Second)(next)
+
+		serve(h, http.MethodPost, "k1", `{}`)
+		serve(h, http.MethodPost, "k1", `{}`)
+
+		assert.Equal(t, 2, *calls)
+	})
+
+	t.Run("Without key or read only", func(t *testing.T) {
+		next, calls := counter(http.StatusOK)
+		h := idempotency.New(log, newMemStore(), time.Hour)(next)
+
+		serve(h, http.MethodPost, "", `{}`)
+		serve(h, http.MethodPost, "", `{}`)
+		serve(h, http.MethodGet, "k1", "")
+		serve(h, http.MethodGet, "k1", "")
+
+		assert.Equal(t, 4, *calls)
+	})
+
+	t.Run("Key too long", func(t *testing.T) {
+		next, calls := counter(http.StatusOK)
+		h := idempotency.New(log, newMemStore(), time.Hour)(next)
+
+		rr := serve(h, http.MethodPost, strings.Repeat("k", 256), `{}`)
+
+		assert.Equal(t, 0, *calls)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("Body too large", func(t *testing.T) {
+		next, calls := counter(http.StatusOK)
+		h := idempotency.New(log, newMemStore(), time.Hour)(next)
+
+		rr := serve(h, http.MethodPost, "k1", strings.Repeat("a", 1<<20+1))
+
+		assert.Equal(t, 0, *calls)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+	})
+
+	t.Run("Retry on the v1 path", func(t *testing.T) {
+		next, calls := counter(http.StatusOK)
+		h := idempotency.New(log, newMemStore(), time.Hour)(next)
+
+		first := serveAt(h, http.MethodPatch, "/url/abc?domain=example.com", "k1", `{}`)
+		retry := serveAt(h, http.MethodPatch, "/api/v1/url/abc?domain=example.com", "k1", `{}`)
+
+		assert.Equal(t, 1, *calls)
+		assert.Equal(t, first.Body.String(), retry.Body.String())
+		assert.Equal(t, "true", retry.Header().Get(idempotency.ReplayedHeader))
+	})
+
+	t.Run("Different path", func(t *testing.T) {
+		next, calls := counter(http.StatusOK)
+		h := idempotency.New(log, newMemStore(), time.Hour)(next)
+
+		serveAt(h, http.MethodPatch, "/api/v1/url/abc", "k1", `{}`)
+		rr := serveAt(h, http.MethodPatch, "/api/v1/url/def", "k1", `{}`)
+
+		assert.Equal(t, 1, *calls)
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	})
+}
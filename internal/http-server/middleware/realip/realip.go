@@ -0,0 +1,102 @@
+// Package realip sets the client address of requests coming through trusted
+// reverse proxies.
+package realip
+
+import (
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// New replaces r.RemoteAddr with the client address from X-Forwarded-For or
+// X-Real-IP, but only for requests sent by one of trustedProxies (IPs or
+// CIDRs). Other clients could pick any address with these headers, so they
+// are ignored for them. Invalid entries are logged and skipped.
+func New(log *slog.Logger, trustedProxies []string) func(next http.Handler) http.Handler {
+	var trusted []netip.Prefix
+	for _, p := range trustedProxies {
+		prefix, err := parsePrefix(p)
+		if err != nil {
+			log.Error("invalid trusted proxy, ignored", slog.String("proxy", p))
+			continue
+		}
+		trusted = append(trusted, prefix)
+	}
+
+	isTrusted := func(addr netip.Addr) bool {
+		addr = addr.Unmap()
+		for _, p := range trusted {
+			if p.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if len(trusted) > 0 {
+				if peer, ok := remoteAddr(r.RemoteAddr); ok && isTrusted(peer) {
+					if ip, ok := clientIP(r.Header, isTrusted); ok {
+						r.RemoteAddr = ip.String()
+					}
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clientIP returns the right-most address of X-Forwarded-For that isn't a
+// trusted proxy, the left-most hops may be forged by the client.
+// X-Real-IP is used without X-Forwarded-For.
+func clientIP(h http.Header, isTrusted func(netip.Addr) bool) (netip.Addr, bool) {
+	var hops []string
+	for _, v := range h.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(v, ",")...)
+	}
+
+	if len(hops) == 0 {
+		addr, err := netip.ParseAddr(strings.TrimSpace(h.Get("X-Real-IP")))
+		return addr, err == nil
+	}
+
+	var client netip.Addr
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		client = addr
+		if !isTrusted(addr) {
+			break
+		}
+	}
+
+	return client, client.IsValid()
+}
+
+func remoteAddr(remote string) (netip.Addr, bool) {
+	host, _, err := net.SplitHostPort(remote)
+	if err != nil {
+		host = remote
+	}
+	addr, err := netip.ParseAddr(host)
+
+	return addr, err == nil
+}
+
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		return netip.ParsePrefix(s)
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
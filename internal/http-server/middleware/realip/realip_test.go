@@ -0,0 +1,87 @@
+package realip_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"url-shortener/internal/http-server/middleware/realip"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+func TestRealIP(t *testing.T) {
+	cases := []struct {
+		name    string
+		trusted []string
+		remote  string
+		xff     []string
+		realIP  string
+		want    string
+	}{
+		{
+			name:   "No trusted proxies",
+			remote: "203.0.113.7:1234",
+			xff:    []string{"198.51.100.1"},
+			want:   "203.0.113.7:1234",
+		},
+		{
+			name:    "Untrusted peer",
+			trusted: []string{"10.0.0.0/8"},
+			remote:  "203.0.113.7:1234",
+			xff:     []string{"198.51.100.1"},
+			want:    "203.0.113.7:1234",
+		},
+		{
+			name:    "Trusted peer",
+			trusted: []string{"10.0.0.0/8"},
+			remote:  "10.0.0.2:1234",
+			xff:     []string{"198.51.100.1"},
+			want:    "198.51.100.1",
+		},
+		{
+			name:    "Forged left-most hop",
+			trusted: []string{"10.0.0.0/8"},
+			remote:  "10.0.0.2:1234",
+			xff:     []string{"1.2.3.4, 198.51.100.1", "10.0.0.3"},
+			want:    "198.51.100.1",
+		},
+		{
+			name:    "X-Real-IP",
+			trusted: []string{"10.0.0.2"},
+			remote:  "10.0.0.2:1234",
+			realIP:  "198.51.100.1",
+			want:    "198.51.100.1",
+		},
+		{
+			name:    "Invalid entry skipped",
+			trusted: []string{"not an ip"},
+			remote:  "10.0.0.2:1234",
+			xff:     []string{"198.51.100.1"},
+			want:    "10.0.0.2:1234",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			var got string
+			h := realip.New(slogdiscard.NewDiscardLogger(), tc.trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.RemoteAddr
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tc.remote
+			for _, v := range tc.xff {
+				req.Header.Add("X-Forwarded-For", v)
+			}
+			if tc.realIP != "" {
+				req.Header.Set("X-Real-IP", tc.realIP)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), req)
+
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
@@ -2,6 +2,7 @@ package httpserver
 
 import (
 	"log/slog"
+	"net/http"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -10,14 +11,20 @@ import (
 	ssoGrpc "url-shortener/internal/clients/sso/grpc"
 	"url-shortener/internal/config"
 	"url-shortener/internal/http-server/handlers/health"
-	"url-shortener/internal/http-server/handlers/login"
+	"url-shortener/internal/http-server/handlers/openapi"
 	"url-shortener/internal/http-server/handlers/redirect"
-	"url-shortener/internal/http-server/handlers/register"
-	"url-shortener/internal/http-server/handlers/url/delete"
-	"url-shortener/internal/http-server/handlers/url/getUrls"
-	"url-shortener/internal/http-server/handlers/url/save"
-	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/http-server/handlers/report"
+	"url-shortener/internal/http-server/handlers/url/qr"
+	mwAudit "url-shortener/internal/http-server/middleware/audit"
+	"url-shortener/internal/http-server/middleware/deprecation"
+	mwDomain "url-shortener/internal/http-server/middleware/domain"
+	"url-shortener/internal/http-server/middleware/idempotency"
 	mwLogger "url-shortener/internal/http-server/middleware/logger"
+	"url-shortener/internal/http-server/middleware/realip"
+	"url-shortener/internal/lib/events"
+	"url-shortener/internal/lib/hub"
+	"url-shortener/internal/lib/targeting"
+	"url-shortener/internal/lib/urlpolicy"
 	"url-shortener/internal/storage"
 )
 
@@ -27,13 +34,19 @@ func NewRouter(
 	urlStorage storage.Storage,
 	ssoClient *ssoGrpc.Client,
 	cfg *config.AppConfig,
+	countryResolver targeting.CountryResolver,
+	urlPolicy *urlpolicy.Policy,
+	eventPublisher events.Publisher,
+	eventHub *hub.Hub,
 ) *chi.Mux {
 	router := chi.NewRouter()
 
 	c := cors.New(cors.Options{
         AllowedOrigins:   []string{"http://localhost:3000"}, 
-        AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-        AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
+        AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+        AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", idempotency.Header},
+        // lets browser clients see replays and deprecation notices
+        ExposedHeaders:   []string{idempotency.ReplayedHeader, "Deprecation", "Sunset", "Link"},
         AllowCredentials: true,
         MaxAge:           300, 
     })
@@ -42,30 +55,76 @@ func NewRouter(
 
 	// Middleware
 	router.Use(middleware.RequestID)
+	router.Use(realip.New(log, cfg.HTTPServer.TrustedProxies))
 	router.Use(middleware.Logger)
 	router.Use(mwLogger.New(log))
 	router.Use(middleware.Recoverer)
 	router.Use(middleware.URLFormat)
+	router.Use(mwAudit.New())
 	
-	// Auth routes
-	router.Post("/login", login.New(log, ssoClient, cfg))
-	router.Post("/register", register.New(log, ssoClient, cfg))
-	router.Get("/login", login.GetLogin(log, cfg))
+	api := apiDeps{
+		log:            log,
+		urlStorage:     urlStorage,
+		ssoClient:      ssoClient,
+		cfg:            cfg,
+		urlPolicy:      urlPolicy,
+		eventPublisher: eventPublisher,
+		eventHub:       eventHub,
+	}
+	router.Route("/api/v1", api.v1)
 
-	// Protected routes
-	router.Route("/url", func(r chi.Router) {
-		authMiddleware := auth.New(log, cfg)
-		r.Use(authMiddleware)
-		r.Post("/", save.New(log, urlStorage))
-		r.Get("/", getUrls.New(log, urlStorage))
-		r.Delete("/{alias}", delete.New(log, urlStorage, ssoClient))
-		// TODO: add DELETE /url/{id}
+	// The v1 routes used to be served at the root, keep them working there
+	// until the sunset
+	router.Group(func(r chi.Router) {
+		r.Use(deprecation.New(cfg.API.LegacyDeprecatedAt, cfg.API.LegacySunset, "/api/v1"))
+		api.v1(r)
 	})
 
 	// Public routes
-	router.Get("/{alias}", redirect.New(log, urlStorage))
+	qrHandler := qr.New(log, urlStorage, cfg.HTTPServer.BaseURL)
+	domainMiddleware := mwDomain.New(log, urlStorage)
+	router.With(domainMiddleware).Post("/{alias}/report", report.New(log, urlStorage))
+	var previewGetter redirect.PreviewGetter
+	if cfg.Bots.PreviewPages {
+		previewGetter = urlStorage
+	}
+	redirectHandler := redirect.New(
+		log, urlStorage, urlStorage, urlStorage, urlStorage, eventPublisher, countryResolver, previewGetter,
+		[]byte(cfg.AppSecret),
+	)
+	router.With(domainMiddleware).Get("/{alias}", byURLFormat(
+		redirectHandler,
+		map[string]http.Handler{
+			"png": qrHandler,
+			"svg": qrHandler,
+		},
+	))
+	// link checkers and unfurlers often try HEAD first
+	router.With(domainMiddleware).Head("/{alias}", redirectHandler)
 	router.Get("/health", health.New(log))
 
+	// URLFormat routes /openapi.json as /openapi with the json format
+	swaggerUI := http.NotFoundHandler()
+	if cfg.HTTPServer.SwaggerUI {
+		swaggerUI = openapi.NewSwaggerUI(log, "/openapi.json")
+	}
+	router.Get("/openapi", byURLFormat(swaggerUI, map[string]http.Handler{
+		"json": openapi.New(log),
+	}))
+
 	return router
 }
 
+// byURLFormat dispatches to the handler registered for the extension parsed by
+// middleware.URLFormat (/{alias}.png), falling back to def.
+func byURLFormat(def http.Handler, handlers map[string]http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		format, _ := r.Context().Value(middleware.URLFormatCtxKey).(string)
+		if h, ok := handlers[format]; ok {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		def.ServeHTTP(w, r)
+	}
+}
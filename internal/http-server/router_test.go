@@ -0,0 +1,669 @@
+package httpserver_test
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/sqlite3"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	ssov1 "github.com/qwertylangs/protos/gen/go/sso"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	ssoGrpc "url-shortener/internal/clients/sso/grpc"
+	"url-shortener/internal/config"
+	httpserver "url-shortener/internal/http-server"
+	"url-shortener/internal/http-server/handlers/openapi"
+	"url-shortener/internal/lib/aliases"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/hub"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/lib/urlpolicy"
+	"url-shortener/internal/storage/sqlite"
+)
+
+const (
+	appSecret = "test-secret"
+	password  = "secret"
+	takenMail = "taken@example.com"
+	adminID   = int64(1)
+	userID    = int64(2)
+)
+
+var methods = []string{"get", "head", "post", "put", "patch", "delete"}
+
+type spec struct {
+	Paths map[string]map[string]json.RawMessage `json:"paths"`
+}
+
+type operation struct {
+	Responses map[string]json.RawMessage `json:"responses"`
+}
+
+// operations returns the documented status codes by "METHOD /path".
+func (s spec) operations(t *testing.T) map[string][]int {
+	t.Helper()
+
+	ops := make(map[string][]int)
+	for path, item := range s.Paths {
+		for method, raw := range item {
+			if !slices.Contains(methods, method) {
+				continue
+			}
+
+			var op operation
+			require.NoError(t, json.Unmarshal(raw, &op), "%s %s", method, path)
+
+			var codes []int
+			for code := range op.Responses {
+				c, err := strconv.Atoi(code)
+				require.NoError(t, err, "%s %s: status %q", method, path, code)
+				codes = append(codes, c)
+			}
+			ops[strings.ToUpper(method)+" "+path] = codes
+		}
+	}
+
+	return ops
+}
+
+func loadSpec(t *testing.T) spec {
+	t.Helper()
+
+	b, err := openapi.Spec()
+	require.NoError(t, err)
+
+	var s spec
+	require.NoError(t, json.Unmarshal(b, &s))
+
+	return s
+}
+
+// extension matches the format suffix middleware.URLFormat strips before
+// routing, /{alias}.{format} and /openapi.json are routed as /{alias} and
+// /openapi.
+var extension = regexp.MustCompile(`\.[^/]+$`)
+
+func TestRouterMatchesSpec(t *testing.T) {
+	router := newRouter(t)
+
+	documented := make(map[string]bool)
+	for op := range loadSpec(t).operations(t) {
+		documented[extension.ReplaceAllString(op, "")] = true
+	}
+
+	routed := make(map[string]bool)
+	err := chi.Walk(router, func(method string, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if len(route) > 1 {
+			route = strings.TrimSuffix(route, "/")
+		}
+		routed[method+" "+route] = true
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	for op := range routed {
+		method, path, _ := strings.Cut(op, " ")
+		// deprecated root aliases of the v1 routes aren't documented separately
+		assert.True(t, documented[op] || documented[method+" /api/v1"+path], "route %s is not documented", op)
+	}
+	for op := range documented {
+		assert.True(t, routed[op], "operation %s is not routed", op)
+	}
+}
+
+// TestResponsesMatchSpec sends requests through the router backed by a real
+// database and checks that every response status is documented for its
+// operation. Every documented operation must be covered by a case.
+func TestResponsesMatchSpec(t *testing.T) {
+	router := newRouter(t)
+	ops := loadSpec(t).operations(t)
+
+	cases := []struct {
+		op     string
+		target string
+		body   string
+		user   int64
+		code   int
+	}{
+		{op: "POST /api/v1/register", target: "/api/v1/register", body: `{"email":"new@example.com","password":"secret"}`, code: 200},
+		{op: "POST /api/v1/register", target: "/api/v1/register", body: `{"email":"taken@example.com","password":"secret"}`, code: 409},
+		{op: "POST /api/v1/register", target: "/api/v1/register", body: `{}`, code: 400},
+		{op: "POST /api/v1/login", target: "/api/v1/login", body: `{"email":"user@example.com","password":"secret"}`, code: 200},
+		{op: "POST /api/v1/login", target: "/api/v1/login", body: `{"email":"user@example.com","password":"wrong"}`, code: 401},
+		{op: "POST /api/v1/login", target: "/api/v1/login", body: `{"email":"user"}`, code: 400},
+		{op: "GET /api/v1/login", target: "/api/v1/login", user: adminID, code: 200},
+		{op: "GET /api/v1/login", target: "/api/v1/login", code: 401},
+
+		{op: "POST /api/v1/url", target: "/api/v1/url", body: `{"url":"https://example.com","alias":"abc","tags":["go","web"]}`, user: adminID, code: 200},
+		{op: "POST /api/v1/url", target: "/api/v1/url", body: `{"url":"https://example.com","alias":"abc"}`, user: adminID, code: 409},
+		{op: "POST /api/v1/url", target: "/api/v1/url", body: `{"url":"not a url"}`, user: adminID, code: 400},
+		{op: "POST /api/v1/url", target: "/api/v1/url", body: `{"url":"https://example.com"}`, code: 401},
+		{op: "POST /api/v1/url", target: "/api/v1/url", body: `{"url":"https://example.org","alias":"tmp"}`, user: adminID, code: 200},
+		{op: "GET /api/v1/url", target: "/api/v1/url", user: adminID, code: 200},
+		{op: "GET /api/v1/url", target: "/api/v1/url?broken=maybe", user: adminID, code: 400},
+		{op: "GET /api/v1/url/live", target: "/api/v1/url/live", user: adminID, code: 200},
+		{op: "GET /api/v1/url/stats", target: "/api/v1/url/stats", user: adminID, code: 200},
+		{op: "GET /api/v1/url/stats", target: "/api/v1/url/stats?from=yesterday", user: adminID, code: 400},
+		{op: "GET /api/v1/url/clicks/export", target: "/api/v1/url/clicks/export", user: adminID, code: 200},
+		{op: "GET /api/v1/url/clicks/export", target: "/api/v1/url/clicks/export?format=xml", user: adminID, code: 400},
+		{op: "PATCH /api/v1/url/{alias}", target: "/api/v1/url/abc", body: `{"title":"Example"}`, user: adminID, code: 200},
+		{op: "PATCH /api/v1/url/{alias}", target: "/api/v1/url/abc", body: `{"title":"Example"}`, user: userID, code: 403},
+		{op: "PATCH /api/v1/url/{alias}", target: "/api/v1/url/nope", body: `{"title":"Example"}`, user: adminID, code: 404},
+		{op: "PATCH /api/v1/url/{alias}", target: "/api/v1/url/abc", body: `{"url":"not a url"}`, user: adminID, code: 400},
+		{op: "DELETE /api/v1/url/{alias}", target: "/api/v1/url/tmp", user: userID, code: 403},
+		{op: "DELETE /api/v1/url/{alias}", target: "/api/v1/url/tmp", user: adminID, code: 200},
+		{op: "DELETE /api/v1/url/{alias}", target: "/api/v1/url/nope", user: adminID, code: 404},
+		{op: "GET /api/v1/url/trash", target: "/api/v1/url/trash", user: adminID, code: 200},
+		{op: "POST /api/v1/url/{alias}/restore", target: "/api/v1/url/tmp/restore", user: adminID, code: 200},
+		{op: "POST /api/v1/url/{alias}/restore", target: "/api/v1/url/nope/restore", user: adminID, code: 404},
+		{op: "POST /api/v1/url/{alias}/rules", target: "/api/v1/url/abc/rules", body: `{"target_url":"https://example.org","platform":"ios"}`, user: adminID, code: 201},
+		{op: "POST /api/v1/url/{alias}/rules", target: "/api/v1/url/abc/rules", body: `{"platform":"ios"}`, user: adminID, code: 400},
+		{op: "GET /api/v1/url/{alias}/rules", target: "/api/v1/url/abc/rules", user: adminID, code: 200},
+		{op: "GET /api/v1/url/{alias}/rules", target: "/api/v1/url/abc/rules", user: userID, code: 403},
+		{op: "PUT /api/v1/url/{alias}/rules/{id}", target: "/api/v1/url/abc/rules/1", body: `{"target_url":"https://example.net"}`, user: adminID, code: 200},
+		{op: "PUT /api/v1/url/{alias}/rules/{id}", target: "/api/v1/url/abc/rules/x", body: `{"target_url":"https://example.net"}`, user: adminID, code: 400},
+		{op: "DELETE /api/v1/url/{alias}/rules/{id}", target: "/api/v1/url/abc/rules/1", user: adminID, code: 200},
+		{op: "DELETE /api/v1/url/{alias}/rules/{id}", target: "/api/v1/url/abc/rules/1", user: adminID, code: 404},
+		{op: "PUT /api/v1/url/{alias}/variants", target: "/api/v1/url/abc/variants", body: `{"variants":[{"url":"https://example.net","weight":1}]}`, user: adminID, code: 200},
+		{op: "PUT /api/v1/url/{alias}/variants", target: "/api/v1/url/abc/variants", body: `{"variants":[{"weight":1}]}`, user: adminID, code: 400},
+		{op: "GET /api/v1/url/{alias}/variants", target: "/api/v1/url/abc/variants", user: adminID, code: 200},
+		{op: "GET /api/v1/url/{alias}/variants", target: "/api/v1/url/nope/variants", user: adminID, code: 404},
+		{op: "GET /api/v1/url/{alias}/live", target: "/api/v1/url/abc/live", user: adminID, code: 200},
+		{op: "GET /api/v1/url/{alias}/live", target: "/api/v1/url/abc/live", user: userID, code: 403},
+		{op: "GET /api/v1/url/{alias}/stats", target: "/api/v1/url/abc/stats", user: adminID, code: 200},
+		{op: "GET /api/v1/url/{alias}/stats", target: "/api/v1/url/nope/stats", user: adminID, code: 404},
+		{op: "GET /api/v1/url/{alias}/clicks/export", target: "/api/v1/url/abc/clicks/export?format=ndjson", user: adminID, code: 200},
+		{op: "GET /api/v1/url/{alias}/clicks/export", target: "/api/v1/url/abc/clicks/export", user: userID, code: 403},
+		{op: "GET /api/v1/url/{alias}/qr", target: "/api/v1/url/abc/qr", user: adminID, code: 200},
+		{op: "GET /api/v1/url/{alias}/qr", target: "/api/v1/url/abc/qr?size=big", user: adminID, code: 400},
+
+		{op: "GET /api/v1/tags", target: "/api/v1/tags", user: adminID, code: 200},
+		{op: "PUT /api/v1/tags/{name}", target: "/api/v1/tags/go", body: `{"name":"golang"}`, user: adminID, code: 200},
+		{op: "PUT /api/v1/tags/{name}", target: "/api/v1/tags/none", body: `{"name":"other"}`, user: adminID, code: 404},
+		{op: "PUT /api/v1/tags/{name}", target: "/api/v1/tags/golang", body: `{"name":"web"}`, user: adminID, code: 409},
+		{op: "POST /api/v1/tags/merge", target: "/api/v1/tags/merge", body: `{"tags":["golang"],"into":"go"}`, user: adminID, code: 200},
+		{op: "POST /api/v1/tags/merge", target: "/api/v1/tags/merge", body: `{}`, user: adminID, code: 400},
+		{op: "GET /api/v1/folders", target: "/api/v1/folders", user: adminID, code: 200},
+		{op: "GET /api/v1/settings", target: "/api/v1/settings", user: adminID, code: 200},
+		{op: "PUT /api/v1/settings", target: "/api/v1/settings", body: `{"reuse_existing":false}`, user: adminID, code: 200},
+		{op: "PUT /api/v1/settings", target: "/api/v1/settings", body: `{}`, user: adminID, code: 400},
+
+		{op: "POST /api/v1/webhooks", target: "/api/v1/webhooks", body: `{"url":"https://hooks.example.com","events":["link.created"]}`, user: adminID, code: 201},
+		{op: "POST /api/v1/webhooks", target: "/api/v1/webhooks", body: `{"url":"https://hooks.example.com","events":["link.moved"]}`, user: adminID, code: 400},
+		{op: "GET /api/v1/webhooks", target: "/api/v1/webhooks", user: adminID, code: 200},
+		{op: "GET /api/v1/webhooks/{id}/deliveries", target: "/api/v1/webhooks/1/deliveries", user: adminID, code: 200},
+		{op: "GET /api/v1/webhooks/{id}/deliveries", target: "/api/v1/webhooks/1/deliveries", user: userID, code: 404},
+		{op: "POST /api/v1/webhooks/{id}/deliveries/{deliveryID}/redeliver", target: "/api/v1/webhooks/1/deliveries/1/redeliver", user: adminID, code: 404},
+		{op: "DELETE /api/v1/webhooks/{id}", target: "/api/v1/webhooks/1", user: adminID, code: 200},
+		{op: "DELETE /api/v1/webhooks/{id}", target: "/api/v1/webhooks/x", user: adminID, code: 400},
+
+		{op: "POST /api/v1/domains", target: "/api/v1/domains", body: `{"hostname":"go.example.com"}`, user: adminID, code: 201},
+		{op: "POST /api/v1/domains", target: "/api/v1/domains", body: `{"hostname":"go.example.com"}`, user: adminID, code: 409},
+		{op: "POST /api/v1/domains", target: "/api/v1/domains", body: `{"hostname":"not a host"}`, user: adminID, code: 400},
+		{op: "GET /api/v1/domains", target: "/api/v1/domains", user: adminID, code: 200},
+		{op: "POST /api/v1/domains/{hostname}/verify", target: "/api/v1/domains/other.example.com/verify", user: adminID, code: 404},
+		{op: "DELETE /api/v1/domains/{hostname}", target: "/api/v1/domains/go.example.com", user: adminID, code: 200},
+		{op: "DELETE /api/v1/domains/{hostname}", target: "/api/v1/domains/go.example.com", user: adminID, code: 404},
+
+		{op: "GET /api/v1/admin/audit", target: "/api/v1/admin/audit", user: adminID, code: 200},
+		{op: "GET /api/v1/admin/audit", target: "/api/v1/admin/audit", user: userID, code: 403},
+		{op: "GET /api/v1/admin/audit", target: "/api/v1/admin/audit", code: 401},
+		{op: "GET /api/v1/admin/audit", target: "/api/v1/admin/audit?from=yesterday", user: adminID, code: 400},
+		{op: "GET /api/v1/admin/stats", target: "/api/v1/admin/stats", user: adminID, code: 200},
+		{op: "GET /api/v1/admin/urls", target: "/api/v1/admin/urls?alias=abc", user: adminID, code: 200},
+		{op: "GET /api/v1/admin/urls", target: "/api/v1/admin/urls?limit=0", user: adminID, code: 400},
+		{op: "GET /api/v1/admin/users/{userID}/urls", target: "/api/v1/admin/users/1/urls", user: adminID, code: 200},
+		{op: "GET /api/v1/admin/users/{userID}/urls", target: "/api/v1/admin/users/x/urls", user: adminID, code: 400},
+		{op: "POST /api/v1/admin/urls/bulk-delete", target: "/api/v1/admin/urls/bulk-delete", body: `{"ids":[999]}`, user: adminID, code: 200},
+		{op: "POST /api/v1/admin/urls/bulk-delete", target: "/api/v1/admin/urls/bulk-delete", body: `{"ids":[]}`, user: adminID, code: 400},
+		{op: "POST /api/v1/admin/urls/transfer", target: "/api/v1/admin/urls/transfer", body: `{"from_user_id":2,"to_user_id":1}`, user: adminID, code: 200},
+		{op: "POST /api/v1/admin/urls/transfer", target: "/api/v1/admin/urls/transfer", body: `{"from_user_id":1,"to_user_id":1}`, user: adminID, code: 400},
+
+		{op: "POST /{alias}/report", target: "/abc/report", body: `{"reason":"spam"}`, code: 201},
+		{op: "POST /{alias}/report", target: "/abc/report", body: `{"reason":"boring"}`, code: 400},
+		{op: "POST /{alias}/report", target: "/nope/report", body: `{"reason":"spam"}`, code: 404},
+		{op: "GET /api/v1/admin/reports", target: "/api/v1/admin/reports?status=open", user: adminID, code: 200},
+		{op: "GET /api/v1/admin/reports", target: "/api/v1/admin/reports?status=closed", user: adminID, code: 400},
+		{op: "POST /api/v1/admin/urls/{alias}/quarantine", target: "/api/v1/admin/urls/abc/quarantine", user: adminID, code: 200},
+		{op: "POST /api/v1/admin/urls/{alias}/quarantine", target: "/api/v1/admin/urls/nope/quarantine", user: adminID, code: 404},
+		{op: "GET /{alias}", target: "/abc", code: 403},
+		{op: "POST /api/v1/admin/urls/{alias}/restore", target: "/api/v1/admin/urls/abc/restore", user: adminID, code: 200},
+		{op: "POST /api/v1/admin/urls/{alias}/restore", target: "/api/v1/admin/urls/nope/restore", user: adminID, code: 404},
+		{op: "GET /{alias}", target: "/abc", code: 302},
+		{op: "GET /{alias}", target: "/nope", code: 404},
+		{op: "HEAD /{alias}", target: "/abc", code: 302},
+		{op: "GET /{alias}.{format}", target: "/abc.png", code: 200},
+		{op: "GET /{alias}.{format}", target: "/abc.svg?level=Z", code: 400},
+		{op: "GET /{alias}.{format}", target: "/nope.svg", code: 404},
+		{op: "DELETE /api/v1/admin/urls/{alias}", target: "/api/v1/admin/urls/tmp", user: adminID, code: 200},
+		{op: "DELETE /api/v1/admin/urls/{alias}", target: "/api/v1/admin/urls/tmp", user: adminID, code: 404},
+
+		{op: "GET /health", target: "/health", code: 200},
+		{op: "GET /openapi.json", target: "/openapi.json", code: 200},
+		{op: "GET /openapi", target: "/openapi", code: 200},
+	}
+
+	covered := make(map[string]bool)
+	for _, tc := range cases {
+		method, _, _ := strings.Cut(tc.op, " ")
+		codes, ok := ops[tc.op]
+		require.True(t, ok, "operation %s is not documented", tc.op)
+
+		req := httptest.NewRequest(method, tc.target, strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		if tc.user != 0 {
+			req.AddCookie(&http.Cookie{Name: "auth_token", Value: token(t, tc.user)})
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, tc.code, rr.Code, "%s %s: %s", method, tc.target, rr.Body.String())
+		assert.Contains(t, codes, rr.Code, "%s returned undocumented status %d", tc.op, rr.Code)
+		covered[tc.op] = true
+	}
+
+	for op := range ops {
+		assert.True(t, covered[op], "operation %s is not covered", op)
+	}
+}
+
+func TestLegacyRoutes(t *testing.T) {
+	router := newRouter(t)
+
+	cases := []struct {
+		name       string
+		target     string
+		deprecated bool
+	}{
+		{name: "v1", target: "/api/v1/login"},
+		{name: "Root alias", target: "/login", deprecated: true},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.target, nil)
+			req.AddCookie(&http.Cookie{Name: "auth_token", Value: token(t, adminID)})
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+			if !tc.deprecated {
+				assert.Empty(t, rr.Header().Get("Deprecation"))
+				assert.Empty(t, rr.Header().Get("Sunset"))
+				return
+			}
+
+			assert.Equal(t, "@1790812800", rr.Header().Get("Deprecation"))
+			assert.Equal(t, "Thu, 01 Apr 2027 00:00:00 GMT", rr.Header().Get("Sunset"))
+			assert.Equal(t, `</api/v1/login>; rel="successor-version"`, rr.Header().Get("Link"))
+		})
+	}
+}
+
+func TestProblemResponses(t *testing.T) {
+	router := newRouter(t)
+
+	cases := []struct {
+		name    string
+		target  string
+		user    int64
+		code    int
+		problem string
+	}{
+		{name: "Handler", target: "/api/v1/url/missing/stats", user: adminID, code: http.StatusNotFound, problem: "not-found"},
+		{name: "Middleware", target: "/api/v1/url", code: http.StatusUnauthorized, problem: "unauthorized"},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.target, nil)
+			req.Header.Set("Accept", resp.ProblemContentType)
+			if tc.user != 0 {
+				req.AddCookie(&http.Cookie{Name: "auth_token", Value: token(t, tc.user)})
+			}
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.code, rr.Code)
+			assert.Equal(t, resp.ProblemContentType, rr.Header().Get("Content-Type"))
+
+			var problem resp.Problem
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+			assert.Equal(t, resp.ProblemTypePrefix+tc.problem, problem.Type)
+			assert.Equal(t, tc.code, problem.Status)
+			assert.NotEmpty(t, problem.Instance)
+		})
+	}
+}
+
+func TestIdempotentRequests(t *testing.T) {
+	router := newRouter(t)
+
+	post := func(key string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/url", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Idempotency-Key", key)
+		req.AddCookie(&http.Cookie{Name: "auth_token", Value: token(t, adminID)})
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	first := post("k1", `{"url":"https://example.com"}`)
+	require.Equal(t, http.StatusOK, first.Code, first.Body.String())
+
+	retry := post("k1", `{"url":"https://example.com"}`)
+	require.Equal(t, http.StatusOK, retry.Code)
+	assert.Equal(t, "true", retry.Header().Get("Idempotent-Replayed"))
+	assert.JSONEq(t, first.Body.String(), retry.Body.String())
+
+	assert.Equal(t, http.StatusUnprocessableEntity, post("k1", `{"url":"https://example.org"}`).Code)
+
+	other := post("k2", `{"url":"https://example.com"}`)
+	require.Equal(t, http.StatusOK, other.Code)
+	assert.NotEqual(t, first.Body.String(), other.Body.String())
+}
+
+func TestReuseExisting(t *testing.T) {
+	router := newRouter(t)
+
+	do := func(method string, target string, body string) map[string]any {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.AddCookie(&http.Cookie{Name: "auth_token", Value: token(t, adminID)})
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		var res map[string]any
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+		return res
+	}
+
+	first := do(http.MethodPost, "/api/v1/url", `{"url":"https://example.com/a?b=2&a=1"}`)
+
+	reused := do(http.MethodPost, "/api/v1/url", `{"url":"HTTPS://Example.com:443/a/?a=1&b=2","reuse_existing":true}`)
+	assert.Equal(t, first["alias"], reused["alias"])
+	assert.Equal(t, true, reused["reused"])
+
+	created := do(http.MethodPost, "/api/v1/url", `{"url":"https://example.com/a?b=2&a=1"}`)
+	assert.NotEqual(t, first["alias"], created["alias"])
+
+	do(http.MethodPut, "/api/v1/settings", `{"reuse_existing":true}`)
+	byDefault := do(http.MethodPost, "/api/v1/url", `{"url":"https://example.com/a?a=1&b=2"}`)
+	assert.Equal(t, first["alias"], byDefault["alias"])
+
+	optedOut := do(http.MethodPost, "/api/v1/url", `{"url":"https://example.com/a?a=1&b=2","reuse_existing":false}`)
+	assert.Nil(t, optedOut["reused"])
+}
+
+func TestReuseExistingConcurrently(t *testing.T) {
+	router := newRouter(t)
+
+	const n = 8
+
+	aliases := make([]string, n)
+	reused := make([]bool, n)
+
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/url",
+				strings.NewReader(`{"url":"https://example.com/race","reuse_existing":true}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.AddCookie(&http.Cookie{Name: "auth_token", Value: token(t, adminID)})
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+			if !assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String()) {
+				return
+			}
+
+			var res struct {
+				Alias  string `json:"alias"`
+				Reused bool   `json:"reused"`
+			}
+			if assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res)) {
+				aliases[i], reused[i] = res.Alias, res.Reused
+			}
+		}()
+	}
+	wg.Wait()
+
+	created := 0
+	for i := range n {
+		assert.Equal(t, aliases[0], aliases[i])
+		if !reused[i] {
+			created++
+		}
+	}
+	assert.Equal(t, 1, created)
+}
+
+func TestAdminBulkDeleteUsesTrash(t *testing.T) {
+	router := newRouter(t)
+
+	do := func(method string, target string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.AddCookie(&http.Cookie{Name: "auth_token", Value: token(t, adminID)})
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code, "%s %s: %s", method, target, rr.Body.String())
+
+		return rr
+	}
+
+	do(http.MethodPost, "/api/v1/url", `{"url":"https://example.com","alias":"bulk"}`)
+
+	var list struct {
+		URLs []struct {
+			ID int64 `json:"id"`
+		} `json:"urls"`
+	}
+	require.NoError(t, json.Unmarshal(do(http.MethodGet, "/api/v1/url", "").Body.Bytes(), &list))
+	require.Len(t, list.URLs, 1)
+
+	do(http.MethodPost, "/api/v1/admin/urls/bulk-delete", `{"ids":[`+strconv.FormatInt(list.URLs[0].ID, 10)+`]}`)
+
+	assert.Contains(t, do(http.MethodGet, "/api/v1/url/trash", "").Body.String(), `"alias":"bulk"`)
+	do(http.MethodPost, "/api/v1/url/bulk/restore", "")
+}
+
+func TestRenameDottedTag(t *testing.T) {
+	router := newRouter(t)
+
+	do := func(method string, target string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.AddCookie(&http.Cookie{Name: "auth_token", Value: token(t, adminID)})
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code, "%s %s: %s", method, target, rr.Body.String())
+
+		return rr
+	}
+
+	do(http.MethodPost, "/api/v1/url", `{"url":"https://example.com","tags":["node","node.js"]}`)
+	do(http.MethodPut, "/api/v1/tags/node.js", `{"name":"nodejs"}`)
+
+	var res struct {
+		Tags []struct {
+			Name string `json:"name"`
+		} `json:"tags"`
+	}
+	require.NoError(t, json.Unmarshal(do(http.MethodGet, "/api/v1/tags", "").Body.Bytes(), &res))
+
+	var names []string
+	for _, tag := range res.Tags {
+		names = append(names, tag.Name)
+	}
+	assert.ElementsMatch(t, []string{"node", "nodejs"}, names)
+}
+
+func TestCORSIdempotencyHeaders(t *testing.T) {
+	router := newRouter(t)
+
+	req := httptest.NewRequest(http.MethodOptions, "/api/v1/url", nil)
+	req.Header.Set("Origin", "http://localhost:3000")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	req.Header.Set("Access-Control-Request-Headers", "content-type,idempotency-key")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, "http://localhost:3000", rr.Header().Get("Access-Control-Allow-Origin"))
+	assert.Contains(t, strings.ToLower(rr.Header().Get("Access-Control-Allow-Headers")), "idempotency-key")
+
+	req = httptest.NewRequest(http.MethodGet, "/health", nil)
+	req.Header.Set("Origin", "http://localhost:3000")
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	exposed := rr.Header().Get("Access-Control-Expose-Headers")
+	for _, h := range []string{"Idempotent-Replayed", "Deprecation", "Sunset", "Link"} {
+		assert.Contains(t, exposed, h)
+	}
+}
+
+// TestReservedAliases checks that the reserved aliases are exactly the first
+// segments of the routes taken ahead of /{alias}.
+func TestReservedAliases(t *testing.T) {
+	router := newRouter(t)
+
+	segments := make(map[string]bool)
+	err := chi.Walk(router, func(_ string, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		segment, _, _ := strings.Cut(strings.TrimPrefix(route, "/"), "/")
+		if segment != "" && !strings.HasPrefix(segment, "{") {
+			segments[segment] = true
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	for segment := range segments {
+		assert.True(t, aliases.IsReserved(segment), "segment %s is not reserved", segment)
+	}
+	for _, alias := range aliases.Reserved {
+		assert.True(t, segments[alias], "reserved alias %s is not routed", alias)
+	}
+}
+
+func newRouter(t *testing.T) *chi.Mux {
+	t.Helper()
+
+	log := slogdiscard.NewDiscardLogger()
+
+	storagePath := filepath.Join(t.TempDir(), "storage.db")
+	migrationsPath, err := filepath.Abs("../../migrations")
+	require.NoError(t, err)
+
+	m, err := migrate.New("file://"+migrationsPath, "sqlite3://"+storagePath)
+	require.NoError(t, err)
+	require.NoError(t, m.Up())
+	srcErr, dbErr := m.Close()
+	require.NoError(t, srcErr)
+	require.NoError(t, dbErr)
+
+	storage, err := sqlite.New(storagePath)
+	require.NoError(t, err)
+
+	ssoClient, err := ssoGrpc.New(context.Background(), log, startSSO(t), time.Second, 1)
+	require.NoError(t, err)
+
+	urlPolicy, err := urlpolicy.New(urlpolicy.Options{})
+	require.NoError(t, err)
+
+	cfg := &config.AppConfig{AppSecret: appSecret}
+	cfg.HTTPServer.SwaggerUI = true
+	cfg.Live.Heartbeat = time.Second
+	cfg.Live.MaxDuration = 10 * time.Millisecond
+	cfg.Trash.GracePeriod = time.Hour
+	cfg.Idempotency.TTL = 24 * time.Hour
+	cfg.API.LegacyDeprecatedAt = time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	cfg.API.LegacySunset = time.Date(2027, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	eventHub := hub.New(log, storage, 16)
+
+	return httpserver.NewRouter(log, storage, ssoClient, cfg, nil, urlPolicy, eventHub, eventHub)
+}
+
+func token(t *testing.T, uid int64) string {
+	t.Helper()
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"uid":   uid,
+		"email": "user@example.com",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte(appSecret))
+	require.NoError(t, err)
+
+	return token
+}
+
+// fakeSSO logs in with the password "secret", refuses to register taken@example.com
+// and treats user 1 as the only admin.
+type fakeSSO struct {
+	ssov1.UnimplementedAuthServer
+	t *testing.T
+}
+
+func (s *fakeSSO) Login(_ context.Context, in *ssov1.LoginRequest) (*ssov1.LoginResponse, error) {
+	if in.GetPassword() != password {
+		return nil, status.Error(codes.Unauthenticated, "invalid credentials")
+	}
+
+	return &ssov1.LoginResponse{Token: token(s.t, adminID)}, nil
+}
+
+func (s *fakeSSO) Register(_ context.Context, in *ssov1.RegisterRequest) (*ssov1.RegisterResponse, error) {
+	if in.GetEmail() == takenMail {
+		return nil, status.Error(codes.AlreadyExists, "user already exists")
+	}
+
+	return &ssov1.RegisterResponse{UserId: 3}, nil
+}
+
+func (s *fakeSSO) IsAdmin(_ context.Context, in *ssov1.IsAdminRequest) (*ssov1.IsAdminResponse, error) {
+	return &ssov1.IsAdminResponse{IsAdmin: in.GetUserId() == adminID}, nil
+}
+
+func startSSO(t *testing.T) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	srv := grpc.NewServer()
+	ssov1.RegisterAuthServer(srv, &fakeSSO{t: t})
+	go func() {
+		_ = srv.Serve(l)
+	}()
+	t.Cleanup(srv.Stop)
+
+	return l.Addr().String()
+}
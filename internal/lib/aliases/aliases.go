@@ -0,0 +1,51 @@
+// Package aliases knows which custom aliases can't be given to links because
+// the router serves something else at them.
+package aliases
+
+import (
+	"slices"
+	"strings"
+
+	resp "url-shortener/internal/lib/api/response"
+)
+
+// Reserved are the first path segments of the routes chi matches ahead of
+// /{alias}. Keep it in sync with the router when adding top-level routes.
+var Reserved = []string{
+	"admin",
+	"api",
+	"domains",
+	"folders",
+	"health",
+	"login",
+	"openapi",
+	"register",
+	"settings",
+	"tags",
+	"url",
+	"webhooks",
+}
+
+// IsReserved reports whether requests for the alias would be routed elsewhere.
+// middleware.URLFormat routes /health.json as /health, so the extension is
+// ignored.
+func IsReserved(alias string) bool {
+	if i := strings.LastIndexByte(alias, '.'); i >= 0 {
+		alias = alias[:i]
+	}
+
+	return slices.Contains(Reserved, alias)
+}
+
+// Check returns the field error of a reserved alias, nil if it can be used.
+func Check(field string, alias string) []resp.FieldError {
+	if !IsReserved(alias) {
+		return nil
+	}
+
+	return []resp.FieldError{{
+		Field:   field,
+		Code:    "reserved",
+		Message: "field " + field + " is reserved",
+	}}
+}
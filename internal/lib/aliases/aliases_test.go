@@ -0,0 +1,37 @@
+package aliases_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"url-shortener/internal/lib/aliases"
+)
+
+func TestIsReserved(t *testing.T) {
+	cases := map[string]bool{
+		"":             false,
+		"abc":          false,
+		"tags":         true,
+		"admin":        true,
+		"openapi.json": true,
+		"health.png":   true,
+		"Tags":         false,
+		"tags2":        false,
+		"my.tags":      false,
+	}
+
+	for alias, want := range cases {
+		assert.Equal(t, want, aliases.IsReserved(alias), alias)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	assert.Nil(t, aliases.Check("Alias", "abc"))
+
+	errs := aliases.Check("Alias", "admin")
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, "Alias", errs[0].Field)
+		assert.Equal(t, "reserved", errs[0].Code)
+	}
+}
@@ -0,0 +1,17 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// ExtendWriteDeadline gives the response d more time to be written. Writers
+// without a deadline to extend, e.g. in tests, are left as they are.
+func ExtendWriteDeadline(rc *http.ResponseController, d time.Duration) error {
+	if err := rc.SetWriteDeadline(time.Now().Add(d)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+
+	return nil
+}
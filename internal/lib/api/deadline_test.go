@@ -0,0 +1,18 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"url-shortener/internal/lib/api"
+)
+
+func TestExtendWriteDeadlineNotSupported(t *testing.T) {
+	rc := http.NewResponseController(httptest.NewRecorder())
+
+	assert.NoError(t, api.ExtendWriteDeadline(rc, time.Minute))
+}
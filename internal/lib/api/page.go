@@ -0,0 +1,42 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	resp "url-shortener/internal/lib/api/response"
+)
+
+const (
+	DefaultLimit = 50
+	MaxLimit     = 500
+)
+
+// ParsePage reads the "limit" and "offset" query parameters, writing the
+// error response if they are invalid.
+func ParsePage(w http.ResponseWriter, r *http.Request, log *slog.Logger) (int, int, bool) {
+	query := r.URL.Query()
+
+	limit, offset := DefaultLimit, 0
+	if v := query.Get("limit"); v != "" {
+		var err error
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > MaxLimit {
+			log.Info("invalid limit", slog.String("limit", v))
+			resp.RenderError(w, r, http.StatusBadRequest, resp.Error("limit must be between 1 and "+strconv.Itoa(MaxLimit)))
+			return 0, 0, false
+		}
+	}
+	if v := query.Get("offset"); v != "" {
+		var err error
+		offset, err = strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			log.Info("invalid offset", slog.String("offset", v))
+			resp.RenderError(w, r, http.StatusBadRequest, resp.Error("invalid offset"))
+			return 0, 0, false
+		}
+	}
+
+	return limit, offset, true
+}
@@ -0,0 +1,48 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"url-shortener/internal/lib/api"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+func TestParsePage(t *testing.T) {
+	cases := []struct {
+		name       string
+		query      string
+		wantLimit  int
+		wantOffset int
+		wantOK     bool
+	}{
+		{name: "Defaults", query: "", wantLimit: api.DefaultLimit, wantOK: true},
+		{name: "Explicit", query: "?limit=10&offset=20", wantLimit: 10, wantOffset: 20, wantOK: true},
+		{name: "Max limit", query: "?limit=500", wantLimit: api.MaxLimit, wantOK: true},
+		{name: "Limit too large", query: "?limit=501"},
+		{name: "Zero limit", query: "?limit=0"},
+		{name: "Negative offset", query: "?offset=-1"},
+		{name: "Not a number", query: "?offset=abc"},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/items"+tc.query, nil)
+
+			limit, offset, ok := api.ParsePage(rr, req, slogdiscard.NewDiscardLogger())
+
+			assert.Equal(t, tc.wantOK, ok)
+			if !tc.wantOK {
+				assert.Equal(t, http.StatusBadRequest, rr.Code)
+				return
+			}
+			assert.Equal(t, tc.wantLimit, limit)
+			assert.Equal(t, tc.wantOffset, offset)
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+
+	"url-shortener/internal/lib/hostname"
+)
+
+// URLParam returns the path parameter key like chi.URLParam. When the
+// parameter ends the path, the extension middleware.URLFormat stripped from
+// it before routing (/tags/node.js) is put back.
+func URLParam(r *http.Request, key string) string {
+	value := chi.URLParam(r, key)
+
+	format, _ := r.Context().Value(middleware.URLFormatCtxKey).(string)
+	if format != "" && strings.HasSuffix(r.URL.Path, "/"+value+"."+format) {
+		value += "." + format
+	}
+
+	return value
+}
+
+// DomainParam returns the normalized "domain" query parameter, the custom
+// domain a link is addressed on. It is empty for the default domain.
+func DomainParam(r *http.Request) string {
+	return hostname.Normalize(r.URL.Query().Get("domain"))
+}
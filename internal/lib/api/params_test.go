@@ -0,0 +1,54 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+
+	"url-shortener/internal/lib/api"
+)
+
+func TestURLParam(t *testing.T) {
+	cases := []struct {
+		name string
+		path string
+		want string
+	}{
+		{name: "Plain", path: "/tags/golang", want: "golang"},
+		{name: "Dotted", path: "/tags/node.js", want: "node.js"},
+		{name: "Several dots", path: "/tags/go.example.com", want: "go.example.com"},
+		{name: "Not last segment", path: "/tags/node.js/merge", want: "node.js"},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			var got string
+			router := chi.NewRouter()
+			router.Use(middleware.URLFormat)
+			handler := func(w http.ResponseWriter, r *http.Request) {
+				got = api.URLParam(r, "name")
+			}
+			router.Get("/tags/{name}", handler)
+			router.Get("/tags/{name}/merge", handler)
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			assert.Equal(t, http.StatusOK, rr.Code)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestDomainParam(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/url/abc?domain=Links.Example.com.", nil)
+	assert.Equal(t, "links.example.com", api.DomainParam(req))
+
+	req = httptest.NewRequest(http.MethodGet, "/url/abc", nil)
+	assert.Empty(t, api.DomainParam(req))
+}
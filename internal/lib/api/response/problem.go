@@ -0,0 +1,148 @@
+package response
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+// ProblemContentType is the media type of RFC 9457 problem details. Clients
+// opt into problems by accepting it.
+const ProblemContentType = "application/problem+json"
+
+// ProblemTypePrefix prefixes the type URIs of problems.
+const ProblemTypePrefix = "urn:url-shortener:problem:"
+
+// Problem is an RFC 9457 problem details object. Instance is the request id.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+type problemType struct {
+	name  string
+	title string
+}
+
+// validationProblem is used for responses listing invalid fields.
+var validationProblem = problemType{name: "validation-error", title: "Request validation failed"}
+
+var problemTypes = map[int]problemType{
+	http.StatusBadRequest:          {name: "bad-request", title: "Bad request"},
+	http.StatusUnauthorized:        {name: "unauthorized", title: "Authentication required"},
+	http.StatusForbidden:           {name: "forbidden", title: "Access denied"},
+	http.StatusNotFound:            {name: "not-found", title: "Resource not found"},
+	http.StatusConflict:            {name: "conflict", title: "Resource conflict"},
+	http.StatusUnprocessableEntity: {name: "unprocessable", title: "Request cannot be processed"},
+	http.StatusInternalServerError: {name: "internal", title: "Internal error"},
+}
+
+// ErrorBody is an error response body, either a Response or a handler
+// response embedding one.
+type ErrorBody interface {
+	errorResponse() Response
+}
+
+func (r Response) errorResponse() Response {
+	return r
+}
+
+// RenderError renders body with the given status. Clients accepting
+// application/problem+json get it as an RFC 9457 problem instead, with the
+// fields a handler response adds to Response as extension members.
+func RenderError(w http.ResponseWriter, r *http.Request, status int, body ErrorBody) {
+	if !WantsProblem(r) {
+		render.Status(r, status)
+		render.JSON(w, r, body)
+		return
+	}
+
+	res := body.errorResponse()
+	problem := NewProblem(r, status, res.Error)
+	problem.Errors = res.Errors
+	if len(res.Errors) > 0 {
+		problem.Type = ProblemTypePrefix + validationProblem.name
+		problem.Title = validationProblem.title
+	}
+
+	writeProblem(w, problem, body)
+}
+
+// RenderStatusError renders a plain text error for the status, or a problem
+// for clients accepting application/problem+json. It is meant for
+// middlewares answering before any handler.
+func RenderStatusError(w http.ResponseWriter, r *http.Request, status int) {
+	if !WantsProblem(r) {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	writeProblem(w, NewProblem(r, status, ""), nil)
+}
+
+// NewProblem returns the problem of the status with the given detail.
+func NewProblem(r *http.Request, status int, detail string) Problem {
+	problem := Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: middleware.GetReqID(r.Context()),
+	}
+	if t, ok := problemTypes[status]; ok {
+		problem.Type = ProblemTypePrefix + t.name
+		problem.Title = t.title
+	}
+
+	return problem
+}
+
+// WantsProblem tells whether the client accepts application/problem+json.
+func WantsProblem(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), ProblemContentType)
+}
+
+func writeProblem(w http.ResponseWriter, problem Problem, body ErrorBody) {
+	var out any = problem
+	if ext := extensions(body); len(ext) > 0 {
+		b, _ := json.Marshal(problem)
+		_ = json.Unmarshal(b, &ext)
+		out = ext
+	}
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(problem.Status)
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+// extensions returns the fields a handler response adds to Response.
+func extensions(body ErrorBody) map[string]json.RawMessage {
+	if body == nil {
+		return nil
+	}
+	if _, ok := body.(Response); ok {
+		return nil
+	}
+
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil
+	}
+	var ext map[string]json.RawMessage
+	if err := json.Unmarshal(b, &ext); err != nil {
+		return nil
+	}
+	for _, field := range []string{"status", "error", "errors"} {
+		delete(ext, field)
+	}
+
+	return ext
+}
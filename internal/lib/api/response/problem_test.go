@@ -0,0 +1,117 @@
+package response_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	resp "url-shortener/internal/lib/api/response"
+)
+
+func newRequest(accept string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/url", nil)
+	if accept != "" {
+		r.Header.Set("Accept", accept)
+	}
+	return r.WithContext(context.WithValue(r.Context(), middleware.RequestIDKey, "req-1"))
+}
+
+func TestRenderErrorJSON(t *testing.T) {
+	rr := httptest.NewRecorder()
+	resp.RenderError(rr, newRequest(""), http.StatusNotFound, resp.Error("url not found"))
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Contains(t, rr.Header().Get("Content-Type"), "application/json")
+	assert.JSONEq(t, `{"status":"Error","error":"url not found"}`, rr.Body.String())
+}
+
+func TestRenderErrorProblem(t *testing.T) {
+	validation := resp.Response{
+		Status: resp.StatusError,
+		Error:  "field URL is a required field",
+		Errors: []resp.FieldError{{Field: "URL", Code: "required", Message: "field URL is a required field"}},
+	}
+	withExtension := struct {
+		resp.Response
+		Verification string `json:"verification"`
+	}{Response: resp.Error("verification record not found"), Verification: "_shortener.example.com"}
+
+	cases := []struct {
+		name   string
+		status int
+		body   resp.ErrorBody
+		want   string
+	}{
+		{
+			name:   "Not found",
+			status: http.StatusNotFound,
+			body:   resp.Error("url not found"),
+			want: `{"type":"urn:url-shortener:problem:not-found","title":"Resource not found",
+				"status":404,"detail":"url not found","instance":"req-1"}`,
+		},
+		{
+			name:   "Validation",
+			status: http.StatusBadRequest,
+			body:   validation,
+			want: `{"type":"urn:url-shortener:problem:validation-error","title":"Request validation failed",
+				"status":400,"detail":"field URL is a required field","instance":"req-1",
+				"errors":[{"field":"URL","code":"required","message":"field URL is a required field"}]}`,
+		},
+		{
+			name:   "Extension members",
+			status: http.StatusUnprocessableEntity,
+			body:   withExtension,
+			want: `{"type":"urn:url-shortener:problem:unprocessable","title":"Request cannot be processed",
+				"status":422,"detail":"verification record not found","instance":"req-1",
+				"verification":"_shortener.example.com"}`,
+		},
+		{
+			name:   "Unmapped status",
+			status: http.StatusTooManyRequests,
+			body:   resp.Error("slow down"),
+			want: `{"type":"about:blank","title":"Too Many Requests",
+				"status":429,"detail":"slow down","instance":"req-1"}`,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			resp.RenderError(rr, newRequest("application/problem+json, application/json"), tc.status, tc.body)
+
+			assert.Equal(t, tc.status, rr.Code)
+			assert.Equal(t, resp.ProblemContentType, rr.Header().Get("Content-Type"))
+			assert.JSONEq(t, tc.want, rr.Body.String())
+		})
+	}
+}
+
+func TestRenderStatusError(t *testing.T) {
+	rr := httptest.NewRecorder()
+	resp.RenderStatusError(rr, newRequest(""), http.StatusUnauthorized)
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.Equal(t, "Unauthorized\n", rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	resp.RenderStatusError(rr, newRequest(resp.ProblemContentType), http.StatusUnauthorized)
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.Equal(t, resp.ProblemContentType, rr.Header().Get("Content-Type"))
+
+	var problem resp.Problem
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+	assert.Equal(t, resp.Problem{
+		Type:     "urn:url-shortener:problem:unauthorized",
+		Title:    "Authentication required",
+		Status:   http.StatusUnauthorized,
+		Instance: "req-1",
+	}, problem)
+}
@@ -8,8 +8,16 @@ import (
 )
 
 type Response struct {
-	Status string `json:"status"`
-	Error  string `json:"error,omitempty"`
+	Status string       `json:"status"`
+	Error  string       `json:"error,omitempty"`
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes a single invalid field of a request.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
 }
 
 const (
@@ -31,21 +39,40 @@ func Error(msg string) Response {
 }
 
 func ValidationError(errs validator.ValidationErrors) Response {
-	var errMsgs []string
+	var fieldErrs []FieldError
 
 	for _, err := range errs {
+		var msg string
 		switch err.ActualTag() {
 		case "required":
-			errMsgs = append(errMsgs, fmt.Sprintf("field %s is a required field", err.Field()))
-		case "url":
-			errMsgs = append(errMsgs, fmt.Sprintf("field %s is not a valid URL", err.Field()))
+			msg = fmt.Sprintf("field %s is a required field", err.Field())
+		case "url", "http_url":
+			msg = fmt.Sprintf("field %s is not a valid URL", err.Field())
 		default:
-			errMsgs = append(errMsgs, fmt.Sprintf("field %s is not valid", err.Field()))
+			msg = fmt.Sprintf("field %s is not valid", err.Field())
 		}
+
+		fieldErrs = append(fieldErrs, FieldError{
+			Field:   err.Field(),
+			Code:    err.ActualTag(),
+			Message: msg,
+		})
+	}
+
+	return FieldErrors(fieldErrs)
+}
+
+// FieldErrors returns an error response listing every invalid field,
+// Error holds all messages joined for clients reading only the flat message.
+func FieldErrors(errs []FieldError) Response {
+	errMsgs := make([]string, 0, len(errs))
+	for _, err := range errs {
+		errMsgs = append(errMsgs, err.Message)
 	}
 
 	return Response{
 		Status: StatusError,
 		Error:  strings.Join(errMsgs, ", "),
+		Errors: errs,
 	}
 }
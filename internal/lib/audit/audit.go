@@ -0,0 +1,57 @@
+package audit
+
+import "context"
+
+// Actions recorded in the audit log.
+const (
+	ActionURLCreate        = "url.create"
+	ActionURLDelete        = "url.delete"
+	ActionURLUndelete      = "url.undelete"
+	ActionURLPurge         = "url.purge"
+	ActionURLUpdate        = "url.update"
+	ActionTagRename        = "tag.rename"
+	ActionTagMerge         = "tag.merge"
+	ActionRuleCreate       = "rule.create"
+	ActionRuleUpdate       = "rule.update"
+	ActionRuleDelete       = "rule.delete"
+	ActionVariantsSet      = "variants.set"
+	ActionDomainCreate     = "domain.create"
+	ActionDomainVerify     = "domain.verify"
+	ActionDomainDelete     = "domain.delete"
+	ActionReportCreate     = "report.create"
+	ActionWebhookCreate    = "webhook.create"
+	ActionWebhookDelete    = "webhook.delete"
+	ActionWebhookRedeliver = "webhook.redeliver"
+	ActionURLQuarantine    = "admin.url.quarantine"
+	ActionURLRestore       = "admin.url.restore"
+	ActionURLBulkDelete    = "admin.url.bulk_delete"
+	ActionURLTransfer      = "admin.url.transfer"
+	ActionLogin            = "auth.login"
+	ActionLoginFailed      = "auth.login_failed"
+	ActionRegister         = "auth.register"
+	ActionRegisterFailed   = "auth.register_failed"
+)
+
+// Actor is who performed an audited operation.
+type Actor struct {
+	// UserID is zero for anonymous requests.
+	UserID    int64
+	Email     string
+	RequestID string
+	IP        string
+}
+
+type actorKey struct{}
+
+// WithActor returns a copy of ctx carrying the actor. Storage methods read it
+// to attribute the audit records they write.
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor stored in ctx, the zero Actor if there is none.
+func ActorFrom(ctx context.Context) Actor {
+	actor, _ := ctx.Value(actorKey{}).(Actor)
+
+	return actor
+}
@@ -0,0 +1,119 @@
+// Package botdetect tells redirects served to people from those served to
+// crawlers, scanners and link preview fetchers.
+package botdetect
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Classes of traffic.
+const (
+	Human = "human"
+	Bot   = "bot"
+	// Preview is a link unfurler fetching the page to show a preview of it.
+	Preview = "preview"
+)
+
+// Classes lists all classes of traffic.
+var Classes = []string{Human, Bot, Preview}
+
+//go:embed patterns.txt
+var patterns string
+
+var defaultClassifier = MustParse(strings.NewReader(patterns))
+
+// Classifier classifies requests by their user agent and headers.
+type Classifier struct {
+	preview []string
+	bot     []string
+}
+
+// Parse reads a pattern list: one lowercase user agent substring per line in
+// [preview] and [bot] sections. Blank lines and lines starting with # are skipped.
+func Parse(r io.Reader) (*Classifier, error) {
+	c := &Classifier{}
+
+	var section *[]string
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+			continue
+		case line == "[preview]":
+			section = &c.preview
+		case line == "[bot]":
+			section = &c.bot
+		case section == nil:
+			return nil, fmt.Errorf("line %d: pattern outside of a section", n)
+		default:
+			*section = append(*section, strings.ToLower(line))
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// MustParse is like Parse but panics on error.
+func MustParse(r io.Reader) *Classifier {
+	c, err := Parse(r)
+	if err != nil {
+		panic("botdetect: " + err.Error())
+	}
+
+	return c
+}
+
+// Classify classifies r with the built-in pattern list.
+func Classify(r *http.Request) string {
+	return defaultClassifier.Classify(r)
+}
+
+// Classify returns Preview for known link unfurlers and Bot for other known
+// agents, requests without a user agent, HEAD requests and prefetches. All
+// other requests are Human.
+func (c *Classifier) Classify(r *http.Request) string {
+	ua := strings.ToLower(r.UserAgent())
+	if ua == "" {
+		return Bot
+	}
+
+	for _, p := range c.preview {
+		if strings.Contains(ua, p) {
+			return Preview
+		}
+	}
+	for _, p := range c.bot {
+		if strings.Contains(ua, p) {
+			return Bot
+		}
+	}
+
+	// people follow links with GET, HEAD comes from link checkers
+	if r.Method == http.MethodHead || isPrefetch(r.Header) {
+		return Bot
+	}
+
+	return Human
+}
+
+// isPrefetch reports whether the request is a speculative load by the
+// browser rather than the visitor following the link.
+func isPrefetch(h http.Header) bool {
+	for _, name := range []string{"Sec-Purpose", "Purpose", "X-Purpose", "X-Moz"} {
+		v := strings.ToLower(h.Get(name))
+		if strings.Contains(v, "prefetch") || strings.Contains(v, "preview") || strings.Contains(v, "prerender") {
+			return true
+		}
+	}
+
+	return false
+}
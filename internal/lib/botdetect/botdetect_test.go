@@ -0,0 +1,93 @@
+package botdetect
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		ua     string
+		header map[string]string
+		want   string
+	}{
+		{
+			name: "chrome",
+			ua:   "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			want: Human,
+		},
+		{
+			name: "iphone safari",
+			ua:   "Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Mobile/15E148 Safari/604.1",
+			want: Human,
+		},
+		{
+			name: "slack unfurler",
+			ua:   "Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)",
+			want: Preview,
+		},
+		{name: "telegram", ua: "TelegramBot (like TwitterBot)", want: Preview},
+		{name: "twitter", ua: "Twitterbot/1.0", want: Preview},
+		{name: "facebook", ua: "facebookexternalhit/1.1 (+http://www.facebook.com/externalhit_uatext.php)", want: Preview},
+		{name: "whatsapp", ua: "WhatsApp/2.23.20.0", want: Preview},
+		{
+			name: "googlebot",
+			ua:   "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			want: Bot,
+		},
+		{name: "curl", ua: "curl/8.4.0", want: Bot},
+		{name: "go client", ua: "Go-http-client/1.1", want: Bot},
+		{name: "no user agent", ua: "", want: Bot},
+		{
+			name:   "head request",
+			method: http.MethodHead,
+			ua:     "Mozilla/5.0 (X11; Linux x86_64; rv:120.0) Gecko/20100101 Firefox/120.0",
+			want:   Bot,
+		},
+		{
+			name:   "chrome prefetch",
+			ua:     "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			header: map[string]string{"Sec-Purpose": "prefetch;prerender"},
+			want:   Bot,
+		},
+		{
+			name:   "firefox prefetch",
+			ua:     "Mozilla/5.0 (X11; Linux x86_64; rv:120.0) Gecko/20100101 Firefox/120.0",
+			header: map[string]string{"X-Moz": "prefetch"},
+			want:   Bot,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			r := httptest.NewRequest(method, "/abc", nil)
+			r.Header.Set("User-Agent", tt.ua)
+			for k, v := range tt.header {
+				r.Header.Set(k, v)
+			}
+
+			assert.Equal(t, tt.want, Classify(r))
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	c, err := Parse(strings.NewReader("# comment\n[preview]\nUnfurler\n\n[bot]\nfetcher\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"unfurler"}, c.preview)
+	assert.Equal(t, []string{"fetcher"}, c.bot)
+
+	_, err = Parse(strings.NewReader("fetcher\n"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,59 @@
+package dnsverify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// RecordPrefix is prepended to the hostname to get the name of the TXT record.
+	RecordPrefix = "_url-shortener."
+	// ValuePrefix is prepended to the verification token in the TXT record value.
+	ValuePrefix = "url-shortener-verification="
+)
+
+var ErrNotVerified = errors.New("verification record not found")
+
+// TXTResolver looks up DNS TXT records. *net.Resolver implements it.
+type TXTResolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+type Verifier struct {
+	resolver TXTResolver
+}
+
+func New(resolver TXTResolver) *Verifier {
+	return &Verifier{resolver: resolver}
+}
+
+// RecordName returns the name of the TXT record proving ownership of hostname.
+func RecordName(hostname string) string {
+	return RecordPrefix + hostname
+}
+
+// RecordValue returns the expected value of the TXT record.
+func RecordValue(token string) string {
+	return ValuePrefix + token
+}
+
+// Verify checks that the verification TXT record of hostname contains token.
+func (v *Verifier) Verify(ctx context.Context, hostname string, token string) error {
+	const op = "lib.dnsverify.Verify"
+
+	records, err := v.resolver.LookupTXT(ctx, RecordName(hostname))
+	if err != nil {
+		return fmt.Errorf("%s: %w: %w", op, ErrNotVerified, err)
+	}
+
+	expected := RecordValue(token)
+	for _, record := range records {
+		if strings.TrimSpace(record) == expected {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%s: %w", op, ErrNotVerified)
+}
@@ -0,0 +1,31 @@
+package dnsverify
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type stubResolver map[string][]string
+
+func (s stubResolver) LookupTXT(_ context.Context, name string) ([]string, error) {
+	records, ok := s[name]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+
+	return records, nil
+}
+
+func TestVerify(t *testing.T) {
+	v := New(stubResolver{
+		"_url-shortener.go.example.com":  {"v=spf1 -all", "url-shortener-verification=token123"},
+		"_url-shortener.bad.example.com": {"url-shortener-verification=other"},
+	})
+
+	assert.NoError(t, v.Verify(context.Background(), "go.example.com", "token123"))
+	assert.ErrorIs(t, v.Verify(context.Background(), "bad.example.com", "token123"), ErrNotVerified)
+	assert.ErrorIs(t, v.Verify(context.Background(), "missing.example.com", "token123"), ErrNotVerified)
+}
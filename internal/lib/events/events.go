@@ -0,0 +1,85 @@
+// Package events describes what happens to links for consumers outside the
+// request that caused it, such as webhooks.
+package events
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Event types.
+const (
+	LinkCreated = "link.created"
+	LinkUpdated = "link.updated"
+	LinkDeleted = "link.deleted"
+	LinkClicked = "link.clicked"
+)
+
+// Types lists all event types in the order they are documented.
+var Types = []string{LinkCreated, LinkUpdated, LinkDeleted, LinkClicked}
+
+// Event is something that happened to the link Domain/Alias. Data is
+// marshalled to JSON as is.
+type Event struct {
+	Type       string
+	Domain     string
+	Alias      string
+	Data       any
+	OccurredAt time.Time
+}
+
+// Publisher hands events to their consumers. Producers log failures and go on,
+// an event that can't be published must not fail the request that caused it.
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// New returns an event of the given type happening now.
+func New(eventType string, domain string, alias string, data any) Event {
+	return Event{
+		Type:       eventType,
+		Domain:     domain,
+		Alias:      alias,
+		Data:       data,
+		OccurredAt: time.Now().UTC(),
+	}
+}
+
+// LinkData is the data of link.created, link.updated and link.deleted events.
+// Updates carry only the changed fields, listed in Changed.
+type LinkData struct {
+	URL         string   `json:"url,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Folder      string   `json:"folder,omitempty"`
+	Title       string   `json:"title,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Changed     []string `json:"changed,omitempty"`
+}
+
+// ClickData is the data of link.clicked events.
+type ClickData struct {
+	VariantID int64  `json:"variant_id,omitempty"`
+	VisitorID string `json:"visitor_id,omitempty"`
+	// VisitorKey is what unique visitors are counted by: the visitor id if
+	// the visitor sent its cookie, a fingerprint otherwise, see
+	// visitors.Fingerprint. It is kept out of payloads.
+	VisitorKey string `json:"-"`
+	// Class is human, bot or preview.
+	Class string `json:"class,omitempty"`
+}
+
+// Multi publishes every event to all publishers. All of them get the event
+// even if some fail, the errors are joined.
+type Multi []Publisher
+
+func (m Multi) Publish(ctx context.Context, event Event) error {
+	var errs []error
+	for _, p := range m {
+		if err := p.Publish(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
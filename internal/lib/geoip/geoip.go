@@ -0,0 +1,46 @@
+package geoip
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// Reader resolves countries from an offline MaxMind DB (GeoLite2/GeoIP2 Country or City).
+type Reader struct {
+	db *maxminddb.Reader
+}
+
+type record struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+}
+
+func New(path string) (*Reader, error) {
+	const op = "lib.geoip.New"
+
+	db, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Reader{db: db}, nil
+}
+
+// Country returns the ISO 3166-1 alpha-2 code of the country ip belongs to.
+func (r *Reader) Country(ip net.IP) (string, error) {
+	const op = "lib.geoip.Country"
+
+	var rec record
+	if err := r.db.Lookup(ip, &rec); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return rec.Country.ISOCode, nil
+}
+
+func (r *Reader) Close() error {
+	return r.db.Close()
+}
@@ -0,0 +1,267 @@
+// Package hll counts distinct values with HyperLogLog sketches.
+//
+// A sketch has 2^14 registers, so estimates have a standard error of
+// 1.04/sqrt(2^14) ≈ 0.81%: about 68% of them are within 0.81% of the true
+// count, 95% within 1.6% and 99.7% within 2.5%. The error does not depend on
+// the count and does not grow when sketches are merged. Counts are estimated
+// with Ertl's improved estimator ("New cardinality estimation algorithms for
+// HyperLogLog sketches", 2017), which stays unbiased from a handful of values
+// up to billions without bias tables. Small counts are close to exact.
+//
+// A sketch holding few values is kept sparse and takes 3 bytes per value, it
+// becomes dense (16 KiB) once that would take more space than it saves.
+package hll
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/fnv"
+	"math"
+	"math/bits"
+	"sort"
+)
+
+const (
+	precision = 14
+	registers = 1 << precision
+	// maxRank is the largest register value, q+1 in Ertl's notation.
+	maxRank = 64 - precision + 1
+
+	// maxSparse is the number of sparse entries a sketch turns dense at.
+	maxSparse = registers / 4
+
+	version  = 1
+	sparse   = 0
+	dense    = 1
+	header   = 3
+	entryLen = 3
+)
+
+// RelativeError is the standard error of estimates.
+var RelativeError = 1.04 / math.Sqrt(registers)
+
+var ErrInvalidSketch = errors.New("invalid sketch")
+
+// Sketch estimates the number of distinct values added to it. The zero value
+// is an empty sketch. A Sketch is not safe for concurrent use.
+type Sketch struct {
+	// sparse holds non-zero registers until the sketch turns dense.
+	sparse map[uint16]uint8
+	dense  []uint8
+}
+
+// New returns an empty sketch.
+func New() *Sketch {
+	return &Sketch{}
+}
+
+// Add adds the value to the sketch.
+func (s *Sketch) Add(value string) {
+	h := hash(value)
+	idx := uint16(h >> (64 - precision))
+	rank := uint8(bits.LeadingZeros64(h<<precision)) + 1
+	if rank > maxRank {
+		rank = maxRank
+	}
+
+	s.set(idx, rank)
+}
+
+// Merge adds all values of other to the sketch, so it estimates the count of
+// the union of both.
+func (s *Sketch) Merge(other *Sketch) {
+	if other.dense != nil {
+		for idx, rank := range other.dense {
+			if rank > 0 {
+				s.set(uint16(idx), rank)
+			}
+		}
+		return
+	}
+
+	for idx, rank := range other.sparse {
+		s.set(idx, rank)
+	}
+}
+
+// Estimate returns the estimated number of distinct values.
+func (s *Sketch) Estimate() uint64 {
+	// histogram of register values
+	var c [maxRank + 1]float64
+	if s.dense != nil {
+		for _, rank := range s.dense {
+			c[rank]++
+		}
+	} else {
+		c[0] = registers - float64(len(s.sparse))
+		for _, rank := range s.sparse {
+			c[rank]++
+		}
+	}
+
+	const m = float64(registers)
+	if c[0] == m {
+		return 0
+	}
+
+	z := m * tau(1-c[maxRank]/m)
+	for k := maxRank - 1; k >= 1; k-- {
+		z += c[k]
+		z *= 0.5
+	}
+	z += m * sigma(c[0]/m)
+
+	return uint64(math.Round(m * m / (2 * math.Ln2 * z)))
+}
+
+// MarshalBinary encodes the sketch.
+func (s *Sketch) MarshalBinary() ([]byte, error) {
+	if s.dense != nil {
+		b := make([]byte, header, header+registers)
+		b[0], b[1], b[2] = version, precision, dense
+		return append(b, s.dense...), nil
+	}
+
+	idxs := make([]int, 0, len(s.sparse))
+	for idx := range s.sparse {
+		idxs = append(idxs, int(idx))
+	}
+	sort.Ints(idxs)
+
+	b := make([]byte, header, header+entryLen*len(idxs))
+	b[0], b[1], b[2] = version, precision, sparse
+	for _, idx := range idxs {
+		b = binary.BigEndian.AppendUint16(b, uint16(idx))
+		b = append(b, s.sparse[uint16(idx)])
+	}
+
+	return b, nil
+}
+
+// UnmarshalBinary replaces the sketch with one encoded by MarshalBinary.
+func (s *Sketch) UnmarshalBinary(b []byte) error {
+	if len(b) < header || b[0] != version || b[1] != precision {
+		return ErrInvalidSketch
+	}
+
+	switch b[2] {
+	case dense:
+		if len(b) != header+registers {
+			return ErrInvalidSketch
+		}
+		regs := make([]uint8, registers)
+		for i, rank := range b[header:] {
+			if rank > maxRank {
+				return ErrInvalidSketch
+			}
+			regs[i] = rank
+		}
+		s.sparse, s.dense = nil, regs
+	case sparse:
+		entries := b[header:]
+		if len(entries)%entryLen != 0 || len(entries)/entryLen > maxSparse {
+			return ErrInvalidSketch
+		}
+		regs := make(map[uint16]uint8, len(entries)/entryLen)
+		for i := 0; i < len(entries); i += entryLen {
+			idx := binary.BigEndian.Uint16(entries[i:])
+			rank := entries[i+2]
+			if int(idx) >= registers || rank == 0 || rank > maxRank {
+				return ErrInvalidSketch
+			}
+			regs[idx] = rank
+		}
+		s.sparse, s.dense = regs, nil
+	default:
+		return ErrInvalidSketch
+	}
+
+	return nil
+}
+
+// Parse decodes a sketch encoded by MarshalBinary.
+func Parse(b []byte) (*Sketch, error) {
+	s := New()
+	if err := s.UnmarshalBinary(b); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *Sketch) set(idx uint16, rank uint8) {
+	if s.dense != nil {
+		if rank > s.dense[idx] {
+			s.dense[idx] = rank
+		}
+		return
+	}
+
+	if s.sparse == nil {
+		s.sparse = make(map[uint16]uint8)
+	}
+	if rank <= s.sparse[idx] {
+		return
+	}
+	s.sparse[idx] = rank
+
+	if len(s.sparse) >= maxSparse {
+		s.dense = make([]uint8, registers)
+		for i, r := range s.sparse {
+			s.dense[i] = r
+		}
+		s.sparse = nil
+	}
+}
+
+// hash is FNV-1a followed by the murmur3 finalizer, FNV alone doesn't spread
+// short similar strings over the high bits well enough.
+func hash(value string) uint64 {
+	f := fnv.New64a()
+	_, _ = f.Write([]byte(value))
+	h := f.Sum64()
+
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+
+	return h
+}
+
+func sigma(x float64) float64 {
+	if x == 1 {
+		return math.Inf(1)
+	}
+
+	y := 1.0
+	z := x
+	for {
+		x *= x
+		prev := z
+		z += x * y
+		y += y
+		if z == prev {
+			return z
+		}
+	}
+}
+
+func tau(x float64) float64 {
+	if x == 0 || x == 1 {
+		return 0
+	}
+
+	y := 1.0
+	z := 1 - x
+	for {
+		x = math.Sqrt(x)
+		prev := z
+		y *= 0.5
+		z -= (1 - x) * (1 - x) * y
+		if z == prev {
+			return z / 3
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package hll
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// within3Sigma reports whether the estimate is within three standard errors of
+// n, which holds for 99.7% of estimates.
+func within3Sigma(t *testing.T, n int, estimate uint64) {
+	t.Helper()
+
+	relErr := math.Abs(float64(estimate)-float64(n)) / float64(n)
+	assert.LessOrEqualf(t, relErr, 3*RelativeError, "n=%d estimate=%d error=%.4f", n, estimate, relErr)
+}
+
+func TestEstimateAccuracy(t *testing.T) {
+	for _, n := range []int{1000, 5000, 20000, 50000, 100000, 1000000} {
+		t.Run(strconv.Itoa(n), func(t *testing.T) {
+			s := New()
+			for i := range n {
+				s.Add("visitor-" + strconv.Itoa(i))
+			}
+			within3Sigma(t, n, s.Estimate())
+		})
+	}
+}
+
+func TestEstimateSmallCountsNearlyExact(t *testing.T) {
+	s := New()
+	assert.Equal(t, uint64(0), s.Estimate())
+
+	for i := range 100 {
+		s.Add("visitor-" + strconv.Itoa(i))
+		// repeated values are not counted again
+		s.Add("visitor-" + strconv.Itoa(i))
+	}
+	assert.InDelta(t, 100, float64(s.Estimate()), 1)
+}
+
+func TestMergeEstimatesUnion(t *testing.T) {
+	days := make([]*Sketch, 7)
+	union := New()
+	for d := range days {
+		days[d] = New()
+		// consecutive days share half of their visitors
+		for i := d * 5000; i < d*5000+10000; i++ {
+			days[d].Add("visitor-" + strconv.Itoa(i))
+			union.Add("visitor-" + strconv.Itoa(i))
+		}
+	}
+
+	merged := New()
+	for _, s := range days {
+		merged.Merge(s)
+	}
+
+	assert.Equal(t, union.Estimate(), merged.Estimate())
+	within3Sigma(t, 40000, merged.Estimate())
+}
+
+func TestMergeSparseIntoDense(t *testing.T) {
+	big, small := New(), New()
+	for i := range 50000 {
+		big.Add("a-" + strconv.Itoa(i))
+	}
+	for i := range 10 {
+		small.Add("b-" + strconv.Itoa(i))
+	}
+
+	small.Merge(big)
+	within3Sigma(t, 50010, small.Estimate())
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	for _, n := range []int{0, 10, 100000} {
+		s := New()
+		for i := range n {
+			s.Add(strconv.Itoa(i))
+		}
+
+		b, err := s.MarshalBinary()
+		require.NoError(t, err)
+		if n == 10 {
+			assert.Len(t, b, header+10*entryLen)
+		}
+
+		parsed, err := Parse(b)
+		require.NoError(t, err)
+		assert.Equal(t, s.Estimate(), parsed.Estimate())
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, b := range [][]byte{
+		nil,
+		{2, precision, sparse},
+		{version, 12, sparse},
+		{version, precision, dense, 1, 2},
+		{version, precision, sparse, 0, 1},
+		{version, precision, sparse, 0xff, 0xff, 1},
+		{version, precision, sparse, 0, 1, 0},
+	} {
+		_, err := Parse(b)
+		assert.ErrorIs(t, err, ErrInvalidSketch)
+	}
+}
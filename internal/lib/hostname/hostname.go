@@ -0,0 +1,11 @@
+// Package hostname normalizes custom domain names so claims, links and Host
+// headers agree on one spelling.
+package hostname
+
+import "strings"
+
+// Normalize trims surrounding spaces and the trailing dot of a fully
+// qualified name and lower-cases it.
+func Normalize(name string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+}
@@ -0,0 +1,23 @@
+package hostname_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"url-shortener/internal/lib/hostname"
+)
+
+func TestNormalize(t *testing.T) {
+	cases := map[string]string{
+		"":                    "",
+		"links.example.com":   "links.example.com",
+		"Links.Example.COM":   "links.example.com",
+		"links.example.com.":  "links.example.com",
+		" Links.example.com ": "links.example.com",
+	}
+
+	for in, want := range cases {
+		assert.Equal(t, want, hostname.Normalize(in), in)
+	}
+}
@@ -0,0 +1,196 @@
+// Package hub fans link events out to in-process subscribers such as live
+// click streams.
+package hub
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+
+	"url-shortener/internal/lib/events"
+	"url-shortener/internal/lib/logger/sl"
+)
+
+// OwnerResolver returns the id of the user owning a link.
+type OwnerResolver interface {
+	GetURLOwner(ctx context.Context, domain string, alias string) (int64, error)
+}
+
+// Filter selects the events a subscriber gets. Zero fields match anything.
+type Filter struct {
+	Type string
+	// UserID matches events of links owned by the user.
+	UserID int64
+	// Domain and Alias match a single link when Alias is set.
+	Domain string
+	Alias  string
+}
+
+// Hub is an events.Publisher delivering events to subscribers. Publishing
+// never blocks: a subscriber that doesn't keep up loses events and is told
+// how many through Dropped.
+type Hub struct {
+	log        *slog.Logger
+	owners     OwnerResolver
+	bufferSize int
+
+	mu     sync.RWMutex
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+func New(log *slog.Logger, owners OwnerResolver, bufferSize int) *Hub {
+	if bufferSize < 1 {
+		bufferSize = 1
+	}
+
+	return &Hub{
+		log:        log.With(slog.String("component", "hub")),
+		owners:     owners,
+		bufferSize: bufferSize,
+		subs:       make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscription receives events matching its filter until it is closed.
+type Subscription struct {
+	hub     *Hub
+	filter  Filter
+	ch      chan events.Event
+	done    chan struct{}
+	dropped atomic.Int64
+	once    sync.Once
+}
+
+// Subscribe registers a subscriber. Close the subscription when done. After
+// the hub is closed the subscription is returned already done.
+func (h *Hub) Subscribe(filter Filter) *Subscription {
+	sub := &Subscription{
+		hub:    h,
+		filter: filter,
+		ch:     make(chan events.Event, h.bufferSize),
+		done:   make(chan struct{}),
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		sub.once.Do(func() { close(sub.done) })
+		return sub
+	}
+	h.subs[sub] = struct{}{}
+
+	return sub
+}
+
+// Close ends all subscriptions so subscribers such as live streams return,
+// e.g. when the server shuts down.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	h.closed = true
+	subs := make([]*Subscription, 0, len(h.subs))
+	for sub := range h.subs {
+		subs = append(subs, sub)
+	}
+	h.mu.Unlock()
+
+	for _, sub := range subs {
+		sub.Close()
+	}
+}
+
+// Events returns the channel events are delivered on. It is never closed,
+// select on it together with Done and the subscriber's context.
+func (s *Subscription) Events() <-chan events.Event {
+	return s.ch
+}
+
+// Done is closed when the subscription is closed, by the subscriber or by
+// the hub.
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// Dropped returns the number of events lost since the last call because the
+// subscriber's buffer was full.
+func (s *Subscription) Dropped() int64 {
+	return s.dropped.Swap(0)
+}
+
+// Close unsubscribes. It is safe to call more than once.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.hub.mu.Lock()
+		delete(s.hub.subs, s)
+		s.hub.mu.Unlock()
+		close(s.done)
+	})
+}
+
+// Subscribers returns the number of active subscriptions.
+func (h *Hub) Subscribers() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return len(h.subs)
+}
+
+// Publish hands the event to matching subscribers without waiting for them.
+// The owner lookup and the sends happen outside the lock, so a slow lookup
+// doesn't hold up Subscribe and Close, and the redirects waiting behind them.
+func (h *Hub) Publish(ctx context.Context, event events.Event) error {
+	h.mu.RLock()
+	matched := make([]*Subscription, 0, len(h.subs))
+	for sub := range h.subs {
+		f := sub.filter
+		if f.Type != "" && f.Type != event.Type {
+			continue
+		}
+		if f.Alias != "" && (f.Alias != event.Alias || f.Domain != event.Domain) {
+			continue
+		}
+		matched = append(matched, sub)
+	}
+	h.mu.RUnlock()
+
+	var (
+		owner    int64
+		resolved bool
+	)
+	for _, sub := range matched {
+		if userID := sub.filter.UserID; userID != 0 {
+			// only looked up when someone streams all links of a user, and
+			// never cached: links change hands on transfer and aliases are
+			// reused after a purge
+			if !resolved {
+				owner = h.owner(ctx, event.Domain, event.Alias)
+				resolved = true
+			}
+			if owner != userID {
+				continue
+			}
+		}
+
+		// a subscription closed meanwhile never reads its channel again,
+		// which is never closed, so the send is harmless
+		select {
+		case sub.ch <- event:
+		default:
+			sub.dropped.Add(1)
+		}
+	}
+
+	return nil
+}
+
+func (h *Hub) owner(ctx context.Context, domain string, alias string) int64 {
+	userID, err := h.owners.GetURLOwner(ctx, domain, alias)
+	if err != nil {
+		h.log.Error("failed to get link owner", slog.String("alias", alias), sl.Err(err))
+		return 0
+	}
+
+	return userID
+}
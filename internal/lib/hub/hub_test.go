@@ -0,0 +1,159 @@
+package hub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/events"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+type ownersFunc func(domain string, alias string) int64
+
+func (f ownersFunc) GetURLOwner(_ context.Context, domain string, alias string) (int64, error) {
+	return f(domain, alias), nil
+}
+
+func TestPublishFilters(t *testing.T) {
+	h := New(slogdiscard.NewDiscardLogger(), ownersFunc(func(_, alias string) int64 {
+		if alias == "mine" {
+			return 1
+		}
+		return 2
+	}), 4)
+
+	link := h.Subscribe(Filter{Type: events.LinkClicked, Domain: "go.example.com", Alias: "mine"})
+	defer link.Close()
+	user := h.Subscribe(Filter{Type: events.LinkClicked, UserID: 1})
+	defer user.Close()
+
+	ctx := context.Background()
+	require.NoError(t, h.Publish(ctx, events.New(events.LinkClicked, "go.example.com", "mine", nil)))
+	require.NoError(t, h.Publish(ctx, events.New(events.LinkClicked, "", "mine", nil)))
+	require.NoError(t, h.Publish(ctx, events.New(events.LinkClicked, "", "other", nil)))
+	require.NoError(t, h.Publish(ctx, events.New(events.LinkUpdated, "go.example.com", "mine", nil)))
+
+	assert.Len(t, link.Events(), 1)
+	assert.Len(t, user.Events(), 2)
+}
+
+func TestPublishFollowsOwnerChanges(t *testing.T) {
+	owner := int64(1)
+	h := New(slogdiscard.NewDiscardLogger(), ownersFunc(func(_, _ string) int64 {
+		return owner
+	}), 4)
+
+	first := h.Subscribe(Filter{UserID: 1})
+	defer first.Close()
+	second := h.Subscribe(Filter{UserID: 2})
+	defer second.Close()
+
+	ctx := context.Background()
+	require.NoError(t, h.Publish(ctx, events.New(events.LinkClicked, "", "abc", nil)))
+
+	// transferred to another user
+	owner = 2
+	require.NoError(t, h.Publish(ctx, events.New(events.LinkClicked, "", "abc", nil)))
+
+	assert.Len(t, first.Events(), 1)
+	assert.Len(t, second.Events(), 1)
+}
+
+func TestSlowSubscriberDropsEvents(t *testing.T) {
+	h := New(slogdiscard.NewDiscardLogger(), nil, 2)
+
+	sub := h.Subscribe(Filter{})
+	defer sub.Close()
+
+	for range 5 {
+		require.NoError(t, h.Publish(context.Background(), events.New(events.LinkClicked, "", "abc", nil)))
+	}
+
+	assert.Len(t, sub.Events(), 2)
+	assert.Equal(t, int64(3), sub.Dropped())
+	assert.Equal(t, int64(0), sub.Dropped())
+}
+
+func TestClose(t *testing.T) {
+	h := New(slogdiscard.NewDiscardLogger(), nil, 1)
+
+	sub := h.Subscribe(Filter{})
+	assert.Equal(t, 1, h.Subscribers())
+
+	sub.Close()
+	sub.Close()
+	assert.Equal(t, 0, h.Subscribers())
+
+	require.NoError(t, h.Publish(context.Background(), events.New(events.LinkClicked, "", "abc", nil)))
+	assert.Empty(t, sub.Events())
+}
+
+func TestHubClose(t *testing.T) {
+	h := New(slogdiscard.NewDiscardLogger(), nil, 1)
+
+	sub := h.Subscribe(Filter{})
+	h.Close()
+
+	assert.Equal(t, 0, h.Subscribers())
+	select {
+	case <-sub.Done():
+	default:
+		t.Fatal("subscription not done after hub close")
+	}
+
+	late := h.Subscribe(Filter{})
+	assert.Equal(t, 0, h.Subscribers())
+	select {
+	case <-late.Done():
+	default:
+		t.Fatal("subscription after hub close not done")
+	}
+	late.Close()
+}
+
+type blockingOwners struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b blockingOwners) GetURLOwner(context.Context, string, string) (int64, error) {
+	close(b.started)
+	<-b.release
+
+	return 1, nil
+}
+
+func TestSlowOwnerLookupDoesNotBlockSubscribe(t *testing.T) {
+	owners := blockingOwners{started: make(chan struct{}), release: make(chan struct{})}
+	h := New(slogdiscard.NewDiscardLogger(), owners, 1)
+
+	sub := h.Subscribe(Filter{UserID: 1})
+	defer sub.Close()
+
+	published := make(chan struct{})
+	go func() {
+		_ = h.Publish(context.Background(), events.New(events.LinkClicked, "", "abc", nil))
+		close(published)
+	}()
+	<-owners.started
+
+	subscribed := make(chan struct{})
+	go func() {
+		h.Subscribe(Filter{}).Close()
+		close(subscribed)
+	}()
+
+	select {
+	case <-subscribed:
+	case <-time.After(time.Second):
+		t.Fatal("subscribe blocked by owner lookup")
+	}
+
+	close(owners.release)
+	<-published
+	assert.Len(t, sub.Events(), 1)
+}
@@ -0,0 +1,40 @@
+// Package idempotency prunes expired idempotency keys, see the idempotency
+// middleware.
+package idempotency
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"url-shortener/internal/lib/logger/sl"
+)
+
+// Pruner deletes idempotency keys saved before the given time.
+type Pruner interface {
+	PruneIdempotencyKeys(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Run prunes keys older than ttl every interval until ctx is done.
+func Run(ctx context.Context, log *slog.Logger, pruner Pruner, ttl time.Duration, interval time.Duration) {
+	log = log.With(slog.String("component", "idempotency"))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := pruner.PruneIdempotencyKeys(ctx, time.Now().Add(-ttl))
+		if err != nil {
+			// retried on the next tick
+			log.Error("failed to prune idempotency keys", sl.Err(err))
+		} else if n > 0 {
+			log.Info("pruned idempotency keys", slog.Int64("count", n))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package idempotency_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/idempotency"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+type prunerFunc func(ctx context.Context, before time.Time) (int64, error)
+
+func (f prunerFunc) PruneIdempotencyKeys(ctx context.Context, before time.Time) (int64, error) {
+	return f(ctx, before)
+}
+
+func TestRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := make(chan time.Time, 1)
+
+	pruner := prunerFunc(func(_ context.Context, before time.Time) (int64, error) {
+		calls <- before
+		cancel()
+		return 1, nil
+	})
+
+	done := make(chan struct{})
+	go func() {
+		idempotency.Run(ctx, slogdiscard.NewDiscardLogger(), pruner, 24*time.Hour, time.Hour)
+		close(done)
+	}()
+
+	select {
+	case before := <-calls:
+		assert.WithinDuration(t, time.Now().Add(-24*time.Hour), before, time.Minute)
+	case <-time.After(time.Second):
+		require.Fail(t, "prune didn't run on start")
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		require.Fail(t, "Run didn't stop after cancel")
+	}
+}
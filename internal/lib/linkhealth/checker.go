@@ -0,0 +1,105 @@
+// Package linkhealth periodically checks that link destinations still respond.
+package linkhealth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"url-shortener/internal/lib/netguard"
+)
+
+var ErrTooManyRedirects = errors.New("too many redirects")
+
+// CheckerOptions limit a single check.
+type CheckerOptions struct {
+	Timeout      time.Duration
+	MaxRedirects int
+	// UserAgent should name the bot and where to learn about it so site
+	// owners can tell the checks apart from visitors.
+	UserAgent string
+	// AllowPrivate lets the checker connect to loopback and private networks.
+	// Only tests should need it.
+	AllowPrivate bool
+}
+
+// HTTPChecker checks destinations with a HEAD request, falling back to GET
+// for servers that don't answer HEAD properly.
+type HTTPChecker struct {
+	client    *http.Client
+	userAgent string
+}
+
+func NewChecker(opts CheckerOptions) *HTTPChecker {
+	dialer := &net.Dialer{Timeout: opts.Timeout}
+	if !opts.AllowPrivate {
+		dialer.Control = netguard.PublicOnly
+	}
+
+	maxRedirects := opts.MaxRedirects
+
+	return &HTTPChecker{
+		client: &http.Client{
+			Transport: &http.Transport{
+				Proxy:                 nil,
+				DialContext:           dialer.DialContext,
+				TLSHandshakeTimeout:   opts.Timeout,
+				ResponseHeaderTimeout: opts.Timeout,
+				MaxIdleConns:          100,
+				MaxIdleConnsPerHost:   2,
+				IdleConnTimeout:       time.Minute,
+			},
+			Timeout: opts.Timeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) > maxRedirects {
+					return ErrTooManyRedirects
+				}
+				return nil
+			},
+		},
+		userAgent: opts.UserAgent,
+	}
+}
+
+// Check returns the final status code of the destination after redirects.
+func (c *HTTPChecker) Check(ctx context.Context, rawURL string) (int, error) {
+	status, err := c.do(ctx, http.MethodHead, rawURL)
+	if err != nil {
+		return 0, err
+	}
+
+	// plenty of servers reject or mishandle HEAD while serving GET fine
+	if status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented ||
+		status == http.StatusForbidden || status == http.StatusNotFound {
+		return c.do(ctx, http.MethodGet, rawURL)
+	}
+
+	return status, nil
+}
+
+func (c *HTTPChecker) do(ctx context.Context, method string, rawURL string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return 0, fmt.Errorf("scheme %s: %w", req.URL.Scheme, netguard.ErrForbiddenAddress)
+	}
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	// drain a little so the connection can be reused, don't download pages
+	io.Copy(io.Discard, io.LimitReader(res.Body, 4096))
+	res.Body.Close()
+
+	return res.StatusCode, nil
+}
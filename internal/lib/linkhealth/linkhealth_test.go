@@ -0,0 +1,238 @@
+package linkhealth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/lib/netguard"
+	"url-shortener/internal/models"
+)
+
+func testChecker() *HTTPChecker {
+	return NewChecker(CheckerOptions{
+		Timeout:      time.Second,
+		MaxRedirects: 2,
+		UserAgent:    "test-bot",
+		AllowPrivate: true,
+	})
+}
+
+func TestCheck(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodHead, r.Method)
+		assert.Equal(t, "test-bot", r.Header.Get("User-Agent"))
+	})
+	mux.HandleFunc("/no-head", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/gone", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ok", http.StatusMovedPermanently)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	c := testChecker()
+
+	cases := []struct {
+		path   string
+		status int
+		err    error
+	}{
+		{path: "/ok", status: http.StatusOK},
+		{path: "/no-head", status: http.StatusOK},
+		{path: "/gone", status: http.StatusGone},
+		{path: "/missing", status: http.StatusNotFound},
+		{path: "/moved", status: http.StatusOK},
+		{path: "/loop", err: ErrTooManyRedirects},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.path, func(t *testing.T) {
+			status, err := c.Check(context.Background(), srv.URL+tc.path)
+			if tc.err != nil {
+				assert.True(t, errors.Is(err, tc.err), err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.status, status)
+		})
+	}
+}
+
+func TestCheckPrivateAddress(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("private address must not be reached")
+	}))
+	defer srv.Close()
+
+	c := NewChecker(CheckerOptions{Timeout: time.Second})
+
+	_, err := c.Check(context.Background(), srv.URL)
+	assert.True(t, errors.Is(err, netguard.ErrForbiddenAddress), err)
+}
+
+type memStore struct {
+	mu     sync.Mutex
+	urls   []models.URL
+	health map[int64]models.LinkHealth
+}
+
+func (s *memStore) GetDueHealthChecks(_ context.Context, now time.Time, limit int) ([]models.URL, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []models.URL
+	for _, u := range s.urls {
+		h, ok := s.health[u.ID]
+		if ok && h.NextCheckAt.After(now) {
+			continue
+		}
+		if ok {
+			u.Health = &h
+		}
+		due = append(due, u)
+		if len(due) == limit {
+			break
+		}
+	}
+
+	return due, nil
+}
+
+func (s *memStore) SaveLinkHealth(_ context.Context, urlID int64, health models.LinkHealth) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.health[urlID] = health
+
+	return nil
+}
+
+func TestMonitorBrokenAndRecovered(t *testing.T) {
+	var down atomic.Bool
+	down.Store(true)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+
+	store := &memStore{
+		urls:   []models.URL{{ID: 1, URL: srv.URL + "/page"}},
+		health: make(map[int64]models.LinkHealth),
+	}
+
+	m := New(slogdiscard.NewDiscardLogger(), store, testChecker(), Options{
+		Interval:         24 * time.Hour,
+		RetryInterval:    time.Hour,
+		FailureThreshold: 3,
+		BatchSize:        10,
+	})
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+
+	wantWaits := []time.Duration{time.Hour, 2 * time.Hour, 4 * time.Hour}
+	for i, wait := range wantWaits {
+		require.Equal(t, 1, m.CheckDue(context.Background()))
+
+		h := store.health[1]
+		assert.Equal(t, http.StatusBadGateway, h.StatusCode)
+		assert.Equal(t, i+1, h.Failures)
+		assert.Equal(t, now.Add(wait), h.NextCheckAt)
+		assert.Equal(t, i+1 >= 3, h.BrokenAt != nil, "broken after %d failures", i+1)
+
+		assert.Equal(t, 0, m.CheckDue(context.Background()), "not due before backoff")
+		now = h.NextCheckAt
+	}
+
+	down.Store(false)
+	require.Equal(t, 1, m.CheckDue(context.Background()))
+
+	h := store.health[1]
+	assert.Equal(t, http.StatusOK, h.StatusCode)
+	assert.Zero(t, h.Failures)
+	assert.Nil(t, h.BrokenAt)
+	assert.Equal(t, now.Add(24*time.Hour), h.NextCheckAt)
+}
+
+func TestMonitorRateLimited(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	store := &memStore{
+		urls:   []models.URL{{ID: 1, URL: srv.URL}},
+		health: make(map[int64]models.LinkHealth),
+	}
+
+	m := New(slogdiscard.NewDiscardLogger(), store, testChecker(), Options{
+		Interval:         24 * time.Hour,
+		RetryInterval:    time.Hour,
+		FailureThreshold: 1,
+		BatchSize:        10,
+	})
+
+	m.CheckDue(context.Background())
+
+	h := store.health[1]
+	assert.Zero(t, h.Failures)
+	assert.Nil(t, h.BrokenAt)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), h.NextCheckAt, time.Minute)
+}
+
+func TestMonitorPerHostConcurrency(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			m := maxInFlight.Load()
+			if n <= m || maxInFlight.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	store := &memStore{health: make(map[int64]models.LinkHealth)}
+	for i := 1; i <= 10; i++ {
+		store.urls = append(store.urls, models.URL{ID: int64(i), URL: fmt.Sprintf("%s/%d", srv.URL, i)})
+	}
+
+	m := New(slogdiscard.NewDiscardLogger(), store, testChecker(), Options{
+		Interval:           time.Hour,
+		FailureThreshold:   1,
+		Concurrency:        8,
+		PerHostConcurrency: 2,
+		BatchSize:          10,
+	})
+
+	require.Equal(t, 10, m.CheckDue(context.Background()))
+	assert.Len(t, store.health, 10)
+	assert.LessOrEqual(t, maxInFlight.Load(), int32(2))
+}
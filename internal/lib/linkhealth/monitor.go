@@ -0,0 +1,207 @@
+package linkhealth
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/models"
+)
+
+// Checker requests a destination and returns its status code.
+type Checker interface {
+	Check(ctx context.Context, rawURL string) (int, error)
+}
+
+// Store hands out links due for a check and keeps the results.
+type Store interface {
+	GetDueHealthChecks(ctx context.Context, now time.Time, limit int) ([]models.URL, error)
+	SaveLinkHealth(ctx context.Context, urlID int64, health models.LinkHealth) error
+}
+
+type Options struct {
+	// Interval is how often a healthy link is checked and the longest a
+	// failing link waits between checks.
+	Interval time.Duration
+	// RetryInterval is the wait after the first failure, it doubles with
+	// every further failure up to Interval.
+	RetryInterval time.Duration
+	// FailureThreshold is the number of consecutive failures after which a link is broken.
+	FailureThreshold int
+	Concurrency      int
+	// PerHostConcurrency limits parallel checks of the same host.
+	PerHostConcurrency int
+	// BatchSize is the number of links checked per poll.
+	BatchSize    int
+	PollInterval time.Duration
+}
+
+// Monitor checks links that are due in batches.
+type Monitor struct {
+	log     *slog.Logger
+	store   Store
+	checker Checker
+	opts    Options
+	now     func() time.Time
+}
+
+func New(log *slog.Logger, store Store, checker Checker, opts Options) *Monitor {
+	if opts.Concurrency < 1 {
+		opts.Concurrency = 1
+	}
+	if opts.PerHostConcurrency < 1 {
+		opts.PerHostConcurrency = 1
+	}
+	if opts.FailureThreshold < 1 {
+		opts.FailureThreshold = 1
+	}
+
+	return &Monitor{
+		log:     log.With(slog.String("component", "linkhealth")),
+		store:   store,
+		checker: checker,
+		opts:    opts,
+		now:     time.Now,
+	}
+}
+
+// Run checks due links every poll interval until ctx is done.
+func (m *Monitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		m.CheckDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CheckDue checks one batch of due links and returns how many were checked.
+func (m *Monitor) CheckDue(ctx context.Context) int {
+	urls, err := m.store.GetDueHealthChecks(ctx, m.now(), m.opts.BatchSize)
+	if err != nil {
+		m.log.Error("failed to get links due for a check", sl.Err(err))
+		return 0
+	}
+
+	var (
+		wg     sync.WaitGroup
+		global = make(chan struct{}, m.opts.Concurrency)
+		hosts  = make(map[string]chan struct{})
+	)
+
+	for _, u := range urls {
+		host := hostOf(u.URL)
+		hostSem, ok := hosts[host]
+		if !ok {
+			hostSem = make(chan struct{}, m.opts.PerHostConcurrency)
+			hosts[host] = hostSem
+		}
+
+		wg.Add(1)
+		go func(u models.URL) {
+			defer wg.Done()
+
+			// the host slot first so links of a busy host don't hold global slots
+			hostSem <- struct{}{}
+			defer func() { <-hostSem }()
+			global <- struct{}{}
+			defer func() { <-global }()
+
+			if ctx.Err() != nil {
+				return
+			}
+			m.check(ctx, u)
+		}(u)
+	}
+	wg.Wait()
+
+	return len(urls)
+}
+
+func (m *Monitor) check(ctx context.Context, u models.URL) {
+	status, err := m.checker.Check(ctx, u.URL)
+	if ctx.Err() != nil {
+		// shutting down, the link stays due
+		return
+	}
+
+	health := m.next(u.Health, status, err)
+	if health.BrokenAt != nil && (u.Health == nil || u.Health.BrokenAt == nil) {
+		m.log.Info("link is broken", slog.Int64("url_id", u.ID), slog.String("url", u.URL),
+			slog.Int("status", status), slog.Int("failures", health.Failures))
+	}
+
+	if err := m.store.SaveLinkHealth(ctx, u.ID, health); err != nil {
+		m.log.Error("failed to save link health", slog.Int64("url_id", u.ID), sl.Err(err))
+	}
+}
+
+// next works out the new health of a link from the previous one and the
+// result of a check.
+func (m *Monitor) next(prev *models.LinkHealth, status int, err error) models.LinkHealth {
+	now := m.now()
+
+	health := models.LinkHealth{
+		StatusCode:  status,
+		CheckedAt:   now,
+		NextCheckAt: now.Add(m.opts.Interval),
+	}
+	if prev != nil {
+		health.Failures = prev.Failures
+		health.BrokenAt = prev.BrokenAt
+	}
+
+	switch {
+	case err == nil && status < http.StatusBadRequest:
+		health.Failures = 0
+		health.BrokenAt = nil
+	case err == nil && status == http.StatusTooManyRequests:
+		// the site asks us to slow down, that says nothing about the link
+		health.NextCheckAt = now.Add(m.backoff(health.Failures + 1))
+	default:
+		if err != nil {
+			health.Error = err.Error()
+		} else {
+			health.Error = http.StatusText(status)
+		}
+		health.Failures++
+		health.NextCheckAt = now.Add(m.backoff(health.Failures))
+		if health.Failures >= m.opts.FailureThreshold && health.BrokenAt == nil {
+			health.BrokenAt = &now
+		}
+	}
+
+	return health
+}
+
+// backoff is the wait before the next check after n consecutive failures.
+func (m *Monitor) backoff(n int) time.Duration {
+	d := m.opts.RetryInterval
+	for i := 1; i < n && d < m.opts.Interval; i++ {
+		d *= 2
+	}
+	if d <= 0 || d > m.opts.Interval {
+		return m.opts.Interval
+	}
+
+	return d
+}
+
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+
+	return u.Hostname()
+}
@@ -0,0 +1,30 @@
+// Package netguard keeps outgoing requests to user supplied urls away from
+// internal networks.
+package netguard
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+)
+
+var ErrForbiddenAddress = errors.New("address not allowed")
+
+// PublicOnly is a net.Dialer Control func refusing connections to loopback,
+// private, link-local and other non-public addresses. It runs after DNS
+// resolution so names pointing inside are caught too.
+func PublicOnly(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return fmt.Errorf("%s: %w", host, ErrForbiddenAddress)
+	}
+
+	return nil
+}
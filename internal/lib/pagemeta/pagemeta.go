@@ -0,0 +1,262 @@
+// Package pagemeta reads the title and OpenGraph tags of destination pages.
+package pagemeta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+
+	"url-shortener/internal/lib/netguard"
+	"url-shortener/internal/models"
+)
+
+var (
+	ErrNotHTML          = errors.New("not an html page")
+	ErrTooManyRedirects = errors.New("too many redirects")
+	ErrForbiddenAddress = netguard.ErrForbiddenAddress
+	ErrBadStatus        = errors.New("unexpected status")
+)
+
+const (
+	maxTitleLength       = 300
+	maxDescriptionLength = 1000
+	maxImageLength       = 2048
+)
+
+// Options limit what a fetch may cost.
+type Options struct {
+	// Timeout covers the whole fetch including redirects and reading the body.
+	Timeout time.Duration
+	// MaxBytes is how much of the page is read, metadata past it is ignored.
+	MaxBytes     int64
+	MaxRedirects int
+	UserAgent    string
+	// AllowPrivate lets the fetcher connect to loopback and private networks.
+	// Only tests should need it.
+	AllowPrivate bool
+}
+
+// HTTPFetcher fetches pages over HTTP.
+type HTTPFetcher struct {
+	client    *http.Client
+	maxBytes  int64
+	userAgent string
+}
+
+// New returns a fetcher that never follows more than opts.MaxRedirects
+// redirects and refuses to connect to internal addresses.
+func New(opts Options) *HTTPFetcher {
+	dialer := &net.Dialer{Timeout: opts.Timeout}
+	if !opts.AllowPrivate {
+		dialer.Control = netguard.PublicOnly
+	}
+
+	transport := &http.Transport{
+		// no proxy, the dialer has to see the real address
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   opts.Timeout,
+		ResponseHeaderTimeout: opts.Timeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       time.Minute,
+	}
+
+	maxRedirects := opts.MaxRedirects
+
+	return &HTTPFetcher{
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   opts.Timeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) > maxRedirects {
+					return ErrTooManyRedirects
+				}
+				if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+					return fmt.Errorf("redirect to %s: %w", req.URL.Scheme, ErrForbiddenAddress)
+				}
+				return nil
+			},
+		},
+		maxBytes:  opts.MaxBytes,
+		userAgent: opts.UserAgent,
+	}
+}
+
+// Fetch reads metadata of the page at rawURL.
+func (f *HTTPFetcher) Fetch(ctx context.Context, rawURL string) (models.PageMetadata, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return models.PageMetadata{}, err
+	}
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return models.PageMetadata{}, fmt.Errorf("scheme %s: %w", req.URL.Scheme, ErrForbiddenAddress)
+	}
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	if f.userAgent != "" {
+		req.Header.Set("User-Agent", f.userAgent)
+	}
+
+	res, err := f.client.Do(req)
+	if err != nil {
+		return models.PageMetadata{}, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return models.PageMetadata{}, fmt.Errorf("%w %d", ErrBadStatus, res.StatusCode)
+	}
+
+	contentType := res.Header.Get("Content-Type")
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return models.PageMetadata{}, fmt.Errorf("%w: %s", ErrNotHTML, contentType)
+	}
+
+	body, err := charset.NewReader(io.LimitReader(res.Body, f.maxBytes), contentType)
+	if err != nil {
+		return models.PageMetadata{}, fmt.Errorf("decode page: %w", err)
+	}
+
+	meta := Parse(body, res.Request.URL)
+	meta.FetchedAt = time.Now().UTC()
+
+	return meta, nil
+}
+
+// Parse reads metadata from the head of an html document. OpenGraph tags take
+// precedence over <title> and the description meta tag. Relative image urls
+// are resolved against base.
+func Parse(r io.Reader, base *url.URL) models.PageMetadata {
+	var (
+		meta        models.PageMetadata
+		title       string
+		description string
+		inTitle     bool
+	)
+
+	z := html.NewTokenizer(r)
+loop:
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			// io.EOF or the size cap, use what was read
+			break loop
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			switch string(name) {
+			case "title":
+				inTitle = title == ""
+			case "body":
+				break loop
+			case "meta":
+				if !hasAttr {
+					continue
+				}
+				key, content := metaAttrs(z)
+				switch key {
+				case "og:title":
+					meta.Title = content
+				case "og:description":
+					meta.Description = content
+				case "og:image", "og:image:url":
+					if meta.Image == "" {
+						meta.Image = resolve(base, content)
+					}
+				case "og:site_name":
+					meta.SiteName = content
+				case "description":
+					description = content
+				}
+			}
+		case html.TextToken:
+			if inTitle {
+				title += string(z.Text())
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch string(name) {
+			case "title":
+				inTitle = false
+			case "head":
+				break loop
+			}
+		}
+	}
+
+	if meta.Title == "" {
+		meta.Title = title
+	}
+	if meta.Description == "" {
+		meta.Description = description
+	}
+
+	meta.Title = clean(meta.Title, maxTitleLength)
+	meta.Description = clean(meta.Description, maxDescriptionLength)
+	meta.SiteName = clean(meta.SiteName, maxTitleLength)
+	if len(meta.Image) > maxImageLength {
+		meta.Image = ""
+	}
+
+	return meta
+}
+
+// metaAttrs returns the property or name of a meta tag and its content.
+func metaAttrs(z *html.Tokenizer) (string, string) {
+	var key, content string
+
+	for {
+		attr, val, more := z.TagAttr()
+		switch string(attr) {
+		case "property", "name":
+			if key == "" {
+				key = strings.ToLower(strings.TrimSpace(string(val)))
+			}
+		case "content":
+			content = string(val)
+		}
+		if !more {
+			return key, content
+		}
+	}
+}
+
+func resolve(base *url.URL, ref string) string {
+	u, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return ""
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ""
+	}
+
+	return u.String()
+}
+
+// clean collapses whitespace and cuts s to at most limit bytes without
+// splitting a character.
+func clean(s string, limit int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) <= limit {
+		return s
+	}
+
+	s = s[:limit]
+	for len(s) > 0 && !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+
+	return s
+}
@@ -0,0 +1,161 @@
+package pagemeta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testFetcher(maxBytes int64) *HTTPFetcher {
+	return New(Options{
+		Timeout:      time.Second,
+		MaxBytes:     maxBytes,
+		MaxRedirects: 2,
+		UserAgent:    "test-agent",
+		AllowPrivate: true,
+	})
+}
+
+func TestFetch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "test-agent", r.Header.Get("User-Agent"))
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, `<!doctype html><html><head>
+			<title> Plain   title </title>
+			<meta name="description" content="plain description">
+			<meta property="og:title" content="OG title">
+			<meta property="og:image" content="/img/cover.png">
+			<meta property="og:site_name" content="Example">
+			</head><body><meta property="og:description" content="ignored"></body></html>`)
+	}))
+	defer srv.Close()
+
+	meta, err := testFetcher(1<<20).Fetch(context.Background(), srv.URL+"/page")
+	require.NoError(t, err)
+
+	assert.Equal(t, "OG title", meta.Title)
+	assert.Equal(t, "plain description", meta.Description)
+	assert.Equal(t, srv.URL+"/img/cover.png", meta.Image)
+	assert.Equal(t, "Example", meta.SiteName)
+	assert.False(t, meta.FetchedAt.IsZero())
+}
+
+func TestFetchCharset(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=windows-1251")
+		// "Привет" in windows-1251
+		w.Write(append([]byte("<title>"), append([]byte{0xcf, 0xf0, 0xe8, 0xe2, 0xe5, 0xf2}, "</title>"...)...))
+	}))
+	defer srv.Close()
+
+	meta, err := testFetcher(1<<20).Fetch(context.Background(), srv.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "Привет", meta.Title)
+}
+
+func TestFetchSizeCap(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><head><title>early</title>")
+		fmt.Fprint(w, strings.Repeat("<!-- padding -->", 1000))
+		fmt.Fprint(w, `<meta property="og:title" content="late"></head></html>`)
+	}))
+	defer srv.Close()
+
+	meta, err := testFetcher(1024).Fetch(context.Background(), srv.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "early", meta.Title)
+}
+
+func TestFetchRedirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hop/", func(w http.ResponseWriter, r *http.Request) {
+		var n int
+		fmt.Sscanf(r.URL.Path, "/hop/%d", &n)
+		if n == 0 {
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, "<title>arrived</title>")
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/hop/%d", n-1), http.StatusFound)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	meta, err := testFetcher(1<<20).Fetch(context.Background(), srv.URL+"/hop/2")
+	require.NoError(t, err)
+	assert.Equal(t, "arrived", meta.Title)
+
+	_, err = testFetcher(1<<20).Fetch(context.Background(), srv.URL+"/hop/3")
+	assert.True(t, errors.Is(err, ErrTooManyRedirects), err)
+}
+
+func TestFetchTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	f := New(Options{Timeout: 100 * time.Millisecond, MaxBytes: 1024, AllowPrivate: true})
+
+	start := time.Now()
+	_, err := f.Fetch(context.Background(), srv.URL)
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestFetchErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/json":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	f := testFetcher(1 << 20)
+
+	_, err := f.Fetch(context.Background(), srv.URL+"/json")
+	assert.True(t, errors.Is(err, ErrNotHTML), err)
+
+	_, err = f.Fetch(context.Background(), srv.URL+"/missing")
+	assert.True(t, errors.Is(err, ErrBadStatus), err)
+
+	_, err = f.Fetch(context.Background(), "ftp://example.com/file")
+	assert.True(t, errors.Is(err, ErrForbiddenAddress), err)
+}
+
+func TestFetchPrivateAddress(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("private address must not be reached")
+	}))
+	defer srv.Close()
+
+	f := New(Options{Timeout: time.Second, MaxBytes: 1024})
+
+	_, err := f.Fetch(context.Background(), srv.URL)
+	assert.True(t, errors.Is(err, ErrForbiddenAddress), err)
+}
+
+func TestParseLimits(t *testing.T) {
+	long := strings.Repeat("й", 400)
+
+	meta := Parse(strings.NewReader("<title>"+long+"</title>"), nil)
+
+	assert.LessOrEqual(t, len(meta.Title), maxTitleLength)
+	assert.True(t, strings.HasPrefix(long, meta.Title))
+}
@@ -0,0 +1,64 @@
+package pagemeta
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/models"
+)
+
+// Fetcher reads metadata of a page.
+type Fetcher interface {
+	Fetch(ctx context.Context, rawURL string) (models.PageMetadata, error)
+}
+
+// Store hands out links waiting for metadata and keeps the results.
+type Store interface {
+	GetPendingMetadata(ctx context.Context, limit int) ([]models.URL, error)
+	SaveURLMetadata(ctx context.Context, urlID int64, meta models.PageMetadata) error
+}
+
+// Run fetches metadata of up to batch pending links every interval until ctx
+// is done. Failed fetches are stored with their error and not retried until
+// the link's destination changes.
+func Run(ctx context.Context, log *slog.Logger, store Store, fetcher Fetcher, interval time.Duration, batch int) {
+	log = log.With(slog.String("component", "pagemeta"))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		fetchPending(ctx, log, store, fetcher, batch)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func fetchPending(ctx context.Context, log *slog.Logger, store Store, fetcher Fetcher, batch int) {
+	urls, err := store.GetPendingMetadata(ctx, batch)
+	if err != nil {
+		log.Error("failed to get links waiting for metadata", sl.Err(err))
+		return
+	}
+
+	for _, u := range urls {
+		meta, err := fetcher.Fetch(ctx, u.URL)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Info("failed to fetch page metadata", slog.String("url", u.URL), sl.Err(err))
+			meta = models.PageMetadata{Error: err.Error(), FetchedAt: time.Now().UTC()}
+		}
+
+		if err := store.SaveURLMetadata(ctx, u.ID, meta); err != nil {
+			log.Error("failed to save page metadata", slog.Int64("url_id", u.ID), sl.Err(err))
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package pagemeta
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/models"
+)
+
+type fakeStore struct {
+	pending []models.URL
+	saved   map[int64]models.PageMetadata
+}
+
+func (s *fakeStore) GetPendingMetadata(_ context.Context, limit int) ([]models.URL, error) {
+	if len(s.pending) > limit {
+		return s.pending[:limit], nil
+	}
+	return s.pending, nil
+}
+
+func (s *fakeStore) SaveURLMetadata(_ context.Context, urlID int64, meta models.PageMetadata) error {
+	s.saved[urlID] = meta
+	return nil
+}
+
+type fakeFetcher map[string]error
+
+func (f fakeFetcher) Fetch(_ context.Context, rawURL string) (models.PageMetadata, error) {
+	if err := f[rawURL]; err != nil {
+		return models.PageMetadata{}, err
+	}
+	return models.PageMetadata{Title: rawURL, FetchedAt: time.Now()}, nil
+}
+
+func TestFetchPending(t *testing.T) {
+	store := &fakeStore{
+		pending: []models.URL{
+			{ID: 1, URL: "https://ok.example.com"},
+			{ID: 2, URL: "https://broken.example.com"},
+			{ID: 3, URL: "https://later.example.com"},
+		},
+		saved: make(map[int64]models.PageMetadata),
+	}
+	fetcher := fakeFetcher{"https://broken.example.com": errors.New("connection refused")}
+
+	fetchPending(context.Background(), slogdiscard.NewDiscardLogger(), store, fetcher, 2)
+
+	require.Len(t, store.saved, 2)
+	assert.Equal(t, "https://ok.example.com", store.saved[1].Title)
+	assert.Equal(t, "connection refused", store.saved[2].Error)
+	assert.False(t, store.saved[2].FetchedAt.IsZero(), "failed fetches must not stay pending")
+}
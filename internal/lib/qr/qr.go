@@ -0,0 +1,183 @@
+package qr
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+
+	"github.com/skip2/go-qrcode"
+)
+
+const (
+	FormatPNG = "png"
+	FormatSVG = "svg"
+
+	DefaultSize   = 256
+	DefaultMargin = 4
+	DefaultLevel  = "M"
+
+	MinSize   = 64
+	MaxSize   = 2048
+	MaxMargin = 16
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown qr format")
+	ErrUnknownLevel  = errors.New("unknown error correction level")
+	ErrInvalidSize   = errors.New("invalid qr size")
+	ErrInvalidMargin = errors.New("invalid qr margin")
+)
+
+var levels = map[string]qrcode.RecoveryLevel{
+	"L": qrcode.Low,
+	"M": qrcode.Medium,
+	"Q": qrcode.High,
+	"H": qrcode.Highest,
+}
+
+// Options controls QR code rendering.
+type Options struct {
+	// Size is the width and height of the image in pixels.
+	Size int
+	// Margin is the quiet zone around the code, in modules.
+	Margin int
+	// Level is the error correction level: L, M, Q or H.
+	Level string
+}
+
+// DefaultOptions returns options producing a 256px code with the standard quiet zone.
+func DefaultOptions() Options {
+	return Options{
+		Size:   DefaultSize,
+		Margin: DefaultMargin,
+		Level:  DefaultLevel,
+	}
+}
+
+func (o Options) Validate() error {
+	if o.Size < MinSize || o.Size > MaxSize {
+		return fmt.Errorf("%w: must be between %d and %d", ErrInvalidSize, MinSize, MaxSize)
+	}
+	if o.Margin < 0 || o.Margin > MaxMargin {
+		return fmt.Errorf("%w: must be between 0 and %d", ErrInvalidMargin, MaxMargin)
+	}
+	if _, ok := levels[strings.ToUpper(o.Level)]; !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownLevel, o.Level)
+	}
+
+	return nil
+}
+
+// ContentType returns the MIME type of the format.
+func ContentType(format string) string {
+	switch format {
+	case FormatSVG:
+		return "image/svg+xml"
+	default:
+		return "image/png"
+	}
+}
+
+// Render writes a QR code encoding content to w in the given format.
+func Render(w io.Writer, content string, format string, opts Options) error {
+	const op = "lib.qr.Render"
+
+	if err := opts.Validate(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	code, err := qrcode.New(content, levels[strings.ToUpper(opts.Level)])
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	code.DisableBorder = true
+
+	modules := code.Bitmap()
+
+	switch format {
+	case FormatPNG:
+		err = writePNG(w, modules, opts)
+	case FormatSVG:
+		err = writeSVG(w, modules, opts)
+	default:
+		err = ErrUnknownFormat
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func writePNG(w io.Writer, modules [][]bool, opts Options) error {
+	total := len(modules) + 2*opts.Margin
+
+	scale := opts.Size / total
+	if scale < 1 {
+		scale = 1
+	}
+	// center the code if size is not a multiple of the module count
+	offset := (opts.Size - scale*total) / 2
+	if offset < 0 {
+		offset = 0
+	}
+	size := max(opts.Size, scale*total)
+
+	img := image.NewPaletted(image.Rect(0, 0, size, size), color.Palette{color.White, color.Black})
+
+	for y, row := range modules {
+		for x, dark := range row {
+			if !dark {
+				continue
+			}
+
+			x0 := offset + (x+opts.Margin)*scale
+			y0 := offset + (y+opts.Margin)*scale
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					img.SetColorIndex(x0+dx, y0+dy, 1)
+				}
+			}
+		}
+	}
+
+	return png.Encode(w, img)
+}
+
+// writeSVG renders every horizontal run of dark modules as one path segment,
+// scaled to the requested size through the viewBox.
+func writeSVG(w io.Writer, modules [][]bool, opts Options) error {
+	total := len(modules) + 2*opts.Margin
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		opts.Size, opts.Size, total, total)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="#ffffff"/>`, total, total)
+	b.WriteString(`<path fill="#000000" d="`)
+
+	for y, row := range modules {
+		for x := 0; x < len(row); {
+			if !row[x] {
+				x++
+				continue
+			}
+
+			start := x
+			for x < len(row) && row[x] {
+				x++
+			}
+			fmt.Fprintf(&b, "M%d %dh%dv1h-%dz", start+opts.Margin, y+opts.Margin, x-start, x-start)
+		}
+	}
+
+	b.WriteString(`"/></svg>`)
+	b.WriteString("\n")
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
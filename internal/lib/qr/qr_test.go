@@ -0,0 +1,67 @@
+package qr
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestRender_Golden(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		opts   Options
+	}{
+		{
+			name:   "default.png",
+			format: FormatPNG,
+			opts:   DefaultOptions(),
+		},
+		{
+			name:   "no_margin_high.png",
+			format: FormatPNG,
+			opts:   Options{Size: 128, Margin: 0, Level: "H"},
+		},
+		{
+			name:   "default.svg",
+			format: FormatSVG,
+			opts:   DefaultOptions(),
+		},
+		{
+			name:   "large_margin_low.svg",
+			format: FormatSVG,
+			opts:   Options{Size: 512, Margin: 8, Level: "L"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, Render(&buf, "https://sho.rt/abc123", tt.format, tt.opts))
+
+			golden := filepath.Join("testdata", tt.name+".golden")
+			if *update {
+				require.NoError(t, os.WriteFile(golden, buf.Bytes(), 0o644))
+			}
+
+			expected, err := os.ReadFile(golden)
+			require.NoError(t, err)
+			assert.Equal(t, expected, buf.Bytes())
+		})
+	}
+}
+
+func TestRender_InvalidOptions(t *testing.T) {
+	var buf bytes.Buffer
+
+	assert.ErrorIs(t, Render(&buf, "x", FormatPNG, Options{Size: 10, Margin: 4, Level: "M"}), ErrInvalidSize)
+	assert.ErrorIs(t, Render(&buf, "x", FormatPNG, Options{Size: 256, Margin: -1, Level: "M"}), ErrInvalidMargin)
+	assert.ErrorIs(t, Render(&buf, "x", FormatPNG, Options{Size: 256, Margin: 4, Level: "X"}), ErrUnknownLevel)
+	assert.ErrorIs(t, Render(&buf, "x", "gif", DefaultOptions()), ErrUnknownFormat)
+}
@@ -0,0 +1,20 @@
+// Package referrer reduces Referer headers to the domain traffic came from.
+package referrer
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Domain returns the lower-cased host of the referrer without a leading
+// "www.", or an empty string for direct traffic and unparsable values.
+func Domain(referer string) string {
+	u, err := url.Parse(strings.TrimSpace(referer))
+	if err != nil {
+		return ""
+	}
+
+	host := strings.ToLower(u.Hostname())
+
+	return strings.TrimPrefix(host, "www.")
+}
@@ -0,0 +1,23 @@
+package referrer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDomain(t *testing.T) {
+	tests := map[string]string{
+		"https://www.Google.com/search?q=x": "google.com",
+		"https://t.co/abc":                  "t.co",
+		"http://news.ycombinator.com:8080/": "news.ycombinator.com",
+		"android-app://com.slack/":          "com.slack",
+		"":                                  "",
+		"not a url":                         "",
+		"::":                                "",
+	}
+
+	for referer, want := range tests {
+		assert.Equal(t, want, Domain(referer), referer)
+	}
+}
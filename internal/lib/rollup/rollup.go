@@ -0,0 +1,99 @@
+// Package rollup runs the job aggregating raw clicks into the summary tables
+// stats are read from, and pruning raw clicks past their retention period.
+package rollup
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"url-shortener/internal/lib/logger/sl"
+)
+
+// Store rolls up and prunes clicks in batches.
+type Store interface {
+	RollupClicks(ctx context.Context, limit int) (int64, error)
+	PruneClicks(ctx context.Context, before time.Time, limit int) (int64, error)
+	PruneHourlyClicks(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Options control the job.
+type Options struct {
+	Interval  time.Duration
+	BatchSize int
+	// Retention is how long raw clicks are kept after they were rolled up,
+	// zero keeps them forever.
+	Retention time.Duration
+	// HourlyRetention is how long hourly rollups are kept, zero keeps them
+	// forever. Daily rollups are never pruned.
+	HourlyRetention time.Duration
+}
+
+// Run rolls up pending clicks and prunes old ones every interval until ctx is
+// done. Each run works through all pending clicks in batches. The first run
+// starts right away so clicks recorded before a restart are not left waiting.
+func Run(ctx context.Context, log *slog.Logger, store Store, opts Options) {
+	log = log.With(slog.String("component", "rollup"))
+
+	ticker := time.NewTicker(opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		rollupPending(ctx, log, store, opts.BatchSize)
+		prune(ctx, log, store, opts, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func rollupPending(ctx context.Context, log *slog.Logger, store Store, batch int) {
+	var total int64
+	for ctx.Err() == nil {
+		n, err := store.RollupClicks(ctx, batch)
+		if err != nil {
+			// the batch was rolled back, retried on the next tick
+			log.Error("failed to roll up clicks", sl.Err(err))
+			break
+		}
+		total += n
+		if n < int64(batch) {
+			break
+		}
+	}
+
+	if total > 0 {
+		log.Debug("rolled up clicks", slog.Int64("count", total))
+	}
+}
+
+// prune deletes raw clicks in batches so redirects don't wait for one long
+// delete. Clicks that are not rolled up yet are never deleted.
+func prune(ctx context.Context, log *slog.Logger, store Store, opts Options, now time.Time) {
+	if opts.Retention > 0 {
+		var total int64
+		for ctx.Err() == nil {
+			n, err := store.PruneClicks(ctx, now.Add(-opts.Retention), opts.BatchSize)
+			if err != nil {
+				log.Error("failed to prune clicks", sl.Err(err))
+				break
+			}
+			total += n
+			if n < int64(opts.BatchSize) {
+				break
+			}
+		}
+		if total > 0 {
+			log.Info("pruned clicks", slog.Int64("count", total))
+		}
+	}
+
+	if opts.HourlyRetention > 0 {
+		if _, err := store.PruneHourlyClicks(ctx, now.Add(-opts.HourlyRetention)); err != nil {
+			log.Error("failed to prune hourly rollups", sl.Err(err))
+		}
+	}
+}
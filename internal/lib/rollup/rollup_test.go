@@ -0,0 +1,85 @@
+package rollup
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+type fakeStore struct {
+	pending     int64
+	old         int64
+	rollupErr   error
+	rollupCalls int
+	prunedUntil time.Time
+	hourlyUntil time.Time
+}
+
+func (s *fakeStore) RollupClicks(_ context.Context, limit int) (int64, error) {
+	s.rollupCalls++
+	if s.rollupErr != nil {
+		return 0, s.rollupErr
+	}
+	n := min(s.pending, int64(limit))
+	s.pending -= n
+	return n, nil
+}
+
+func (s *fakeStore) PruneClicks(_ context.Context, before time.Time, limit int) (int64, error) {
+	s.prunedUntil = before
+	n := min(s.old, int64(limit))
+	s.old -= n
+	return n, nil
+}
+
+func (s *fakeStore) PruneHourlyClicks(_ context.Context, before time.Time) (int64, error) {
+	s.hourlyUntil = before
+	return 0, nil
+}
+
+func TestRollupPendingDrainsBacklog(t *testing.T) {
+	store := &fakeStore{pending: 250}
+
+	rollupPending(context.Background(), slogdiscard.NewDiscardLogger(), store, 100)
+
+	assert.Equal(t, int64(0), store.pending)
+	assert.Equal(t, 3, store.rollupCalls)
+}
+
+func TestRollupPendingStopsOnError(t *testing.T) {
+	store := &fakeStore{pending: 250, rollupErr: errors.New("database is locked")}
+
+	rollupPending(context.Background(), slogdiscard.NewDiscardLogger(), store, 100)
+
+	assert.Equal(t, 1, store.rollupCalls)
+}
+
+func TestPrune(t *testing.T) {
+	now := time.Date(2024, 3, 31, 12, 0, 0, 0, time.UTC)
+	store := &fakeStore{old: 250}
+
+	prune(context.Background(), slogdiscard.NewDiscardLogger(), store, Options{
+		BatchSize:       100,
+		Retention:       30 * 24 * time.Hour,
+		HourlyRetention: 7 * 24 * time.Hour,
+	}, now)
+
+	assert.Equal(t, int64(0), store.old)
+	assert.Equal(t, now.AddDate(0, 0, -30), store.prunedUntil)
+	assert.Equal(t, now.AddDate(0, 0, -7), store.hourlyUntil)
+}
+
+func TestPruneKeepsForeverByDefault(t *testing.T) {
+	store := &fakeStore{old: 250}
+
+	prune(context.Background(), slogdiscard.NewDiscardLogger(), store, Options{BatchSize: 100}, time.Now())
+
+	assert.Equal(t, int64(250), store.old)
+	assert.True(t, store.prunedUntil.IsZero())
+	assert.True(t, store.hourlyUntil.IsZero())
+}
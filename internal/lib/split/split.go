@@ -0,0 +1,39 @@
+package split
+
+import (
+	"hash/fnv"
+
+	"url-shortener/internal/models"
+)
+
+// Pick deterministically chooses a variant for the key (visitor id + alias),
+// so the same visitor keeps landing on the same variant while weights are unchanged.
+// Variants with non-positive weight are never picked.
+func Pick(variants []models.Variant, key string) (models.Variant, bool) {
+	var total uint64
+	for _, v := range variants {
+		if v.Weight > 0 {
+			total += uint64(v.Weight)
+		}
+	}
+	if total == 0 {
+		return models.Variant{}, false
+	}
+
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	point := h.Sum64() % total
+
+	for _, v := range variants {
+		if v.Weight <= 0 {
+			continue
+		}
+		if point < uint64(v.Weight) {
+			return v, true
+		}
+		point -= uint64(v.Weight)
+	}
+
+	// unreachable: point is always less than total
+	return models.Variant{}, false
+}
@@ -0,0 +1,44 @@
+package split
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/models"
+)
+
+func TestPick(t *testing.T) {
+	variants := []models.Variant{
+		{ID: 1, URL: "https://example.com/a", Weight: 3},
+		{ID: 2, URL: "https://example.com/b", Weight: 1},
+		{ID: 3, URL: "https://example.com/c", Weight: 0},
+	}
+
+	counts := make(map[int64]int)
+	for i := 0; i < 10000; i++ {
+		key := fmt.Sprintf("visitor-%d", i)
+
+		v, ok := Pick(variants, key)
+		require.True(t, ok)
+
+		again, _ := Pick(variants, key)
+		assert.Equal(t, v.ID, again.ID, "assignment must be sticky")
+
+		counts[v.ID]++
+	}
+
+	assert.Zero(t, counts[3])
+	assert.InDelta(t, 7500, counts[1], 300)
+	assert.InDelta(t, 2500, counts[2], 300)
+}
+
+func TestPick_NoWeight(t *testing.T) {
+	_, ok := Pick(nil, "visitor")
+	assert.False(t, ok)
+
+	_, ok = Pick([]models.Variant{{ID: 1, Weight: 0}}, "visitor")
+	assert.False(t, ok)
+}
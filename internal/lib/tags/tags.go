@@ -0,0 +1,33 @@
+package tags
+
+import (
+	"sort"
+	"strings"
+)
+
+// Normalize trims and lowercases tag names, dropping empty and duplicate ones.
+// The result is sorted so equal sets of tags compare equal.
+func Normalize(names []string) []string {
+	seen := make(map[string]struct{}, len(names))
+	res := make([]string, 0, len(names))
+
+	for _, name := range names {
+		name = Name(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+		res = append(res, name)
+	}
+	sort.Strings(res)
+
+	return res
+}
+
+// Name normalizes a single tag name.
+func Name(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
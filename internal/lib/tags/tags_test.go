@@ -0,0 +1,26 @@
+package tags
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	cases := []struct {
+		name  string
+		input []string
+		want  []string
+	}{
+		{name: "nil", input: nil, want: []string{}},
+		{name: "trim and lowercase", input: []string{"  Work ", "GO"}, want: []string{"go", "work"}},
+		{name: "duplicates", input: []string{"go", "Go", " go"}, want: []string{"go"}},
+		{name: "empty names", input: []string{"", "  ", "news"}, want: []string{"news"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, Normalize(tc.input))
+		})
+	}
+}
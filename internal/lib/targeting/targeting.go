@@ -0,0 +1,204 @@
+package targeting
+
+import (
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"url-shortener/internal/models"
+)
+
+const (
+	PlatformIOS     = "ios"
+	PlatformAndroid = "android"
+	PlatformWindows = "windows"
+	PlatformMacOS   = "macos"
+	PlatformLinux   = "linux"
+	PlatformOther   = "other"
+)
+
+// CountryResolver resolves an IP address into an ISO 3166-1 alpha-2 country code.
+type CountryResolver interface {
+	Country(ip net.IP) (string, error)
+}
+
+// Visitor holds the request attributes rules are matched against.
+type Visitor struct {
+	Platform  string
+	Languages []string
+	Country   string
+	Time      time.Time
+}
+
+// NewVisitor extracts visitor attributes from the request. Country is left
+// empty when resolver is nil or fails to resolve the address.
+func NewVisitor(r *http.Request, resolver CountryResolver) Visitor {
+	v := Visitor{
+		Platform:  ParsePlatform(r.UserAgent()),
+		Languages: ParseAcceptLanguage(r.Header.Get("Accept-Language")),
+		Time:      time.Now().UTC(),
+	}
+
+	if resolver != nil {
+		if ip := ClientIP(r); ip != nil {
+			if country, err := resolver.Country(ip); err == nil {
+				v.Country = strings.ToUpper(country)
+			}
+		}
+	}
+
+	return v
+}
+
+// Match returns the target URL of the first rule matching the visitor.
+// Rules must be sorted by position.
+func Match(rules []models.Rule, v Visitor) (string, bool) {
+	for _, rule := range rules {
+		if matches(rule, v) {
+			return rule.TargetURL, true
+		}
+	}
+
+	return "", false
+}
+
+func matches(rule models.Rule, v Visitor) bool {
+	if rule.Platform != "" && !strings.EqualFold(rule.Platform, v.Platform) {
+		return false
+	}
+	if rule.Country != "" && !strings.EqualFold(rule.Country, v.Country) {
+		return false
+	}
+	if rule.Language != "" && !matchLanguage(rule.Language, v.Languages) {
+		return false
+	}
+	if rule.TimeFrom != "" || rule.TimeTo != "" {
+		if !matchTime(rule.TimeFrom, rule.TimeTo, v.Time) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchLanguage reports whether any of the accepted languages satisfies the
+// rule. A rule without a region ("de") matches every regional variant.
+func matchLanguage(ruleLang string, accepted []string) bool {
+	ruleLang = strings.ToLower(ruleLang)
+	for _, lang := range accepted {
+		if lang == ruleLang {
+			return true
+		}
+		if !strings.Contains(ruleLang, "-") && strings.HasPrefix(lang, ruleLang+"-") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchTime reports whether t falls into [from, to). Ranges where from is
+// later than to wrap around midnight. An empty bound is treated as open.
+func matchTime(from, to string, t time.Time) bool {
+	now := t.Hour()*60 + t.Minute()
+
+	start, okStart := parseClock(from)
+	end, okEnd := parseClock(to)
+	if !okStart {
+		start = 0
+	}
+	if !okEnd {
+		end = 24 * 60
+	}
+
+	if start <= end {
+		return now >= start && now < end
+	}
+
+	return now >= start || now < end
+}
+
+func parseClock(s string) (int, bool) {
+	if s == "" {
+		return 0, false
+	}
+
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, false
+	}
+
+	return t.Hour()*60 + t.Minute(), true
+}
+
+// ParsePlatform detects the visitor operating system from the User-Agent header.
+func ParsePlatform(ua string) string {
+	ua = strings.ToLower(ua)
+
+	switch {
+	case strings.Contains(ua, "iphone"), strings.Contains(ua, "ipad"), strings.Contains(ua, "ipod"):
+		return PlatformIOS
+	case strings.Contains(ua, "android"):
+		return PlatformAndroid
+	case strings.Contains(ua, "windows"):
+		return PlatformWindows
+	case strings.Contains(ua, "mac os x"), strings.Contains(ua, "macintosh"):
+		return PlatformMacOS
+	case strings.Contains(ua, "linux"):
+		return PlatformLinux
+	default:
+		return PlatformOther
+	}
+}
+
+// ParseAcceptLanguage returns lower-cased language tags ordered by quality.
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+
+	var langs []weighted
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		tag, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || tag == "*" || q <= 0 {
+			continue
+		}
+		langs = append(langs, weighted{tag: tag, q: q})
+	}
+
+	sort.SliceStable(langs, func(i, j int) bool { return langs[i].q > langs[j].q })
+
+	res := make([]string, 0, len(langs))
+	for _, l := range langs {
+		res = append(res, l.tag)
+	}
+
+	return res
+}
+
+// ClientIP returns the address of the client that sent the request.
+func ClientIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	return net.ParseIP(host)
+}
@@ -0,0 +1,74 @@
+package targeting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"url-shortener/internal/models"
+)
+
+func TestMatch(t *testing.T) {
+	rules := []models.Rule{
+		{Platform: PlatformIOS, TargetURL: "https://apps.apple.com/app"},
+		{Platform: PlatformAndroid, TargetURL: "https://play.google.com/app"},
+		{Country: "DE", Language: "de", TargetURL: "https://example.de/"},
+		{TimeFrom: "22:00", TimeTo: "06:00", TargetURL: "https://example.com/night"},
+	}
+
+	noon := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	night := time.Date(2024, 1, 1, 23, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		visitor  Visitor
+		expected string
+	}{
+		{
+			name:     "ios",
+			visitor:  Visitor{Platform: PlatformIOS, Time: night},
+			expected: "https://apps.apple.com/app",
+		},
+		{
+			name:     "android",
+			visitor:  Visitor{Platform: PlatformAndroid, Time: noon},
+			expected: "https://play.google.com/app",
+		},
+		{
+			name:     "country and regional language",
+			visitor:  Visitor{Platform: PlatformWindows, Country: "DE", Languages: []string{"de-at", "en"}, Time: noon},
+			expected: "https://example.de/",
+		},
+		{
+			name:     "country without language",
+			visitor:  Visitor{Platform: PlatformWindows, Country: "DE", Languages: []string{"en"}, Time: noon},
+			expected: "",
+		},
+		{
+			name:     "time range across midnight",
+			visitor:  Visitor{Platform: PlatformLinux, Time: night},
+			expected: "https://example.com/night",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, ok := Match(rules, tt.visitor)
+
+			assert.Equal(t, tt.expected != "", ok)
+			assert.Equal(t, tt.expected, target)
+		})
+	}
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	assert.Equal(t, []string{"fr-ch", "fr", "en", "de"}, ParseAcceptLanguage("fr-CH, fr;q=0.9, en;q=0.8, de;q=0.7, *;q=0.5"))
+	assert.Empty(t, ParseAcceptLanguage(""))
+}
+
+func TestParsePlatform(t *testing.T) {
+	assert.Equal(t, PlatformIOS, ParsePlatform("Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15"))
+	assert.Equal(t, PlatformAndroid, ParsePlatform("Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36"))
+	assert.Equal(t, PlatformMacOS, ParsePlatform("Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15"))
+	assert.Equal(t, PlatformOther, ParsePlatform("curl/8.0"))
+}
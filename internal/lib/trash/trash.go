@@ -0,0 +1,39 @@
+package trash
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"url-shortener/internal/lib/logger/sl"
+)
+
+// Purger removes links that have been in the trash since before deletedBefore.
+type Purger interface {
+	PurgeDeletedURLs(ctx context.Context, deletedBefore time.Time) (int64, error)
+}
+
+// Run purges links older than grace every interval until ctx is done. The
+// first purge runs right away so a long interval doesn't delay it after restarts.
+func Run(ctx context.Context, log *slog.Logger, purger Purger, grace time.Duration, interval time.Duration) {
+	log = log.With(slog.String("component", "trash"))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := purger.PurgeDeletedURLs(ctx, time.Now().Add(-grace))
+		if err != nil {
+			// retried on the next tick
+			log.Error("failed to purge deleted urls", sl.Err(err))
+		} else if n > 0 {
+			log.Info("purged deleted urls", slog.Int64("count", n))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
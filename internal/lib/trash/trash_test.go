@@ -0,0 +1,49 @@
+package trash_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/lib/trash"
+)
+
+type purgerFunc func(ctx context.Context, deletedBefore time.Time) (int64, error)
+
+func (f purgerFunc) PurgeDeletedURLs(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	return f(ctx, deletedBefore)
+}
+
+func TestRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := make(chan time.Time, 1)
+
+	purger := purgerFunc(func(_ context.Context, deletedBefore time.Time) (int64, error) {
+		calls <- deletedBefore
+		cancel()
+		return 1, nil
+	})
+
+	done := make(chan struct{})
+	go func() {
+		trash.Run(ctx, slogdiscard.NewDiscardLogger(), purger, 24*time.Hour, time.Hour)
+		close(done)
+	}()
+
+	select {
+	case deletedBefore := <-calls:
+		assert.WithinDuration(t, time.Now().Add(-24*time.Hour), deletedBefore, time.Minute)
+	case <-time.After(time.Second):
+		require.Fail(t, "purge didn't run on start")
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		require.Fail(t, "Run didn't stop after cancel")
+	}
+}
@@ -0,0 +1,58 @@
+// Package urlnorm normalizes destination urls so equivalent spellings of a
+// url can be found by one hash.
+package urlnorm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/url"
+	"strings"
+)
+
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// Normalize lowercases the scheme and host, drops the default port of the
+// scheme and trailing slashes of the path, and sorts the query parameters by
+// name. The fragment and the order of repeated parameters are kept. A url
+// that doesn't parse is only trimmed.
+func Normalize(rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+
+	host := strings.ToLower(u.Hostname())
+	if port := u.Port(); port != "" && port != defaultPorts[u.Scheme] {
+		u.Host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		u.Host = "[" + host + "]"
+	} else {
+		u.Host = host
+	}
+
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = strings.TrimRight(u.RawPath, "/")
+
+	if u.RawQuery != "" {
+		// Encode sorts by name
+		u.RawQuery = u.Query().Encode()
+	}
+	u.ForceQuery = false
+
+	return u.String()
+}
+
+// Hash returns the hex SHA-256 of the normalized url.
+func Hash(rawURL string) string {
+	sum := sha256.Sum256([]byte(Normalize(rawURL)))
+
+	return hex.EncodeToString(sum[:])
+}
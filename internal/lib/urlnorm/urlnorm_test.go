@@ -0,0 +1,43 @@
+package urlnorm_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"url-shortener/internal/lib/urlnorm"
+)
+
+func TestNormalize(t *testing.T) {
+	cases := []struct {
+		name string
+		url  string
+		want string
+	}{
+		{name: "Scheme and host case", url: "HTTPS://Example.COM/Path", want: "https://example.com/Path"},
+		{name: "Default http port", url: "http://example.com:80/a", want: "http://example.com/a"},
+		{name: "Default https port", url: "https://example.com:443/a", want: "https://example.com/a"},
+		{name: "Other port", url: "https://example.com:8443/a", want: "https://example.com:8443/a"},
+		{name: "Port of other scheme", url: "http://example.com:443/a", want: "http://example.com:443/a"},
+		{name: "IPv6", url: "http://[::1]:80/", want: "http://[::1]"},
+		{name: "Trailing slash", url: "https://example.com/a/", want: "https://example.com/a"},
+		{name: "Root", url: "https://example.com/", want: "https://example.com"},
+		{name: "Sorted query", url: "https://example.com/?b=2&a=1&a=0", want: "https://example.com?a=1&a=0&b=2"},
+		{name: "Empty query", url: "https://example.com/a?", want: "https://example.com/a"},
+		{name: "Fragment", url: "https://example.com/a/#Top", want: "https://example.com/a#Top"},
+		{name: "Unparsable", url: " http://[::1 ", want: "http://[::1"},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, urlnorm.Normalize(tc.url))
+		})
+	}
+}
+
+func TestHash(t *testing.T) {
+	assert.Equal(t, urlnorm.Hash("https://Example.com:443/a/?y=2&x=1"), urlnorm.Hash("https://example.com/a?x=1&y=2"))
+	assert.NotEqual(t, urlnorm.Hash("https://example.com/a"), urlnorm.Hash("https://example.com/b"))
+	assert.Len(t, urlnorm.Hash("https://example.com"), 64)
+}
@@ -0,0 +1,296 @@
+package urlpolicy
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+)
+
+const (
+	CodeInvalidURL       = "invalid_url"
+	CodeSchemeNotAllowed = "scheme_not_allowed"
+	CodeDomainDenied     = "domain_denied"
+	CodeRedirectLoop     = "redirect_loop"
+	CodePhishing         = "phishing"
+)
+
+// Violation describes why a destination url was rejected.
+type Violation struct {
+	Code    string
+	Message string
+}
+
+type Options struct {
+	// AllowedSchemes lists accepted url schemes, http and https when empty.
+	AllowedSchemes []string
+	// ShortDomains are hosts short links are served from. Destinations on them
+	// would redirect back to the shortener.
+	ShortDomains []string
+	// DenyListPath is a file with one denied domain per line. Subdomains of a
+	// denied domain are denied too. Lines starting with # are ignored.
+	DenyListPath string
+	// PhishingListPath is a file with one hex encoded SHA-256 per line, of
+	// either a host or a "host/path" pair of a known phishing page.
+	PhishingListPath string
+}
+
+type fileList struct {
+	path    string
+	modTime time.Time
+	entries map[string]struct{}
+}
+
+// Policy checks destination urls before they are shortened. Lists loaded from
+// files are reloaded by Watch when the files change.
+type Policy struct {
+	schemes      map[string]struct{}
+	shortDomains map[string]struct{}
+
+	mu       sync.RWMutex
+	deny     fileList
+	phishing fileList
+}
+
+func New(opts Options) (*Policy, error) {
+	const op = "lib.urlpolicy.New"
+
+	schemes := opts.AllowedSchemes
+	if len(schemes) == 0 {
+		schemes = []string{"http", "https"}
+	}
+
+	p := &Policy{
+		schemes:      toSet(schemes),
+		shortDomains: toSet(opts.ShortDomains),
+		deny:         fileList{path: opts.DenyListPath},
+		phishing:     fileList{path: opts.PhishingListPath},
+	}
+
+	if _, err := p.Reload(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return p, nil
+}
+
+// Check returns every rule the url violates, nil if it is allowed.
+func (p *Policy) Check(rawURL string) []Violation {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Scheme == "" {
+		return []Violation{{Code: CodeInvalidURL, Message: "url can't be parsed"}}
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	if _, ok := p.schemes[scheme]; !ok {
+		return []Violation{{Code: CodeSchemeNotAllowed, Message: fmt.Sprintf("scheme %q is not allowed", scheme)}}
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "" {
+		return []Violation{{Code: CodeInvalidURL, Message: "url has no host"}}
+	}
+
+	var violations []Violation
+
+	if p.isShortDomain(u) {
+		violations = append(violations, Violation{
+			Code:    CodeRedirectLoop,
+			Message: "url points to the shortener itself",
+		})
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if matchDomain(p.deny.entries, host) {
+		violations = append(violations, Violation{
+			Code:    CodeDomainDenied,
+			Message: fmt.Sprintf("domain %s is not allowed", host),
+		})
+	}
+
+	if len(p.phishing.entries) > 0 {
+		if _, ok := p.phishing.entries[hash(host)]; ok {
+			violations = append(violations, Violation{Code: CodePhishing, Message: "url is a known phishing page"})
+		} else if _, ok := p.phishing.entries[hash(host+u.EscapedPath())]; ok {
+			violations = append(violations, Violation{Code: CodePhishing, Message: "url is a known phishing page"})
+		}
+	}
+
+	return violations
+}
+
+func (p *Policy) isShortDomain(u *url.URL) bool {
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if _, ok := p.shortDomains[host]; ok {
+		return true
+	}
+
+	// short domains may be configured with a port
+	_, ok := p.shortDomains[strings.ToLower(u.Host)]
+
+	return ok
+}
+
+// Reload rereads list files that changed since the last load and reports
+// whether anything was reloaded.
+func (p *Policy) Reload() (bool, error) {
+	const op = "lib.urlpolicy.Reload"
+
+	p.mu.RLock()
+	deny, phishing := p.deny, p.phishing
+	p.mu.RUnlock()
+
+	denyChanged, err := reloadList(&deny, normalizeDomain)
+	if err != nil {
+		return false, fmt.Errorf("%s: deny list: %w", op, err)
+	}
+	phishingChanged, err := reloadList(&phishing, strings.ToLower)
+	if err != nil {
+		return false, fmt.Errorf("%s: phishing list: %w", op, err)
+	}
+
+	if !denyChanged && !phishingChanged {
+		return false, nil
+	}
+
+	p.mu.Lock()
+	p.deny, p.phishing = deny, phishing
+	p.mu.Unlock()
+
+	return true, nil
+}
+
+// Watch polls list files every interval and reloads them on change until ctx is done.
+func (p *Policy) Watch(ctx context.Context, log *slog.Logger, interval time.Duration) {
+	log = log.With(slog.String("component", "urlpolicy"))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := p.Reload()
+			if err != nil {
+				// keep serving the previously loaded lists
+				log.Error("failed to reload url policy lists", sl.Err(err))
+				continue
+			}
+			if reloaded {
+				log.Info("url policy lists reloaded")
+			}
+		}
+	}
+}
+
+func reloadList(l *fileList, normalize func(string) string) (bool, error) {
+	if l.path == "" {
+		return false, nil
+	}
+
+	info, err := os.Stat(l.path)
+	if err != nil {
+		return false, err
+	}
+	if l.entries != nil && info.ModTime().Equal(l.modTime) {
+		return false, nil
+	}
+
+	f, err := os.Open(l.path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	entries := make(map[string]struct{})
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries[normalize(line)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return false, err
+	}
+
+	l.entries = entries
+	l.modTime = info.ModTime()
+
+	return true, nil
+}
+
+// matchDomain reports whether host or any of its parent domains is in the set.
+func matchDomain(set map[string]struct{}, host string) bool {
+	if len(set) == 0 {
+		return false
+	}
+	if net.ParseIP(host) != nil {
+		_, ok := set[host]
+		return ok
+	}
+
+	for {
+		if _, ok := set[host]; ok {
+			return true
+		}
+
+		_, parent, found := strings.Cut(host, ".")
+		if !found {
+			return false
+		}
+		host = parent
+	}
+}
+
+func normalizeDomain(s string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(strings.ToLower(s), "*."), ".")
+}
+
+func hash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+
+	return hex.EncodeToString(sum[:])
+}
+
+func toSet(values []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		if v = strings.ToLower(strings.TrimSpace(v)); v != "" {
+			set[v] = struct{}{}
+		}
+	}
+
+	return set
+}
+
+// FieldErrors converts violations of the url in field into response errors.
+func FieldErrors(field string, violations []Violation) []resp.FieldError {
+	errs := make([]resp.FieldError, 0, len(violations))
+	for _, v := range violations {
+		errs = append(errs, resp.FieldError{
+			Field:   field,
+			Code:    v.Code,
+			Message: fmt.Sprintf("field %s: %s", field, v.Message),
+		})
+	}
+
+	return errs
+}
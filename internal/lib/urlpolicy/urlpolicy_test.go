@@ -0,0 +1,85 @@
+package urlpolicy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func codes(violations []Violation) []string {
+	res := make([]string, 0, len(violations))
+	for _, v := range violations {
+		res = append(res, v.Code)
+	}
+
+	return res
+}
+
+func TestPolicy_Check(t *testing.T) {
+	dir := t.TempDir()
+
+	denyList := filepath.Join(dir, "deny.txt")
+	require.NoError(t, os.WriteFile(denyList, []byte("# known bad\nevil.com\n*.malware.net\n"), 0o644))
+
+	sum := sha256.Sum256([]byte("phish.example.org/login"))
+	phishingList := filepath.Join(dir, "phishing.txt")
+	require.NoError(t, os.WriteFile(phishingList, []byte(hex.EncodeToString(sum[:])+"\n"), 0o644))
+
+	p, err := New(Options{
+		ShortDomains:     []string{"sho.rt", "localhost:8082"},
+		DenyListPath:     denyList,
+		PhishingListPath: phishingList,
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		url      string
+		expected []string
+	}{
+		{url: "https://google.com/search?q=1", expected: []string{}},
+		{url: "javascript:alert(1)", expected: []string{CodeSchemeNotAllowed}},
+		{url: "file:///etc/passwd", expected: []string{CodeSchemeNotAllowed}},
+		{url: "data:text/html;base64,PHNjcmlwdD4=", expected: []string{CodeSchemeNotAllowed}},
+		{url: "https://sho.rt/abc", expected: []string{CodeRedirectLoop}},
+		{url: "http://localhost:8082/abc", expected: []string{CodeRedirectLoop}},
+		{url: "https://EVIL.com/path", expected: []string{CodeDomainDenied}},
+		{url: "https://cdn.malware.net/x.exe", expected: []string{CodeDomainDenied}},
+		{url: "https://notevil.com/", expected: []string{}},
+		{url: "https://phish.example.org/login", expected: []string{CodePhishing}},
+		{url: "https://phish.example.org/about", expected: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			assert.Equal(t, tt.expected, codes(p.Check(tt.url)))
+		})
+	}
+}
+
+func TestPolicy_Reload(t *testing.T) {
+	denyList := filepath.Join(t.TempDir(), "deny.txt")
+	require.NoError(t, os.WriteFile(denyList, []byte("evil.com\n"), 0o644))
+
+	p, err := New(Options{DenyListPath: denyList})
+	require.NoError(t, err)
+
+	assert.Empty(t, p.Check("https://bad.org"))
+
+	require.NoError(t, os.WriteFile(denyList, []byte("evil.com\nbad.org\n"), 0o644))
+	// make sure the change is visible even on filesystems with coarse mtime
+	require.NoError(t, os.Chtimes(denyList, time.Now().Add(time.Minute), time.Now().Add(time.Minute)))
+
+	reloaded, err := p.Reload()
+	require.NoError(t, err)
+	assert.True(t, reloaded)
+	assert.Equal(t, []string{CodeDomainDenied}, codes(p.Check("https://bad.org")))
+
+	reloaded, err = p.Reload()
+	require.NoError(t, err)
+	assert.False(t, reloaded)
+}
@@ -0,0 +1,121 @@
+// Package useragent parses User-Agent headers into browser, OS and device
+// class. It knows the common browsers only and never fails, unknown agents
+// are reported as Other.
+package useragent
+
+import "strings"
+
+const Other = "Other"
+
+// Device classes.
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceOther   = "other"
+)
+
+// Agent is what the user agent tells about the visitor's software.
+type Agent struct {
+	Browser string
+	// BrowserVersion is the major version, empty if unknown.
+	BrowserVersion string
+	OS             string
+	Device         string
+}
+
+// browsers are checked in order, most browsers also claim to be Chrome or
+// Safari so the specific ones come first.
+var browsers = []struct {
+	name   string
+	tokens []string
+}{
+	{"Edge", []string{"edg/", "edga/", "edgios/", "edge/"}},
+	{"Opera", []string{"opr/", "opera/", "opios/"}},
+	{"Samsung Internet", []string{"samsungbrowser/"}},
+	{"Yandex", []string{"yabrowser/"}},
+	{"Vivaldi", []string{"vivaldi/"}},
+	{"Firefox", []string{"firefox/", "fxios/"}},
+	{"Chrome", []string{"chrome/", "crios/"}},
+	{"Safari", []string{"version/"}},
+	{"Internet Explorer", []string{"msie ", "trident/"}},
+}
+
+// Parse parses the user agent.
+func Parse(ua string) Agent {
+	lower := strings.ToLower(ua)
+
+	agent := Agent{
+		Browser: Other,
+		OS:      parseOS(lower),
+	}
+
+browsers:
+	for _, b := range browsers {
+		for _, token := range b.tokens {
+			i := strings.Index(lower, token)
+			if i < 0 {
+				continue
+			}
+			// "Version/" is only Safari when it says so
+			if b.name == "Safari" && !strings.Contains(lower, "safari/") {
+				continue
+			}
+
+			agent.Browser = b.name
+			agent.BrowserVersion = majorVersion(lower[i+len(token):])
+			if b.name == "Internet Explorer" && token == "trident/" {
+				// Trident 7 is IE 11
+				agent.BrowserVersion = "11"
+			}
+			break browsers
+		}
+	}
+
+	agent.Device = parseDevice(lower, agent.OS)
+
+	return agent
+}
+
+func parseOS(ua string) string {
+	switch {
+	case strings.Contains(ua, "iphone"), strings.Contains(ua, "ipad"), strings.Contains(ua, "ipod"):
+		return "iOS"
+	case strings.Contains(ua, "android"):
+		return "Android"
+	case strings.Contains(ua, "windows"):
+		return "Windows"
+	case strings.Contains(ua, "cros"):
+		return "ChromeOS"
+	case strings.Contains(ua, "mac os x"), strings.Contains(ua, "macintosh"):
+		return "macOS"
+	case strings.Contains(ua, "linux"):
+		return "Linux"
+	default:
+		return Other
+	}
+}
+
+func parseDevice(ua string, os string) string {
+	switch {
+	case strings.Contains(ua, "ipad"), strings.Contains(ua, "tablet"),
+		os == "Android" && !strings.Contains(ua, "mobile"):
+		return DeviceTablet
+	case strings.Contains(ua, "mobi"), strings.Contains(ua, "iphone"), strings.Contains(ua, "ipod"):
+		return DeviceMobile
+	case os == "Windows", os == "macOS", os == "Linux", os == "ChromeOS":
+		return DeviceDesktop
+	default:
+		return DeviceOther
+	}
+}
+
+// majorVersion returns the leading digits of s.
+func majorVersion(s string) string {
+	end := 0
+	for end < len(s) && s[end] >= '0' && s[end] <= '9' {
+		end++
+	}
+
+	return s[:end]
+}
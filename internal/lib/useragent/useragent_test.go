@@ -0,0 +1,67 @@
+package useragent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		ua   string
+		want Agent
+	}{
+		{
+			ua:   "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.6099.130 Safari/537.36",
+			want: Agent{Browser: "Chrome", BrowserVersion: "120", OS: "Windows", Device: DeviceDesktop},
+		},
+		{
+			ua:   "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.2210.91",
+			want: Agent{Browser: "Edge", BrowserVersion: "120", OS: "Windows", Device: DeviceDesktop},
+		},
+		{
+			ua:   "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Safari/605.1.15",
+			want: Agent{Browser: "Safari", BrowserVersion: "17", OS: "macOS", Device: DeviceDesktop},
+		},
+		{
+			ua:   "Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0",
+			want: Agent{Browser: "Firefox", BrowserVersion: "121", OS: "Linux", Device: DeviceDesktop},
+		},
+		{
+			ua:   "Mozilla/5.0 (iPhone; CPU iPhone OS 17_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Mobile/15E148 Safari/604.1",
+			want: Agent{Browser: "Safari", BrowserVersion: "17", OS: "iOS", Device: DeviceMobile},
+		},
+		{
+			ua:   "Mozilla/5.0 (iPhone; CPU iPhone OS 17_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/120.0.6099.119 Mobile/15E148 Safari/604.1",
+			want: Agent{Browser: "Chrome", BrowserVersion: "120", OS: "iOS", Device: DeviceMobile},
+		},
+		{
+			ua:   "Mozilla/5.0 (iPad; CPU OS 17_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Mobile/15E148 Safari/604.1",
+			want: Agent{Browser: "Safari", BrowserVersion: "17", OS: "iOS", Device: DeviceTablet},
+		},
+		{
+			ua:   "Mozilla/5.0 (Linux; Android 14; SM-S918B) AppleWebKit/537.36 (KHTML, like Gecko) SamsungBrowser/23.0 Chrome/115.0.0.0 Mobile Safari/537.36",
+			want: Agent{Browser: "Samsung Internet", BrowserVersion: "23", OS: "Android", Device: DeviceMobile},
+		},
+		{
+			ua:   "Mozilla/5.0 (Linux; Android 13; SM-X700) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			want: Agent{Browser: "Chrome", BrowserVersion: "120", OS: "Android", Device: DeviceTablet},
+		},
+		{
+			ua:   "Mozilla/5.0 (Windows NT 10.0; WOW64; Trident/7.0; rv:11.0) like Gecko",
+			want: Agent{Browser: "Internet Explorer", BrowserVersion: "11", OS: "Windows", Device: DeviceDesktop},
+		},
+		{
+			ua:   "curl/8.4.0",
+			want: Agent{Browser: Other, OS: Other, Device: DeviceOther},
+		},
+		{
+			ua:   "",
+			want: Agent{Browser: Other, OS: Other, Device: DeviceOther},
+		},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Parse(tt.ua), tt.ua)
+	}
+}
@@ -0,0 +1,168 @@
+// Package visitors counts unique visitors of links with per-link, per-day
+// HyperLogLog sketches.
+//
+// Visitors are told apart by their visitor cookie. A visitor that sent none
+// is counted by a fingerprint of client IP and User-Agent instead, as the
+// cookie issued to clients that don't keep cookies never comes back. On top
+// of the error of the sketches (see package hll), people sharing an address
+// and browser build then count once, and a visitor switching networks counts
+// again.
+package visitors
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"sync"
+	"time"
+
+	"url-shortener/internal/lib/events"
+	"url-shortener/internal/lib/hll"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/models"
+)
+
+// Store persists sketches by merging them into the stored ones.
+type Store interface {
+	MergeVisitorSketches(ctx context.Context, sketches []models.VisitorSketch) error
+}
+
+type key struct {
+	domain string
+	alias  string
+	day    time.Time
+	class  string
+}
+
+// Counter is an events.Publisher adding the visitor of every click to the
+// sketch of the link, day and class of traffic in memory. Sketches are written to the store on
+// Flush, so stats lag behind by up to the flush interval.
+type Counter struct {
+	log   *slog.Logger
+	store Store
+
+	mu      sync.Mutex
+	pending map[key]*hll.Sketch
+}
+
+func New(log *slog.Logger, store Store) *Counter {
+	return &Counter{
+		log:     log.With(slog.String("component", "visitors")),
+		store:   store,
+		pending: make(map[key]*hll.Sketch),
+	}
+}
+
+// Publish counts the visitor of link.clicked events, other events are ignored.
+func (c *Counter) Publish(_ context.Context, event events.Event) error {
+	if event.Type != events.LinkClicked {
+		return nil
+	}
+	data, ok := event.Data.(events.ClickData)
+	if !ok {
+		return nil
+	}
+	visitor := data.VisitorKey
+	if visitor == "" {
+		visitor = data.VisitorID
+	}
+	if visitor == "" {
+		return nil
+	}
+
+	k := key{
+		domain: event.Domain,
+		alias:  event.Alias,
+		day:    event.OccurredAt.UTC().Truncate(24 * time.Hour),
+		class:  data.Class,
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	sketch, ok := c.pending[k]
+	if !ok {
+		sketch = hll.New()
+		c.pending[k] = sketch
+	}
+	sketch.Add(visitor)
+
+	return nil
+}
+
+// Fingerprint identifies a visitor without cookie by a hash of the client IP
+// and User-Agent keyed with key, so the addresses can't be recovered from
+// stored sketches or by trying them.
+func Fingerprint(key []byte, ip string, userAgent string) string {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(ip))
+	h.Write([]byte{0})
+	h.Write([]byte(userAgent))
+
+	return "fp_" + hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// Flush writes the sketches collected since the last flush. They are kept for
+// the next flush if the store fails.
+func (c *Counter) Flush(ctx context.Context) error {
+	c.mu.Lock()
+	pending := c.pending
+	c.pending = make(map[key]*hll.Sketch)
+	c.mu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	sketches := make([]models.VisitorSketch, 0, len(pending))
+	for k, sketch := range pending {
+		b, err := sketch.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		sketches = append(sketches, models.VisitorSketch{
+			Domain: k.domain,
+			Alias:  k.alias,
+			Day:    k.day,
+			Class:  k.class,
+			Sketch: b,
+		})
+	}
+
+	if err := c.store.MergeVisitorSketches(ctx, sketches); err != nil {
+		c.mu.Lock()
+		for k, sketch := range pending {
+			if newer, ok := c.pending[k]; ok {
+				sketch.Merge(newer)
+			}
+			c.pending[k] = sketch
+		}
+		c.mu.Unlock()
+
+		return err
+	}
+
+	return nil
+}
+
+// Run flushes every interval until ctx is done. Call Flush once more after
+// the server stopped serving redirects so no clicks are lost.
+func (c *Counter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := c.Flush(ctx); err != nil {
+			// retried on the next tick
+			c.log.Error("failed to flush visitor sketches", sl.Err(err))
+		}
+	}
+}
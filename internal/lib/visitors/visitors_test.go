@@ -0,0 +1,124 @@
+package visitors
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/events"
+	"url-shortener/internal/lib/hll"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/models"
+)
+
+type storeFunc func(sketches []models.VisitorSketch) error
+
+func (f storeFunc) MergeVisitorSketches(_ context.Context, sketches []models.VisitorSketch) error {
+	return f(sketches)
+}
+
+func click(alias string, visitorID string, at time.Time) events.Event {
+	event := events.New(events.LinkClicked, "", alias, events.ClickData{VisitorID: visitorID})
+	event.OccurredAt = at
+	return event
+}
+
+func estimates(t *testing.T, sketches []models.VisitorSketch) map[string]uint64 {
+	t.Helper()
+
+	got := make(map[string]uint64)
+	for _, vs := range sketches {
+		s, err := hll.Parse(vs.Sketch)
+		require.NoError(t, err)
+		got[vs.Alias+" "+vs.Day.Format("2006-01-02")] = s.Estimate()
+	}
+
+	return got
+}
+
+func TestFlushPerLinkAndDay(t *testing.T) {
+	var flushed []models.VisitorSketch
+	c := New(slogdiscard.NewDiscardLogger(), storeFunc(func(sketches []models.VisitorSketch) error {
+		flushed = append(flushed, sketches...)
+		return nil
+	}))
+
+	ctx := context.Background()
+	day := time.Date(2024, 3, 1, 23, 0, 0, 0, time.UTC)
+	for i := range 10 {
+		// every visitor clicks twice
+		require.NoError(t, c.Publish(ctx, click("abc", strconv.Itoa(i), day)))
+		require.NoError(t, c.Publish(ctx, click("abc", strconv.Itoa(i), day.Add(90*time.Minute))))
+	}
+	require.NoError(t, c.Publish(ctx, click("xyz", "1", day)))
+	require.NoError(t, c.Publish(ctx, events.New(events.LinkUpdated, "", "abc", events.LinkData{})))
+
+	require.NoError(t, c.Flush(ctx))
+	assert.Equal(t, map[string]uint64{
+		"abc 2024-03-01": 10,
+		"abc 2024-03-02": 10,
+		"xyz 2024-03-01": 1,
+	}, estimates(t, flushed))
+
+	flushed = nil
+	require.NoError(t, c.Flush(ctx))
+	assert.Empty(t, flushed)
+}
+
+func TestFlushKeepsSketchesOnError(t *testing.T) {
+	fail := true
+	var flushed []models.VisitorSketch
+	c := New(slogdiscard.NewDiscardLogger(), storeFunc(func(sketches []models.VisitorSketch) error {
+		if fail {
+			return errors.New("database is locked")
+		}
+		flushed = sketches
+		return nil
+	}))
+
+	ctx := context.Background()
+	day := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, c.Publish(ctx, click("abc", "1", day)))
+	require.Error(t, c.Flush(ctx))
+
+	require.NoError(t, c.Publish(ctx, click("abc", "2", day)))
+	fail = false
+	require.NoError(t, c.Flush(ctx))
+
+	assert.Equal(t, map[string]uint64{"abc 2024-03-01": 2}, estimates(t, flushed))
+}
+
+func TestPublishPrefersVisitorKey(t *testing.T) {
+	var flushed []models.VisitorSketch
+	c := New(slogdiscard.NewDiscardLogger(), storeFunc(func(sketches []models.VisitorSketch) error {
+		flushed = sketches
+		return nil
+	}))
+
+	ctx := context.Background()
+	day := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	fp := Fingerprint([]byte("key"), "192.0.2.1", "curl/8.0")
+	for i := range 5 {
+		// a client without cookies gets a new visitor id on every click
+		event := events.New(events.LinkClicked, "", "abc", events.ClickData{VisitorID: strconv.Itoa(i), VisitorKey: fp})
+		event.OccurredAt = day
+		require.NoError(t, c.Publish(ctx, event))
+	}
+
+	require.NoError(t, c.Flush(ctx))
+	assert.Equal(t, map[string]uint64{"abc 2024-03-01": 1}, estimates(t, flushed))
+}
+
+func TestFingerprint(t *testing.T) {
+	fp := Fingerprint([]byte("key"), "192.0.2.1", "curl/8.0")
+
+	assert.Equal(t, fp, Fingerprint([]byte("key"), "192.0.2.1", "curl/8.0"))
+	assert.NotEqual(t, fp, Fingerprint([]byte("other"), "192.0.2.1", "curl/8.0"))
+	assert.NotEqual(t, fp, Fingerprint([]byte("key"), "192.0.2.2", "curl/8.0"))
+	assert.NotEqual(t, fp, Fingerprint([]byte("key"), "192.0.2.1curl/8.0", ""))
+}
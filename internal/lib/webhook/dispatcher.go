@@ -0,0 +1,199 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/netguard"
+	"url-shortener/internal/models"
+)
+
+// Store hands out due deliveries and keeps the outcome of attempts.
+type Store interface {
+	GetDueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error)
+	SaveWebhookAttempt(ctx context.Context, delivery models.WebhookDelivery) error
+}
+
+type Options struct {
+	// Timeout covers a single attempt.
+	Timeout time.Duration
+	// MaxAttempts is the number of attempts after which a delivery fails for good.
+	MaxAttempts int
+	// RetryInterval is the wait after the first failed attempt, it doubles
+	// with every further attempt up to MaxRetryInterval.
+	RetryInterval    time.Duration
+	MaxRetryInterval time.Duration
+	BatchSize        int
+	PollInterval     time.Duration
+	UserAgent        string
+	// AllowPrivate lets deliveries go to loopback and private networks.
+	// Only tests should need it.
+	AllowPrivate bool
+}
+
+// Dispatcher sends due deliveries.
+type Dispatcher struct {
+	log    *slog.Logger
+	store  Store
+	client *http.Client
+	opts   Options
+	now    func() time.Time
+}
+
+func NewDispatcher(log *slog.Logger, store Store, opts Options) *Dispatcher {
+	dialer := &net.Dialer{Timeout: opts.Timeout}
+	if !opts.AllowPrivate {
+		dialer.Control = netguard.PublicOnly
+	}
+	if opts.MaxAttempts < 1 {
+		opts.MaxAttempts = 1
+	}
+
+	return &Dispatcher{
+		log:   log.With(slog.String("component", "webhook")),
+		store: store,
+		client: &http.Client{
+			Transport: &http.Transport{
+				Proxy:                 nil,
+				DialContext:           dialer.DialContext,
+				TLSHandshakeTimeout:   opts.Timeout,
+				ResponseHeaderTimeout: opts.Timeout,
+				IdleConnTimeout:       time.Minute,
+			},
+			Timeout: opts.Timeout,
+			// a redirect would send the signed payload somewhere the user didn't register
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		opts: opts,
+		now:  time.Now,
+	}
+}
+
+// Run sends due deliveries every poll interval until ctx is done.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.DispatchDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchDue sends one batch of due deliveries and returns how many were attempted.
+func (d *Dispatcher) DispatchDue(ctx context.Context) int {
+	deliveries, err := d.store.GetDueWebhookDeliveries(ctx, d.now(), d.opts.BatchSize)
+	if err != nil {
+		d.log.Error("failed to get due webhook deliveries", sl.Err(err))
+		return 0
+	}
+
+	for _, delivery := range deliveries {
+		if ctx.Err() != nil {
+			return 0
+		}
+
+		statusCode, err := d.send(ctx, delivery)
+		if ctx.Err() != nil {
+			// shutting down, the delivery stays due
+			return 0
+		}
+
+		delivery = d.outcome(delivery, statusCode, err)
+		if delivery.Status == models.DeliveryStatusFailed {
+			d.log.Info("webhook delivery failed for good", slog.Int64("delivery_id", delivery.ID),
+				slog.Int64("webhook_id", delivery.WebhookID), slog.String("error", delivery.LastError))
+		}
+
+		if err := d.store.SaveWebhookAttempt(ctx, delivery); err != nil {
+			d.log.Error("failed to save webhook attempt", slog.Int64("delivery_id", delivery.ID), sl.Err(err))
+		}
+	}
+
+	return len(deliveries)
+}
+
+func (d *Dispatcher) send(ctx context.Context, delivery models.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(HeaderSignature, Sign(delivery.Secret, d.now(), delivery.Payload))
+	if d.opts.UserAgent != "" {
+		req.Header.Set("User-Agent", d.opts.UserAgent)
+	}
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	io.Copy(io.Discard, io.LimitReader(res.Body, 4096))
+	res.Body.Close()
+
+	return res.StatusCode, nil
+}
+
+// outcome applies the result of an attempt to the delivery.
+func (d *Dispatcher) outcome(delivery models.WebhookDelivery, statusCode int, err error) models.WebhookDelivery {
+	now := d.now()
+
+	delivery.Attempts++
+	delivery.LastStatusCode = statusCode
+	delivery.LastError = ""
+
+	if err == nil && statusCode >= 200 && statusCode <= 299 {
+		delivery.Status = models.DeliveryStatusDelivered
+		delivery.NextAttemptAt = nil
+		delivery.DeliveredAt = &now
+		return delivery
+	}
+
+	if err != nil {
+		delivery.LastError = err.Error()
+	} else {
+		delivery.LastError = fmt.Sprintf("unexpected status %d", statusCode)
+	}
+
+	if delivery.Attempts >= d.opts.MaxAttempts {
+		delivery.Status = models.DeliveryStatusFailed
+		delivery.NextAttemptAt = nil
+		return delivery
+	}
+
+	next := now.Add(d.backoff(delivery.Attempts))
+	delivery.Status = models.DeliveryStatusPending
+	delivery.NextAttemptAt = &next
+
+	return delivery
+}
+
+// backoff is the wait before the next attempt after n failed attempts.
+func (d *Dispatcher) backoff(n int) time.Duration {
+	wait := d.opts.RetryInterval
+	for i := 1; i < n && wait < d.opts.MaxRetryInterval; i++ {
+		wait *= 2
+	}
+	if wait > d.opts.MaxRetryInterval {
+		return d.opts.MaxRetryInterval
+	}
+
+	return wait
+}
@@ -0,0 +1,131 @@
+// Package webhook delivers link events to user endpoints through an outbox
+// table. Deliveries are signed with the webhook's secret and retried with
+// exponential backoff.
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"url-shortener/internal/lib/events"
+)
+
+// Headers sent with every delivery.
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// NewSecret returns a random signing secret.
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// Sign returns the signature header value for body sent at ts:
+// "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">".
+func Sign(secret string, ts time.Time, body []byte) string {
+	t := strconv.FormatInt(ts.Unix(), 10)
+
+	return "t=" + t + ",v1=" + hex.EncodeToString(mac(secret, t, body))
+}
+
+// Verify checks a signature header produced by Sign and rejects signatures
+// older than tolerance. Receivers can use it as a reference implementation.
+func Verify(secret string, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var t, v1 string
+	for _, part := range strings.Split(header, ",") {
+		k, v, _ := strings.Cut(part, "=")
+		switch k {
+		case "t":
+			t = v
+		case "v1":
+			v1 = v
+		}
+	}
+
+	ts, err := strconv.ParseInt(t, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if now.Sub(time.Unix(ts, 0)) > tolerance {
+		return fmt.Errorf("%w: too old", ErrInvalidSignature)
+	}
+
+	got, err := hex.DecodeString(v1)
+	if err != nil || !hmac.Equal(got, mac(secret, t, body)) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+func mac(secret string, t string, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(t))
+	h.Write([]byte("."))
+	h.Write(body)
+
+	return h.Sum(nil)
+}
+
+// Payload is the JSON body of a delivery.
+type Payload struct {
+	Event      string    `json:"event"`
+	Domain     string    `json:"domain,omitempty"`
+	Alias      string    `json:"alias"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Data       any       `json:"data,omitempty"`
+}
+
+// Enqueuer stores deliveries of an event for the webhooks of the link's owner.
+type Enqueuer interface {
+	EnqueueWebhookEvent(ctx context.Context, domain string, alias string, event string, payload []byte) (int64, error)
+}
+
+// Outbox is an events.Publisher queueing events for delivery. Events are
+// queued after the change that caused them has committed, so an event is lost
+// if the process dies in between or the insert fails: queueing is at-most-once,
+// only the deliveries already queued are retried until they succeed.
+type Outbox struct {
+	enqueuer Enqueuer
+}
+
+func NewOutbox(enqueuer Enqueuer) *Outbox {
+	return &Outbox{enqueuer: enqueuer}
+}
+
+func (o *Outbox) Publish(ctx context.Context, event events.Event) error {
+	payload, err := json.Marshal(Payload{
+		Event:      event.Type,
+		Domain:     event.Domain,
+		Alias:      event.Alias,
+		OccurredAt: event.OccurredAt,
+		Data:       event.Data,
+	})
+	if err != nil {
+		return fmt.Errorf("marshal payload: %w", err)
+	}
+
+	if _, err := o.enqueuer.EnqueueWebhookEvent(ctx, event.Domain, event.Alias, event.Type, payload); err != nil {
+		return fmt.Errorf("enqueue %s: %w", event.Type, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,215 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/events"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/models"
+)
+
+func TestSignVerify(t *testing.T) {
+	body := []byte(`{"event":"link.created"}`)
+	now := time.Unix(1700000000, 0)
+
+	sig := Sign("secret", now, body)
+	assert.True(t, strings.HasPrefix(sig, "t=1700000000,v1="))
+
+	require.NoError(t, Verify("secret", sig, body, time.Minute, now.Add(30*time.Second)))
+	assert.ErrorIs(t, Verify("other", sig, body, time.Minute, now), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify("secret", sig, []byte(`{}`), time.Minute, now), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify("secret", sig, body, time.Minute, now.Add(2*time.Minute)), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify("secret", "garbage", body, time.Minute, now), ErrInvalidSignature)
+}
+
+type enqueuerFunc func(domain string, alias string, event string, payload []byte)
+
+func (f enqueuerFunc) EnqueueWebhookEvent(_ context.Context, domain string, alias string, event string, payload []byte) (int64, error) {
+	f(domain, alias, event, payload)
+	return 1, nil
+}
+
+func TestOutboxPublish(t *testing.T) {
+	var got Payload
+	outbox := NewOutbox(enqueuerFunc(func(domain, alias, event string, payload []byte) {
+		assert.Equal(t, "go.example.com", domain)
+		assert.Equal(t, "abc", alias)
+		assert.Equal(t, events.LinkCreated, event)
+		require.NoError(t, json.Unmarshal(payload, &got))
+	}))
+
+	err := outbox.Publish(context.Background(),
+		events.New(events.LinkCreated, "go.example.com", "abc", events.LinkData{URL: "https://example.com"}))
+	require.NoError(t, err)
+
+	assert.Equal(t, events.LinkCreated, got.Event)
+	assert.Equal(t, "abc", got.Alias)
+	assert.Equal(t, map[string]any{"url": "https://example.com"}, got.Data)
+}
+
+type memStore struct {
+	due   []models.WebhookDelivery
+	saved []models.WebhookDelivery
+}
+
+func (s *memStore) GetDueWebhookDeliveries(_ context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	var due []models.WebhookDelivery
+	for _, d := range s.due {
+		if d.Status == models.DeliveryStatusPending && !d.NextAttemptAt.After(now) {
+			due = append(due, d)
+		}
+	}
+	return due, nil
+}
+
+func (s *memStore) SaveWebhookAttempt(_ context.Context, d models.WebhookDelivery) error {
+	s.saved = append(s.saved, d)
+	for i := range s.due {
+		if s.due[i].ID == d.ID {
+			s.due[i] = d
+		}
+	}
+	return nil
+}
+
+func testDispatcher(store Store, now *time.Time) *Dispatcher {
+	d := NewDispatcher(slogdiscard.NewDiscardLogger(), store, Options{
+		Timeout:          time.Second,
+		MaxAttempts:      3,
+		RetryInterval:    time.Minute,
+		MaxRetryInterval: time.Hour,
+		BatchSize:        10,
+		AllowPrivate:     true,
+	})
+	d.now = func() time.Time { return *now }
+
+	return d
+}
+
+func pending(id int64, url string, now time.Time) models.WebhookDelivery {
+	return models.WebhookDelivery{
+		ID:            id,
+		WebhookID:     7,
+		Event:         events.LinkClicked,
+		Payload:       []byte(`{"event":"link.clicked","alias":"abc"}`),
+		Status:        models.DeliveryStatusPending,
+		NextAttemptAt: &now,
+		URL:           url,
+		Secret:        "whsec_test",
+	}
+}
+
+func TestDispatchDelivered(t *testing.T) {
+	now := time.Now()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, events.LinkClicked, r.Header.Get(HeaderEvent))
+		assert.Equal(t, "1", r.Header.Get(HeaderDelivery))
+		assert.NoError(t, Verify("whsec_test", r.Header.Get(HeaderSignature), body, time.Minute, time.Now()))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	store := &memStore{due: []models.WebhookDelivery{pending(1, srv.URL, now)}}
+
+	require.Equal(t, 1, testDispatcher(store, &now).DispatchDue(context.Background()))
+
+	require.Len(t, store.saved, 1)
+	d := store.saved[0]
+	assert.Equal(t, models.DeliveryStatusDelivered, d.Status)
+	assert.Equal(t, 1, d.Attempts)
+	assert.Equal(t, http.StatusNoContent, d.LastStatusCode)
+	assert.Nil(t, d.NextAttemptAt)
+	assert.NotNil(t, d.DeliveredAt)
+}
+
+func TestDispatchRetriesWithBackoff(t *testing.T) {
+	now := time.Now()
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	store := &memStore{due: []models.WebhookDelivery{pending(1, srv.URL, now)}}
+	d := testDispatcher(store, &now)
+
+	// attempts 1 and 2 are retried after 1m and 2m, attempt 3 is the last one
+	for i, wait := range []time.Duration{time.Minute, 2 * time.Minute} {
+		require.Equal(t, 1, d.DispatchDue(context.Background()))
+
+		got := store.saved[i]
+		assert.Equal(t, models.DeliveryStatusPending, got.Status)
+		assert.Equal(t, i+1, got.Attempts)
+		assert.Equal(t, http.StatusServiceUnavailable, got.LastStatusCode)
+		assert.Equal(t, "unexpected status 503", got.LastError)
+		require.NotNil(t, got.NextAttemptAt)
+		assert.Equal(t, now.Add(wait), *got.NextAttemptAt)
+
+		assert.Equal(t, 0, d.DispatchDue(context.Background()), "not due before backoff")
+		now = *got.NextAttemptAt
+	}
+
+	require.Equal(t, 1, d.DispatchDue(context.Background()))
+	last := store.saved[2]
+	assert.Equal(t, models.DeliveryStatusFailed, last.Status)
+	assert.Equal(t, 3, last.Attempts)
+	assert.Nil(t, last.NextAttemptAt)
+
+	assert.Equal(t, 0, d.DispatchDue(context.Background()))
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestDispatchDoesNotFollowRedirects(t *testing.T) {
+	now := time.Now()
+
+	var followed atomic.Bool
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hook", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/elsewhere", http.StatusTemporaryRedirect)
+	})
+	mux.HandleFunc("/elsewhere", func(w http.ResponseWriter, r *http.Request) {
+		followed.Store(true)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	store := &memStore{due: []models.WebhookDelivery{pending(1, srv.URL+"/hook", now)}}
+	testDispatcher(store, &now).DispatchDue(context.Background())
+
+	assert.False(t, followed.Load())
+	assert.Equal(t, http.StatusTemporaryRedirect, store.saved[0].LastStatusCode)
+	assert.Equal(t, models.DeliveryStatusPending, store.saved[0].Status)
+}
+
+func TestDispatchConnectionError(t *testing.T) {
+	now := time.Now()
+
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	store := &memStore{due: []models.WebhookDelivery{pending(1, url, now)}}
+	testDispatcher(store, &now).DispatchDue(context.Background())
+
+	got := store.saved[0]
+	assert.Zero(t, got.LastStatusCode)
+	assert.NotEmpty(t, got.LastError)
+	assert.Equal(t, models.DeliveryStatusPending, got.Status)
+}
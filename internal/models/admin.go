@@ -0,0 +1,29 @@
+package models
+
+// URLFilter narrows down listed links. Empty fields match everything.
+type URLFilter struct {
+	// Alias and URL match substrings of the alias and the destination url.
+	Alias  string
+	URL    string
+	UserID int64
+	Tag    string
+	Folder string
+	// Broken keeps only links whose destination failed enough health checks.
+	Broken bool
+	Limit  int
+	Offset int
+}
+
+// Stats are system-wide counters shown to admins.
+type Stats struct {
+	URLs            int64 `json:"urls"`
+	QuarantinedURLs int64 `json:"quarantined_urls"`
+	// DeletedURLs are links in the trash waiting to be purged.
+	DeletedURLs     int64 `json:"deleted_urls"`
+	Users           int64 `json:"users"`
+	Clicks          int64 `json:"clicks"`
+	ClicksLast24h   int64 `json:"clicks_last_24h"`
+	Domains         int64 `json:"domains"`
+	VerifiedDomains int64 `json:"verified_domains"`
+	OpenReports     int64 `json:"open_reports"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditEntry records a mutating operation. Before and After hold JSON
+// snapshots of the changed object and are empty for creations and deletions
+// respectively.
+type AuditEntry struct {
+	ID        int64           `json:"id"`
+	UserID    int64           `json:"user_id,omitempty"`
+	Email     string          `json:"email,omitempty"`
+	RequestID string          `json:"request_id,omitempty"`
+	IP        string          `json:"ip,omitempty"`
+	Action    string          `json:"action"`
+	Domain    string          `json:"domain,omitempty"`
+	Alias     string          `json:"alias,omitempty"`
+	Before    json.RawMessage `json:"before,omitempty"`
+	After     json.RawMessage `json:"after,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// AuditFilter narrows down the audit log. Empty fields match everything.
+type AuditFilter struct {
+	UserID int64
+	Action string
+	Domain string
+	Alias  string
+	From   time.Time
+	To     time.Time
+	Limit  int
+	Offset int
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// Click is a single redirect served for a short link.
+type Click struct {
+	ID        int64  `json:"id"`
+	Domain    string `json:"domain,omitempty"`
+	Alias     string `json:"alias"`
+	VariantID int64  `json:"variant_id,omitempty"`
+	VisitorID string `json:"visitor_id,omitempty"`
+	// Class is human, bot or preview.
+	Class string `json:"class"`
+	// Referrer is the domain the visitor came from, empty for direct traffic.
+	Referrer       string    `json:"referrer,omitempty"`
+	Browser        string    `json:"browser,omitempty"`
+	BrowserVersion string    `json:"browser_version,omitempty"`
+	OS             string    `json:"os,omitempty"`
+	Device         string    `json:"device,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// Periods clicks are counted over in exports.
+const (
+	PeriodHour = "hour"
+	PeriodDay  = "day"
+)
+
+// ClickCount is the number of clicks of a link in an hour or a day (UTC) by
+// class of traffic and split variant.
+type ClickCount struct {
+	Domain string
+	Alias  string
+	// Period is the start of the hour or day.
+	Period    time.Time
+	Class     string
+	VariantID int64
+	Clicks    int64
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// Domain is a custom hostname short links can be served from.
+type Domain struct {
+	ID                int64      `json:"id"`
+	Hostname          string     `json:"hostname"`
+	UserID            int64      `json:"user_id"`
+	VerificationToken string     `json:"verification_token"`
+	VerifiedAt        *time.Time `json:"verified_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+func (d Domain) Verified() bool {
+	return d.VerifiedAt != nil
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// LinkHealth is the result of the last check of a link's destination.
+type LinkHealth struct {
+	// StatusCode is zero when the request failed before a response.
+	StatusCode int    `json:"status_code,omitempty"`
+	Error      string `json:"error,omitempty"`
+	// Failures is the number of consecutive failed checks.
+	Failures  int       `json:"failures"`
+	CheckedAt time.Time `json:"checked_at"`
+	// BrokenAt is set once Failures reaches the threshold and cleared by the next successful check.
+	BrokenAt    *time.Time `json:"broken_at,omitempty"`
+	NextCheckAt time.Time  `json:"-"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// IdempotencyKey is a request a user sent with an Idempotency-Key header.
+// Status is 0 while the request is in flight, the response is stored once
+// it completes.
+type IdempotencyKey struct {
+	UserID      int64
+	Key         string
+	RequestHash string
+	Status      int
+	ContentType string
+	Body        []byte
+	CreatedAt   time.Time
+}
+
+func (k IdempotencyKey) Completed() bool {
+	return k.Status != 0
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// PageMetadata is what the destination page says about itself in its <title>
+// and OpenGraph tags.
+type PageMetadata struct {
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Image       string `json:"image,omitempty"`
+	SiteName    string `json:"site_name,omitempty"`
+	// Error is why the page could not be read, the other fields are empty then.
+	Error     string    `json:"error,omitempty"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
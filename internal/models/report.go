@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+const (
+	ReportStatusOpen     = "open"
+	ReportStatusResolved = "resolved"
+)
+
+// Report is an abuse report filed against a short link.
+type Report struct {
+	ID            int64      `json:"id"`
+	Domain        string     `json:"domain,omitempty"`
+	Alias         string     `json:"alias"`
+	URL           string     `json:"url"`
+	Reason        string     `json:"reason"`
+	Details       string     `json:"details,omitempty"`
+	ReporterEmail string     `json:"reporter_email,omitempty"`
+	ReporterIP    string     `json:"reporter_ip,omitempty"`
+	Status        string     `json:"status"`
+	Quarantined   bool       `json:"quarantined"`
+	CreatedAt     time.Time  `json:"created_at"`
+	ResolvedAt    *time.Time `json:"resolved_at,omitempty"`
+}
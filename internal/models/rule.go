@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Rule is a targeting rule attached to a short link. Rules are evaluated in
+// Position order and the first one whose non-empty conditions all match wins.
+type Rule struct {
+	ID        int64     `json:"id"`
+	Position  int       `json:"position"`
+	Platform  string    `json:"platform,omitempty"`
+	Language  string    `json:"language,omitempty"`
+	Country   string    `json:"country,omitempty"`
+	TimeFrom  string    `json:"time_from,omitempty"`
+	TimeTo    string    `json:"time_to,omitempty"`
+	TargetURL string    `json:"target_url"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,10 @@
+package models
+
+// UserSettings are the defaults of a user. Users without saved settings get
+// the zero value.
+type UserSettings struct {
+	UserID int64 `json:"-"`
+	// ReuseExisting makes shortening a url the user already shortened return
+	// the existing alias, unless the request says otherwise.
+	ReuseExisting bool `json:"reuse_existing"`
+}
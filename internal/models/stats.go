@@ -0,0 +1,73 @@
+package models
+
+import "time"
+
+// Breakdown dimensions of clicks.
+const (
+	DimensionReferrer       = "referrer"
+	DimensionBrowser        = "browser"
+	DimensionBrowserVersion = "browser_version"
+	DimensionOS             = "os"
+	DimensionDevice         = "device"
+)
+
+// Dimensions lists all breakdown dimensions.
+var Dimensions = []string{
+	DimensionReferrer, DimensionBrowser, DimensionBrowserVersion, DimensionOS, DimensionDevice,
+}
+
+// VisitorSketch is a HyperLogLog sketch of the visitors of a link on a day (UTC).
+type VisitorSketch struct {
+	Domain string
+	Alias  string
+	Day    time.Time
+	// Class is the class of traffic the visitors belong to.
+	Class  string
+	Sketch []byte
+}
+
+// StatsFilter selects the links, days and classes of traffic stats are
+// computed for. All links of the user are included when Alias is empty. From
+// and To are inclusive days.
+type StatsFilter struct {
+	UserID  int64
+	Domain  string
+	Alias   string
+	From    time.Time
+	To      time.Time
+	Traffic []string
+	// Top is the number of values returned per breakdown dimension,
+	// breakdowns are left out when zero.
+	Top int
+}
+
+// LinkStats are click counters of one or more links over a range of days.
+type LinkStats struct {
+	From    string   `json:"from"`
+	To      string   `json:"to"`
+	Traffic []string `json:"traffic"`
+	Clicks  int64    `json:"clicks"`
+	// UniqueVisitors is an estimate, UniqueVisitorsError is its relative
+	// standard error. Visitors without the vid cookie are counted by their
+	// address and User-Agent, so the estimate can be off by more than the
+	// error for clients behind a shared address.
+	UniqueVisitors      uint64     `json:"unique_visitors"`
+	UniqueVisitorsError float64    `json:"unique_visitors_error"`
+	Days                []DayStats `json:"days"`
+	// Breakdowns are the top values of each dimension by clicks.
+	Breakdowns map[string][]BreakdownItem `json:"breakdowns,omitempty"`
+}
+
+// DayStats are the counters of a single day.
+type DayStats struct {
+	Day            string `json:"day"`
+	Clicks         int64  `json:"clicks"`
+	UniqueVisitors uint64 `json:"unique_visitors"`
+}
+
+// BreakdownItem is the number of clicks with a value of a dimension. An empty
+// referrer is direct traffic.
+type BreakdownItem struct {
+	Value  string `json:"value"`
+	Clicks int64  `json:"clicks"`
+}
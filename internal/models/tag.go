@@ -0,0 +1,33 @@
+package models
+
+// Tag is a label attached to links. URLs is the number of the user's links carrying it.
+type Tag struct {
+	Name string `json:"name"`
+	URLs int64  `json:"urls"`
+}
+
+// Folder groups links, a link is in at most one folder.
+type Folder struct {
+	Name string `json:"name"`
+	URLs int64  `json:"urls"`
+}
+
+// URLAttrs are the optional attributes of a new link.
+type URLAttrs struct {
+	Tags        []string
+	Folder      string
+	Title       string
+	Description string
+	Notes       string
+}
+
+// URLUpdate changes a link. Nil fields are left as they are, an empty Folder
+// takes the link out of its folder and empty Tags remove all tags.
+type URLUpdate struct {
+	URL         *string
+	Tags        *[]string
+	Folder      *string
+	Title       *string
+	Description *string
+	Notes       *string
+}
@@ -4,9 +4,25 @@ import "time"
 
 type URL struct {
     ID        int64     `json:"id"`
+    Domain    string    `json:"domain,omitempty"`
     Alias     string    `json:"alias"`
     URL       string    `json:"url"`
     UserID    int64     `json:"user_id"`
+    Tags      []string  `json:"tags,omitempty"`
+    Folder    string    `json:"folder,omitempty"`
+	// Title, Description and Notes are set by the owner, Notes are never shown to visitors.
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Notes       string `json:"notes,omitempty"`
+	// Metadata is read from the destination page in the background, nil until fetched.
+	Metadata *PageMetadata `json:"metadata,omitempty"`
+	// Health is the last destination check, nil until the link is checked.
+	Health *LinkHealth `json:"health,omitempty"`
     CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	// QuarantinedAt is set while the link is disabled after an abuse report.
+	QuarantinedAt *time.Time `json:"quarantined_at,omitempty"`
+	// DeletedAt is set while the link is in the trash. The alias stays
+	// reserved until the link is purged.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
\ No newline at end of file
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// Variant is one of the weighted destinations of a split (A/B) link.
+type Variant struct {
+	ID        int64     `json:"id"`
+	URL       string    `json:"url"`
+	Weight    int       `json:"weight"`
+	Clicks    int64     `json:"clicks"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,201 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"url-shortener/internal/lib/audit"
+	"url-shortener/internal/models"
+)
+
+// AdminSearchURLs returns links of all users matching the filter, newest first.
+func (s *Storage) AdminSearchURLs(ctx context.Context, filter models.URLFilter) ([]models.URL, error) {
+	const op = "storage.sqlite.AdminSearchURLs"
+
+	where, args := urlFilterSQL(filter)
+	query := selectURLs + " WHERE 1 = 1" + where
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = -1 // no limit in SQLite
+	}
+	query += " ORDER BY u.id DESC LIMIT ? OFFSET ?"
+	args = append(args, limit, filter.Offset)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	defer rows.Close()
+
+	urls, err := scanURLs(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: scan row: %w", op, err)
+	}
+
+	if err := loadTags(ctx, s.db, urls); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return urls, nil
+}
+
+// AdminDeleteURLs moves links to the trash by id regardless of their owner
+// and returns the number of deleted links, like DeleteURL. Unknown ids and
+// links already in the trash are ignored.
+func (s *Storage) AdminDeleteURLs(ctx context.Context, ids []int64) (int64, error) {
+	const op = "storage.sqlite.AdminDeleteURLs"
+
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	where, args := inClause("WHERE deleted_at IS NULL AND id IN", ids)
+
+	targets, err := auditTargets(ctx, tx, where, args...)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE url SET deleted_at = CURRENT_TIMESTAMP "+where, args...); err != nil {
+		return 0, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	for _, t := range targets {
+		if err := writeAudit(ctx, tx, audit.ActionURLBulkDelete, t.domain, t.alias, t.state, nil); err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return int64(len(targets)), nil
+}
+
+// AdminTransferURLs moves links owned by fromUserID to toUserID and returns
+// the number of moved links. Only links with the given ids are moved unless
+// ids is empty.
+func (s *Storage) AdminTransferURLs(ctx context.Context, fromUserID int64, toUserID int64, ids []int64) (int64, error) {
+	const op = "storage.sqlite.AdminTransferURLs"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	where := "WHERE user_id = ?"
+	args := []any{fromUserID}
+
+	if len(ids) > 0 {
+		in, inArgs := inClause(" AND id IN", ids)
+		where += in
+		args = append(args, inArgs...)
+	}
+
+	targets, err := auditTargets(ctx, tx, where, args...)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE url SET user_id = ?, updated_at = CURRENT_TIMESTAMP "+where,
+		append([]any{toUserID}, args...)...)
+	if err != nil {
+		return 0, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	for _, t := range targets {
+		after := t.state
+		after.UserID = toUserID
+		if err := writeAudit(ctx, tx, audit.ActionURLTransfer, t.domain, t.alias, t.state, after); err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return int64(len(targets)), nil
+}
+
+type auditTarget struct {
+	domain string
+	alias  string
+	state  urlState
+}
+
+// auditTargets snapshots the urls matched by where before a bulk change.
+func auditTargets(ctx context.Context, tx *sql.Tx, where string, args ...any) ([]auditTarget, error) {
+	rows, err := tx.QueryContext(ctx,
+		"SELECT domain, alias, url, user_id, quarantined_at IS NOT NULL FROM url "+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query urls: %w", err)
+	}
+	defer rows.Close()
+
+	var targets []auditTarget
+	for rows.Next() {
+		var t auditTarget
+		if err := rows.Scan(&t.domain, &t.alias, &t.state.URL, &t.state.UserID, &t.state.Quarantined); err != nil {
+			return nil, fmt.Errorf("scan url: %w", err)
+		}
+		targets = append(targets, t)
+	}
+
+	return targets, rows.Err()
+}
+
+// AdminStats returns system-wide counters. Clicks are read from the rollup
+// tables plus those not rolled up yet, ClicksLast24h has hourly resolution.
+func (s *Storage) AdminStats(ctx context.Context) (models.Stats, error) {
+	const op = "storage.sqlite.AdminStats"
+
+	var stats models.Stats
+
+	err := s.db.QueryRowContext(ctx, `SELECT
+			(SELECT COUNT(*) FROM url WHERE deleted_at IS NULL),
+			(SELECT COUNT(*) FROM url WHERE quarantined_at IS NOT NULL AND deleted_at IS NULL),
+			(SELECT COUNT(*) FROM url WHERE deleted_at IS NOT NULL),
+			(SELECT COUNT(DISTINCT user_id) FROM url),
+			(SELECT COALESCE(SUM(clicks), 0) FROM click_daily) + (SELECT COUNT(*) FROM click WHERE rolled_up = 0),
+			(SELECT COALESCE(SUM(clicks), 0) FROM click_hourly WHERE hour > strftime('%Y-%m-%d %H:00:00', 'now', '-1 day')) +
+				(SELECT COUNT(*) FROM click WHERE rolled_up = 0 AND created_at >= datetime('now', '-1 day')),
+			(SELECT COUNT(*) FROM domain),
+			(SELECT COUNT(*) FROM domain WHERE verified_at IS NOT NULL),
+			(SELECT COUNT(*) FROM report WHERE status = ?)`,
+		models.ReportStatusOpen,
+	).Scan(
+		&stats.URLs, &stats.QuarantinedURLs, &stats.DeletedURLs, &stats.Users, &stats.Clicks, &stats.ClicksLast24h,
+		&stats.Domains, &stats.VerifiedDomains, &stats.OpenReports,
+	)
+	if err != nil {
+		return models.Stats{}, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	return stats, nil
+}
+
+// inClause appends an "(?, ?, ...)" list for ids to prefix.
+func inClause(prefix string, ids []int64) (string, []any) {
+	args := make([]any, 0, len(ids))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+
+	return prefix + " (?" + strings.Repeat(", ?", len(ids)-1) + ")", args
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
@@ -0,0 +1,246 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"url-shortener/internal/lib/audit"
+	"url-shortener/internal/models"
+)
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// urlState, ruleState, variantState and domainState are the snapshots stored
+// in the before and after columns of the audit log.
+type urlState struct {
+	URL         string   `json:"url"`
+	UserID      int64    `json:"user_id"`
+	Quarantined bool     `json:"quarantined,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Folder      string   `json:"folder,omitempty"`
+	Title       string   `json:"title,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Notes       string   `json:"notes,omitempty"`
+}
+
+type ruleState struct {
+	ID        int64  `json:"id"`
+	Position  int    `json:"position"`
+	Platform  string `json:"platform,omitempty"`
+	Language  string `json:"language,omitempty"`
+	Country   string `json:"country,omitempty"`
+	TimeFrom  string `json:"time_from,omitempty"`
+	TimeTo    string `json:"time_to,omitempty"`
+	TargetURL string `json:"target_url"`
+}
+
+func newRuleState(r models.Rule) ruleState {
+	return ruleState{
+		ID:        r.ID,
+		Position:  r.Position,
+		Platform:  r.Platform,
+		Language:  r.Language,
+		Country:   r.Country,
+		TimeFrom:  r.TimeFrom,
+		TimeTo:    r.TimeTo,
+		TargetURL: r.TargetURL,
+	}
+}
+
+type variantState struct {
+	ID     int64  `json:"id"`
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+}
+
+type domainState struct {
+	Hostname string `json:"hostname"`
+	UserID   int64  `json:"user_id"`
+	Verified bool   `json:"verified"`
+}
+
+// writeAudit appends an audit record attributed to the actor from ctx. It is
+// called with the transaction of the audited operation so both are committed
+// or rolled back together. nil before or after are stored as NULL.
+func writeAudit(ctx context.Context, e execer, action string, domain string, alias string, before any, after any) error {
+	actor := audit.ActorFrom(ctx)
+
+	return insertAudit(ctx, e, models.AuditEntry{
+		UserID:    actor.UserID,
+		Email:     actor.Email,
+		RequestID: actor.RequestID,
+		IP:        actor.IP,
+		Action:    action,
+		Domain:    domain,
+		Alias:     alias,
+	}, before, after)
+}
+
+func insertAudit(ctx context.Context, e execer, entry models.AuditEntry, before any, after any) error {
+	beforeJSON, err := auditJSON(entry.Before, before)
+	if err != nil {
+		return fmt.Errorf("marshal audit before: %w", err)
+	}
+	afterJSON, err := auditJSON(entry.After, after)
+	if err != nil {
+		return fmt.Errorf("marshal audit after: %w", err)
+	}
+
+	var userID sql.NullInt64
+	if entry.UserID != 0 {
+		userID = sql.NullInt64{Int64: entry.UserID, Valid: true}
+	}
+
+	_, err = e.ExecContext(ctx, `INSERT INTO audit_log(user_id, email, request_id, ip, action, domain, alias, before, after)
+		VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		userID, entry.Email, entry.RequestID, entry.IP, entry.Action, entry.Domain, entry.Alias, beforeJSON, afterJSON,
+	)
+	if err != nil {
+		return fmt.Errorf("write audit log: %w", err)
+	}
+
+	return nil
+}
+
+func auditJSON(raw json.RawMessage, v any) (sql.NullString, error) {
+	if len(raw) > 0 {
+		return sql.NullString{String: string(raw), Valid: true}, nil
+	}
+	if v == nil {
+		return sql.NullString{}, nil
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+
+	return sql.NullString{String: string(b), Valid: true}, nil
+}
+
+// RecordAudit appends an audit record for operations outside of the storage,
+// such as login attempts. Actor fields left empty in entry are taken from ctx.
+func (s *Storage) RecordAudit(ctx context.Context, entry models.AuditEntry) error {
+	const op = "storage.sqlite.RecordAudit"
+
+	actor := audit.ActorFrom(ctx)
+	if entry.UserID == 0 {
+		entry.UserID = actor.UserID
+	}
+	if entry.Email == "" {
+		entry.Email = actor.Email
+	}
+	if entry.RequestID == "" {
+		entry.RequestID = actor.RequestID
+	}
+	if entry.IP == "" {
+		entry.IP = actor.IP
+	}
+
+	if err := insertAudit(ctx, s.db, entry, nil, nil); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// GetAuditLog returns audit records matching the filter, newest first.
+func (s *Storage) GetAuditLog(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	const op = "storage.sqlite.GetAuditLog"
+
+	entries := make([]models.AuditEntry, 0)
+
+	err := s.ExportAuditLog(ctx, filter, func(e models.AuditEntry) error {
+		entries = append(entries, e)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return entries, nil
+}
+
+// ExportAuditLog streams audit records matching the filter to fn, newest
+// first, without loading them all into memory. Iteration stops at the first
+// error returned by fn.
+func (s *Storage) ExportAuditLog(ctx context.Context, filter models.AuditFilter, fn func(models.AuditEntry) error) error {
+	const op = "storage.sqlite.ExportAuditLog"
+
+	query := `SELECT id, COALESCE(user_id, 0), email, request_id, ip, action, domain, alias, before, after, created_at
+		FROM audit_log WHERE 1 = 1`
+	var args []any
+
+	if filter.UserID != 0 {
+		query += " AND user_id = ?"
+		args = append(args, filter.UserID)
+	}
+	if filter.Action != "" {
+		query += " AND action = ?"
+		args = append(args, filter.Action)
+	}
+	if filter.Domain != "" {
+		query += " AND domain = ?"
+		args = append(args, filter.Domain)
+	}
+	if filter.Alias != "" {
+		query += " AND alias = ?"
+		args = append(args, filter.Alias)
+	}
+	if !filter.From.IsZero() {
+		query += " AND created_at >= ?"
+		args = append(args, filter.From.UTC().Format(sqliteTimeFormat))
+	}
+	if !filter.To.IsZero() {
+		query += " AND created_at < ?"
+		args = append(args, filter.To.UTC().Format(sqliteTimeFormat))
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = -1 // no limit in SQLite
+	}
+	query += " ORDER BY id DESC LIMIT ? OFFSET ?"
+	args = append(args, limit, filter.Offset)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			e             models.AuditEntry
+			before, after sql.NullString
+		)
+		err := rows.Scan(
+			&e.ID, &e.UserID, &e.Email, &e.RequestID, &e.IP, &e.Action, &e.Domain, &e.Alias, &before, &after, &e.CreatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("%s: scan row: %w", op, err)
+		}
+		if before.Valid {
+			e.Before = json.RawMessage(before.String)
+		}
+		if after.Valid {
+			e.After = json.RawMessage(after.String)
+		}
+
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("%s: iterate rows: %w", op, err)
+	}
+
+	return nil
+}
+
+// sqliteTimeFormat matches the format of CURRENT_TIMESTAMP so times compare as strings.
+const sqliteTimeFormat = "2006-01-02 15:04:05"
@@ -0,0 +1,169 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/mattn/go-sqlite3"
+
+	"url-shortener/internal/lib/urlnorm"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+// SaveOrReuseURL saves the url under alias like SaveURL, unless the user
+// already has a link on domain whose destination normalizes to the same url.
+// Then the id and alias of the oldest such link are returned and attrs are not
+// applied to it. Links in the trash or in quarantine are not reused. Both the
+// lookup and the domain check are part of the insert, so concurrent requests
+// can't both create a link.
+func (s *Storage) SaveOrReuseURL(
+	ctx context.Context, urlToSave string, domain string, alias string, userID int64, attrs models.URLAttrs,
+) (int64, string, error) {
+	const op = "storage.sqlite.SaveOrReuseURL"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, "", fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	hash := urlnorm.Hash(urlToSave)
+
+	// writing first takes the write lock, the lookups below can't go stale
+	res, err := tx.ExecContext(ctx, `INSERT INTO url(url, url_hash, domain, alias, user_id, title, description, notes)
+		SELECT ?, ?, ?, ?, ?, ?, ?, ?
+		WHERE `+usableDomain+` AND NOT EXISTS (SELECT 1 FROM url `+reusableURL+`)`,
+		urlToSave, hash, domain, alias, userID, attrs.Title, attrs.Description, attrs.Notes,
+		domain, domain, userID,
+		userID, domain, hash)
+	if err != nil {
+		if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return 0, "", fmt.Errorf("%s: %w", op, storage.ErrURLExists)
+		}
+
+		return 0, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, "", fmt.Errorf("%s: rows affected: %w", op, err)
+	}
+	if n == 0 {
+		if domain != "" {
+			if err := checkDomain(ctx, tx, domain, userID); err != nil {
+				return 0, "", fmt.Errorf("%s: %w", op, err)
+			}
+		}
+
+		var (
+			id       int64
+			existing string
+		)
+		err := tx.QueryRowContext(ctx, "SELECT id, alias FROM url "+reusableURL+" ORDER BY id LIMIT 1",
+			userID, domain, hash).Scan(&id, &existing)
+		if err != nil {
+			return 0, "", fmt.Errorf("%s: find existing url: %w", op, err)
+		}
+
+		return id, existing, nil
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, "", fmt.Errorf("%s: failed to get last insert id: %w", op, err)
+	}
+
+	if err := initURL(ctx, tx, id, urlToSave, domain, alias, userID, attrs); err != nil {
+		return 0, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, "", fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return id, "", nil
+}
+
+// reusableURL matches links of user_id on domain with the given url hash
+// that SaveOrReuseURL may hand out.
+const reusableURL = `WHERE user_id = ? AND domain = ? AND url_hash = ? AND deleted_at IS NULL AND quarantined_at IS NULL`
+
+// GetUserSettings returns the settings of the user, the zero settings if the
+// user never saved any.
+func (s *Storage) GetUserSettings(ctx context.Context, userID int64) (models.UserSettings, error) {
+	const op = "storage.sqlite.GetUserSettings"
+
+	settings := models.UserSettings{UserID: userID}
+	err := s.db.QueryRowContext(ctx, "SELECT reuse_existing FROM user_settings WHERE user_id = ?", userID).
+		Scan(&settings.ReuseExisting)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return models.UserSettings{}, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	return settings, nil
+}
+
+// SaveUserSettings replaces the settings of the user.
+func (s *Storage) SaveUserSettings(ctx context.Context, settings models.UserSettings) error {
+	const op = "storage.sqlite.SaveUserSettings"
+
+	_, err := s.db.ExecContext(ctx, `INSERT INTO user_settings(user_id, reuse_existing) VALUES(?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET reuse_existing = excluded.reuse_existing, updated_at = CURRENT_TIMESTAMP`,
+		settings.UserID, settings.ReuseExisting)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	return nil
+}
+
+// HashURLs sets the url hash of links saved before it existed, batchSize
+// links at a time, and returns how many were hashed.
+func (s *Storage) HashURLs(ctx context.Context, batchSize int) (int64, error) {
+	const op = "storage.sqlite.HashURLs"
+
+	var total int64
+	for {
+		rows, err := s.db.QueryContext(ctx, "SELECT id, url FROM url WHERE url_hash IS NULL LIMIT ?", batchSize)
+		if err != nil {
+			return total, fmt.Errorf("%s: execute statement: %w", op, err)
+		}
+
+		hashes := make(map[int64]string)
+		for rows.Next() {
+			var id int64
+			var rawURL string
+			if err := rows.Scan(&id, &rawURL); err != nil {
+				rows.Close()
+				return total, fmt.Errorf("%s: scan row: %w", op, err)
+			}
+			hashes[id] = urlnorm.Hash(rawURL)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return total, fmt.Errorf("%s: iterate rows: %w", op, err)
+		}
+
+		if len(hashes) == 0 {
+			return total, nil
+		}
+
+		tx, err := s.db.BeginTx(ctx, nil)
+		if err != nil {
+			return total, fmt.Errorf("%s: begin transaction: %w", op, err)
+		}
+		for id, hash := range hashes {
+			if _, err := tx.ExecContext(ctx, "UPDATE url SET url_hash = ? WHERE id = ?", hash, id); err != nil {
+				tx.Rollback()
+				return total, fmt.Errorf("%s: update url: %w", op, err)
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			return total, fmt.Errorf("%s: commit transaction: %w", op, err)
+		}
+		total += int64(len(hashes))
+	}
+}
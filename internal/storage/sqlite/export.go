@@ -0,0 +1,140 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"url-shortener/internal/models"
+)
+
+// ExportClicks streams the clicks of the links selected by filter between the
+// days filter.From and filter.To to fn, oldest first, without loading them
+// all into memory. Only clicks still kept in the click table are exported,
+// older ones only exist as counts, see PruneClicks. Iteration stops at the
+// first error returned by fn.
+func (s *Storage) ExportClicks(ctx context.Context, filter models.StatsFilter, fn func(models.Click) error) error {
+	const op = "storage.sqlite.ExportClicks"
+
+	urls, urlArg, err := s.linkScope(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	_, classes, classArgs := trafficScope(filter.Traffic)
+	from, to := dayRange(filter)
+
+	args := append([]any{urlArg, from.Format(sqliteTimeFormat), to.Format(sqliteTimeFormat)}, classArgs...)
+	rows, err := s.db.QueryContext(ctx, `SELECT c.id, u.domain, u.alias, COALESCE(c.variant_id, 0), c.visitor_id, c.class,
+			c.referrer, c.browser, c.browser_version, c.os, c.device, c.created_at
+		FROM click c
+		JOIN url u ON u.id = c.url_id
+		WHERE `+urls+` AND c.created_at >= ? AND c.created_at < ? AND `+classes+`
+		ORDER BY c.created_at, c.id`, args...)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c models.Click
+		err := rows.Scan(&c.ID, &c.Domain, &c.Alias, &c.VariantID, &c.VisitorID, &c.Class,
+			&c.Referrer, &c.Browser, &c.BrowserVersion, &c.OS, &c.Device, &c.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("%s: scan row: %w", op, err)
+		}
+
+		if err := fn(c); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("%s: iterate rows: %w", op, err)
+	}
+
+	return nil
+}
+
+// ExportClickCounts streams the number of clicks of the links selected by
+// filter per hour or day (see models.PeriodHour and models.PeriodDay) to fn,
+// oldest first. Counts are read from the rollup tables plus the clicks not
+// rolled up yet, so hourly counts reach back as far as hourly rollups are
+// kept. Iteration stops at the first error returned by fn.
+func (s *Storage) ExportClickCounts(
+	ctx context.Context, filter models.StatsFilter, period string, fn func(models.ClickCount) error,
+) error {
+	const op = "storage.sqlite.ExportClickCounts"
+
+	var (
+		rollup, rollupPeriod, clickPeriod, periodFormat string
+		rollupFrom, rollupTo                            string
+	)
+	from, to := dayRange(filter)
+	switch period {
+	case models.PeriodHour:
+		rollup, rollupPeriod, clickPeriod = "click_hourly", "hour", "strftime('%Y-%m-%d %H:00:00', created_at)"
+		periodFormat = sqliteTimeFormat
+		rollupFrom, rollupTo = from.Format(sqliteTimeFormat), to.Format(sqliteTimeFormat)
+	case models.PeriodDay:
+		rollup, rollupPeriod, clickPeriod = "click_daily", "day", "date(created_at)"
+		periodFormat = dayFormat
+		rollupFrom, rollupTo = from.Format(dayFormat), to.Format(dayFormat)
+	default:
+		return fmt.Errorf("%s: unknown period %q", op, period)
+	}
+
+	urls, urlArg, err := s.linkScope(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	_, classes, classArgs := trafficScope(filter.Traffic)
+
+	args := append([]any{urlArg, rollupFrom, rollupTo}, classArgs...)
+	args = append(args, urlArg, from.Format(sqliteTimeFormat), to.Format(sqliteTimeFormat))
+	args = append(args, classArgs...)
+	rows, err := s.db.QueryContext(ctx, `SELECT u.domain, u.alias, p.period, p.class, p.variant_id, SUM(p.clicks) FROM (
+			SELECT url_id, `+rollupPeriod+` AS period, class, variant_id, clicks FROM `+rollup+`
+			WHERE `+urls+` AND `+rollupPeriod+` >= ? AND `+rollupPeriod+` < ? AND `+classes+`
+			UNION ALL
+			SELECT url_id, `+clickPeriod+`, class, COALESCE(variant_id, 0), 1 FROM click
+			WHERE rolled_up = 0 AND `+urls+` AND created_at >= ? AND created_at < ? AND `+classes+`
+		) p
+		JOIN url u ON u.id = p.url_id
+		GROUP BY p.url_id, p.period, p.class, p.variant_id
+		ORDER BY p.period, u.domain, u.alias, p.class, p.variant_id`, args...)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			c  models.ClickCount
+			at string
+		)
+		if err := rows.Scan(&c.Domain, &c.Alias, &at, &c.Class, &c.VariantID, &c.Clicks); err != nil {
+			return fmt.Errorf("%s: scan row: %w", op, err)
+		}
+		c.Period, err = time.Parse(periodFormat, at)
+		if err != nil {
+			return fmt.Errorf("%s: parse period: %w", op, err)
+		}
+
+		if err := fn(c); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("%s: iterate rows: %w", op, err)
+	}
+
+	return nil
+}
+
+// dayRange returns the start of filter.From and the end of filter.To, both
+// days in UTC.
+func dayRange(filter models.StatsFilter) (time.Time, time.Time) {
+	from := filter.From.UTC().Truncate(24 * time.Hour)
+	to := filter.To.UTC().Truncate(24*time.Hour).AddDate(0, 0, 1)
+
+	return from, to
+}
@@ -0,0 +1,84 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"url-shortener/internal/models"
+)
+
+// nullHealth scans the left-joined url_health columns of selectURLs.
+type nullHealth struct {
+	statusCode  sql.NullInt64
+	err         sql.NullString
+	failures    sql.NullInt64
+	checkedAt   sql.NullTime
+	nextCheckAt sql.NullTime
+	brokenAt    sql.NullTime
+}
+
+func (h nullHealth) value() *models.LinkHealth {
+	if !h.checkedAt.Valid {
+		return nil
+	}
+
+	health := &models.LinkHealth{
+		StatusCode:  int(h.statusCode.Int64),
+		Error:       h.err.String,
+		Failures:    int(h.failures.Int64),
+		CheckedAt:   h.checkedAt.Time,
+		NextCheckAt: h.nextCheckAt.Time,
+	}
+	if h.brokenAt.Valid {
+		health.BrokenAt = &h.brokenAt.Time
+	}
+
+	return health
+}
+
+// GetDueHealthChecks returns up to limit links whose destination was never
+// checked or is due for a check at now, with the result of their last check.
+// Deleted and quarantined links are skipped.
+func (s *Storage) GetDueHealthChecks(ctx context.Context, now time.Time, limit int) ([]models.URL, error) {
+	const op = "storage.sqlite.GetDueHealthChecks"
+
+	rows, err := s.db.QueryContext(ctx, selectURLs+`
+		WHERE u.deleted_at IS NULL AND u.quarantined_at IS NULL AND (h.url_id IS NULL OR h.next_check_at <= ?)
+		ORDER BY h.next_check_at IS NOT NULL, h.next_check_at, u.id
+		LIMIT ?`, now.UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	defer rows.Close()
+
+	urls, err := scanURLs(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: scan row: %w", op, err)
+	}
+
+	return urls, nil
+}
+
+// SaveLinkHealth stores the result of a destination check.
+func (s *Storage) SaveLinkHealth(ctx context.Context, urlID int64, health models.LinkHealth) error {
+	const op = "storage.sqlite.SaveLinkHealth"
+
+	var brokenAt sql.NullTime
+	if health.BrokenAt != nil {
+		brokenAt = sql.NullTime{Time: health.BrokenAt.UTC(), Valid: true}
+	}
+
+	_, err := s.db.ExecContext(ctx, `INSERT INTO url_health(url_id, status_code, error, failures, checked_at, next_check_at, broken_at)
+		VALUES(?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(url_id) DO UPDATE SET status_code = excluded.status_code, error = excluded.error,
+			failures = excluded.failures, checked_at = excluded.checked_at, next_check_at = excluded.next_check_at,
+			broken_at = excluded.broken_at`,
+		urlID, health.StatusCode, health.Error, health.Failures, health.CheckedAt.UTC(), health.NextCheckAt.UTC(), brokenAt)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+// SaveIdempotencyKey claims the key of the user for a request in flight. A
+// key saved before expiredBefore is replaced, a live one fails with
+// storage.ErrIdempotencyKeyExists.
+func (s *Storage) SaveIdempotencyKey(ctx context.Context, key models.IdempotencyKey, expiredBefore time.Time) error {
+	const op = "storage.sqlite.SaveIdempotencyKey"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM idempotency_key WHERE user_id = ? AND key = ? AND created_at < ?",
+		key.UserID, key.Key, expiredBefore.UTC().Format(sqliteTimeFormat))
+	if err != nil {
+		return fmt.Errorf("%s: delete expired key: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO idempotency_key(user_id, key, request_hash) VALUES(?, ?, ?)",
+		key.UserID, key.Key, key.RequestHash)
+	if err != nil {
+		if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey {
+			return fmt.Errorf("%s: %w", op, storage.ErrIdempotencyKeyExists)
+		}
+
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return nil
+}
+
+// GetIdempotencyKey returns the key of the user with its stored response.
+func (s *Storage) GetIdempotencyKey(ctx context.Context, userID int64, key string) (models.IdempotencyKey, error) {
+	const op = "storage.sqlite.GetIdempotencyKey"
+
+	k := models.IdempotencyKey{UserID: userID, Key: key}
+	err := s.db.QueryRowContext(ctx, `SELECT request_hash, status, content_type, body, created_at
+		FROM idempotency_key WHERE user_id = ? AND key = ?`, userID, key).
+		Scan(&k.RequestHash, &k.Status, &k.ContentType, &k.Body, &k.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.IdempotencyKey{}, fmt.Errorf("%s: %w", op, storage.ErrIdempotencyKeyNotFound)
+	}
+	if err != nil {
+		return models.IdempotencyKey{}, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	return k, nil
+}
+
+// CompleteIdempotencyKey stores the response of the request sent with the key.
+func (s *Storage) CompleteIdempotencyKey(ctx context.Context, key models.IdempotencyKey) error {
+	const op = "storage.sqlite.CompleteIdempotencyKey"
+
+	res, err := s.db.ExecContext(ctx,
+		"UPDATE idempotency_key SET status = ?, content_type = ?, body = ? WHERE user_id = ? AND key = ?",
+		key.Status, key.ContentType, key.Body, key.UserID, key.Key)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrIdempotencyKeyNotFound)
+	}
+
+	return nil
+}
+
+// DeleteIdempotencyKey releases the key so the request can be retried.
+func (s *Storage) DeleteIdempotencyKey(ctx context.Context, userID int64, key string) error {
+	const op = "storage.sqlite.DeleteIdempotencyKey"
+
+	_, err := s.db.ExecContext(ctx, "DELETE FROM idempotency_key WHERE user_id = ? AND key = ?", userID, key)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	return nil
+}
+
+// PruneIdempotencyKeys deletes keys saved before the given time and returns
+// how many were deleted.
+func (s *Storage) PruneIdempotencyKeys(ctx context.Context, before time.Time) (int64, error) {
+	const op = "storage.sqlite.PruneIdempotencyKeys"
+
+	res, err := s.db.ExecContext(ctx, "DELETE FROM idempotency_key WHERE created_at < ?",
+		before.UTC().Format(sqliteTimeFormat))
+	if err != nil {
+		return 0, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	n, _ := res.RowsAffected()
+
+	return n, nil
+}
@@ -0,0 +1,94 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+// resetMetadata drops metadata fetched for the url and queues it for the fetcher.
+func resetMetadata(ctx context.Context, tx *sql.Tx, urlID int64) error {
+	_, err := tx.ExecContext(ctx, `INSERT INTO url_metadata(url_id) VALUES(?)
+		ON CONFLICT(url_id) DO UPDATE SET title = '', description = '', image = '', site_name = '', error = '',
+			fetched_at = NULL`, urlID)
+	if err != nil {
+		return fmt.Errorf("reset metadata: %w", err)
+	}
+
+	return nil
+}
+
+// GetPendingMetadata returns up to limit links waiting for page metadata.
+// Deleted and quarantined links are skipped.
+func (s *Storage) GetPendingMetadata(ctx context.Context, limit int) ([]models.URL, error) {
+	const op = "storage.sqlite.GetPendingMetadata"
+
+	rows, err := s.db.QueryContext(ctx, `SELECT u.id, u.url
+		FROM url_metadata m
+		JOIN url u ON u.id = m.url_id
+		WHERE m.fetched_at IS NULL AND u.deleted_at IS NULL AND u.quarantined_at IS NULL
+		ORDER BY m.url_id
+		LIMIT ?`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	defer rows.Close()
+
+	urls := make([]models.URL, 0)
+	for rows.Next() {
+		var url models.URL
+		if err := rows.Scan(&url.ID, &url.URL); err != nil {
+			return nil, fmt.Errorf("%s: scan row: %w", op, err)
+		}
+		urls = append(urls, url)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: iterate rows: %w", op, err)
+	}
+
+	return urls, nil
+}
+
+// SaveURLMetadata stores metadata fetched for the url.
+func (s *Storage) SaveURLMetadata(ctx context.Context, urlID int64, meta models.PageMetadata) error {
+	const op = "storage.sqlite.SaveURLMetadata"
+
+	_, err := s.db.ExecContext(ctx, `UPDATE url_metadata
+		SET title = ?, description = ?, image = ?, site_name = ?, error = ?, fetched_at = ?
+		WHERE url_id = ?`,
+		meta.Title, meta.Description, meta.Image, meta.SiteName, meta.Error, meta.FetchedAt, urlID)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	return nil
+}
+
+// GetURLPreview returns the link with its title, description and page
+// metadata for rendering a preview of it. Quarantined links are not previewed.
+func (s *Storage) GetURLPreview(ctx context.Context, domain string, alias string) (models.URL, error) {
+	const op = "storage.sqlite.GetURLPreview"
+
+	rows, err := s.db.QueryContext(ctx, selectURLs+`
+		WHERE u.domain = ? AND u.alias = ? AND u.deleted_at IS NULL`, domain, alias)
+	if err != nil {
+		return models.URL{}, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	defer rows.Close()
+
+	urls, err := scanURLs(rows)
+	if err != nil {
+		return models.URL{}, fmt.Errorf("%s: scan row: %w", op, err)
+	}
+	if len(urls) == 0 {
+		return models.URL{}, fmt.Errorf("%s: %w", op, storage.ErrURLNotFound)
+	}
+	if urls[0].QuarantinedAt != nil {
+		return models.URL{}, fmt.Errorf("%s: %w", op, storage.ErrURLQuarantined)
+	}
+
+	return urls[0], nil
+}
@@ -0,0 +1,118 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"url-shortener/internal/models"
+)
+
+// dimensionSQL maps breakdown dimensions to their value in the click table.
+var dimensionSQL = map[string]string{
+	models.DimensionReferrer:       "referrer",
+	models.DimensionBrowser:        "browser",
+	models.DimensionBrowserVersion: "CASE WHEN browser_version = '' THEN browser ELSE browser || ' ' || browser_version END",
+	models.DimensionOS:             "os",
+	models.DimensionDevice:         "device",
+}
+
+// RollupClicks adds up to limit clicks that are not counted in the rollup
+// tables yet to the hourly, daily and breakdown rollups and returns how many
+// were added. Clicks are marked in the same transaction, so a batch is
+// counted exactly once even if the job is interrupted and run again.
+func (s *Storage) RollupClicks(ctx context.Context, limit int) (int64, error) {
+	const op = "storage.sqlite.RollupClicks"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var lastID sql.NullInt64
+	err = tx.QueryRowContext(ctx, `SELECT MAX(id) FROM (
+		SELECT id FROM click WHERE rolled_up = 0 ORDER BY id LIMIT ?)`, limit).Scan(&lastID)
+	if err != nil {
+		return 0, fmt.Errorf("%s: find batch: %w", op, err)
+	}
+	if !lastID.Valid {
+		return 0, nil
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO click_hourly(url_id, hour, class, variant_id, clicks)
+		SELECT url_id, strftime('%Y-%m-%d %H:00:00', created_at), class, COALESCE(variant_id, 0), COUNT(*)
+		FROM click
+		WHERE rolled_up = 0 AND id <= ?
+		GROUP BY 1, 2, 3, 4
+		ON CONFLICT(url_id, hour, class, variant_id) DO UPDATE SET clicks = clicks + excluded.clicks`, lastID.Int64)
+	if err != nil {
+		return 0, fmt.Errorf("%s: roll up hours: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO click_daily(url_id, day, class, variant_id, clicks)
+		SELECT url_id, date(created_at), class, COALESCE(variant_id, 0), COUNT(*)
+		FROM click
+		WHERE rolled_up = 0 AND id <= ?
+		GROUP BY 1, 2, 3, 4
+		ON CONFLICT(url_id, day, class, variant_id) DO UPDATE SET clicks = clicks + excluded.clicks`, lastID.Int64)
+	if err != nil {
+		return 0, fmt.Errorf("%s: roll up days: %w", op, err)
+	}
+
+	for dimension, value := range dimensionSQL {
+		_, err := tx.ExecContext(ctx, `INSERT INTO click_breakdown(url_id, day, class, dimension, value, clicks)
+			SELECT url_id, date(created_at), class, ?, `+value+`, COUNT(*)
+			FROM click
+			WHERE rolled_up = 0 AND id <= ?
+			GROUP BY 1, 2, 3, 5
+			ON CONFLICT(url_id, dimension, day, class, value) DO UPDATE SET clicks = clicks + excluded.clicks`,
+			dimension, lastID.Int64)
+		if err != nil {
+			return 0, fmt.Errorf("%s: roll up %s: %w", op, dimension, err)
+		}
+	}
+
+	res, err := tx.ExecContext(ctx, "UPDATE click SET rolled_up = 1 WHERE rolled_up = 0 AND id <= ?", lastID.Int64)
+	if err != nil {
+		return 0, fmt.Errorf("%s: mark clicks: %w", op, err)
+	}
+	n, _ := res.RowsAffected()
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return n, nil
+}
+
+// PruneClicks deletes up to limit raw clicks recorded before the given time
+// that are counted in the rollups, and returns how many were deleted.
+func (s *Storage) PruneClicks(ctx context.Context, before time.Time, limit int) (int64, error) {
+	const op = "storage.sqlite.PruneClicks"
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM click WHERE id IN (
+		SELECT id FROM click WHERE rolled_up = 1 AND created_at < ? LIMIT ?)`,
+		before.UTC().Format(sqliteTimeFormat), limit)
+	if err != nil {
+		return 0, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	n, _ := res.RowsAffected()
+
+	return n, nil
+}
+
+// PruneHourlyClicks deletes hourly rollups of hours before the given time.
+// Daily rollups are kept.
+func (s *Storage) PruneHourlyClicks(ctx context.Context, before time.Time) (int64, error) {
+	const op = "storage.sqlite.PruneHourlyClicks"
+
+	res, err := s.db.ExecContext(ctx, "DELETE FROM click_hourly WHERE hour < ?", before.UTC().Format(sqliteTimeFormat))
+	if err != nil {
+		return 0, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	n, _ := res.RowsAffected()
+
+	return n, nil
+}
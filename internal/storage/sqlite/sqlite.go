@@ -5,9 +5,13 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/mattn/go-sqlite3"
 
+	"url-shortener/internal/lib/audit"
+	"url-shortener/internal/lib/urlnorm"
 	"url-shortener/internal/models"
 	"url-shortener/internal/storage"
 )
@@ -19,7 +23,7 @@ type Storage struct {
 func New(storagePath string) (*Storage, error) {
 	const op = "storage.sqlite.New"
 
-	db, err := sql.Open("sqlite3", fmt.Sprintf("%s?_journal_mode=WAL&_busy_timeout=5000", storagePath))
+	db, err := sql.Open("sqlite3", fmt.Sprintf("%s?_journal_mode=WAL&_busy_timeout=5000&_foreign_keys=on", storagePath))
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
@@ -27,15 +31,27 @@ func New(storagePath string) (*Storage, error) {
 	return &Storage{db: db}, nil
 }
 
-func (s *Storage) SaveURL(ctx context.Context, urlToSave string, alias string, userID int64) (int64, error) {
+// SaveURL saves the url under alias on the given domain with its tags and folder.
+// A non-empty domain must be a verified custom domain owned by userID, it is
+// checked by the insert so the domain can't change hands in between.
+func (s *Storage) SaveURL(
+	ctx context.Context, urlToSave string, domain string, alias string, userID int64, attrs models.URLAttrs,
+) (int64, error) {
 	const op = "storage.sqlite.SaveURL"
 
-	stmt, err := s.db.Prepare("INSERT INTO url(url, alias, user_id) VALUES(?, ?, ?)")
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return 0, fmt.Errorf("%s: %w", op, err)
+		return 0, fmt.Errorf("%s: begin transaction: %w", op, err)
 	}
+	defer tx.Rollback()
 
-	res, err := stmt.ExecContext(ctx, urlToSave, alias, userID)
+	// the domain is checked by the insert itself, a separate read could go
+	// stale before the write
+	res, err := tx.ExecContext(ctx, `INSERT INTO url(url, url_hash, domain, alias, user_id, title, description, notes)
+		SELECT ?, ?, ?, ?, ?, ?, ?, ?
+		WHERE `+usableDomain,
+		urlToSave, urlnorm.Hash(urlToSave), domain, alias, userID, attrs.Title, attrs.Description, attrs.Notes,
+		domain, domain, userID)
 	if err != nil {
 		if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
 			return 0, fmt.Errorf("%s: %w", op, storage.ErrURLExists)
@@ -44,25 +60,80 @@ func (s *Storage) SaveURL(ctx context.Context, urlToSave string, alias string, u
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: rows affected: %w", op, err)
+	}
+	if n == 0 {
+		if err := checkDomain(ctx, tx, domain, userID); err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+
+		return 0, fmt.Errorf("%s: url not inserted", op)
+	}
+
 	id, err := res.LastInsertId()
 	if err != nil {
 		return 0, fmt.Errorf("%s: failed to get last insert id: %w", op, err)
 	}
 
+	if err := initURL(ctx, tx, id, urlToSave, domain, alias, userID, attrs); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
 	return id, nil
 }
 
-func (s *Storage) GetURL(ctx context.Context, alias string) (string, error) {
+// initURL sets tags, folder and empty metadata of the url just inserted as id
+// and records its creation in the audit log.
+func initURL(
+	ctx context.Context, tx *sql.Tx, id int64, urlToSave string, domain string, alias string, userID int64,
+	attrs models.URLAttrs,
+) error {
+	if len(attrs.Tags) > 0 {
+		if err := setTags(ctx, tx, id, attrs.Tags); err != nil {
+			return err
+		}
+	}
+	if attrs.Folder != "" {
+		if err := setFolder(ctx, tx, id, attrs.Folder); err != nil {
+			return err
+		}
+	}
+
+	if err := resetMetadata(ctx, tx, id); err != nil {
+		return err
+	}
+
+	created := urlState{
+		URL:         urlToSave,
+		UserID:      userID,
+		Tags:        sortedCopy(attrs.Tags),
+		Folder:      attrs.Folder,
+		Title:       attrs.Title,
+		Description: attrs.Description,
+		Notes:       attrs.Notes,
+	}
+
+	return writeAudit(ctx, tx, audit.ActionURLCreate, domain, alias, nil, created)
+}
+
+func (s *Storage) GetURL(ctx context.Context, domain string, alias string) (string, error) {
 	const op = "storage.sqlite.GetURL"
 
-	stmt, err := s.db.Prepare("SELECT url FROM url WHERE alias = ?")
+	stmt, err := s.db.Prepare("SELECT url, quarantined_at IS NOT NULL FROM url WHERE domain = ? AND alias = ? AND deleted_at IS NULL")
 	if err != nil {
 		return "", fmt.Errorf("%s: prepare statement: %w", op, err)
 	}
 
 	var resURL string
+	var quarantined bool
 
-	err = stmt.QueryRowContext(ctx, alias).Scan(&resURL)
+	err = stmt.QueryRowContext(ctx, domain, alias).Scan(&resURL, &quarantined)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return "", storage.ErrURLNotFound
@@ -70,6 +141,9 @@ func (s *Storage) GetURL(ctx context.Context, alias string) (string, error) {
 
 		return "", fmt.Errorf("%s: execute statement: %w", op, err)
 	}
+	if quarantined {
+		return "", storage.ErrURLQuarantined
+	}
 
 	return resURL, nil
 }
@@ -77,41 +151,374 @@ func (s *Storage) GetURL(ctx context.Context, alias string) (string, error) {
 // TODO: implement method
 // func (s *Storage) DeleteURL(alias string) error
 
-func(s *Storage) GetUserURLs(ctx context.Context, userID int64) ([]models.URL, error) {
+// GetUserURLs returns urls of the user matching the filter, recently updated first.
+func (s *Storage) GetUserURLs(ctx context.Context, userID int64, filter models.URLFilter) ([]models.URL, error) {
 	const op = "storage.sqlite.GetUserURLs"
 
-	stmt, err := s.db.Prepare("SELECT id, url, alias, user_id, created_at, updated_at FROM url WHERE user_id = ? ORDER BY updated_at DESC")
+	filter.UserID = userID
+	where, args := urlFilterSQL(filter)
+
+	rows, err := s.db.QueryContext(ctx, selectURLs+" WHERE u.deleted_at IS NULL"+where+" ORDER BY u.updated_at DESC", args...)
 	if err != nil {
-		return nil, fmt.Errorf("%s: prepare statement: %w", op, err)
+		return nil, fmt.Errorf("%s: execute statement: %w", op, err)
 	}
-	defer stmt.Close()
+	defer rows.Close()
+
+	// сделать пагинацию
+	urls, err := scanURLs(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: scan row: %w", op, err)
+	}
+
+	if err := loadTags(ctx, s.db, urls); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return urls, nil
+}
+
+// DeleteURL moves the url to the trash. It stops redirecting but the alias stays
+// taken until PurgeDeletedURLs removes it.
+func (s *Storage) DeleteURL(ctx context.Context, domain string, alias string, userID int64, isAdmin bool) error {
+	const op = "storage.sqlite.DeleteURL"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer tx.Rollback()
 
-	rows, err := stmt.QueryContext(ctx, userID)
+	var before urlState
+	err = tx.QueryRowContext(ctx, `SELECT url, user_id, quarantined_at IS NOT NULL FROM url
+		WHERE domain = ? AND alias = ? AND deleted_at IS NULL`, domain, alias).
+		Scan(&before.URL, &before.UserID, &before.Quarantined)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, storage.ErrUserURLsNotFound
+			return storage.ErrURLNotFound
 		}
+		return fmt.Errorf("%s: query row: %w", op, err)
+	}
+	if !isAdmin && before.UserID != userID {
+		return storage.ErrURLNotOwned
+	}
 
+	_, err = tx.ExecContext(ctx, "UPDATE url SET deleted_at = CURRENT_TIMESTAMP WHERE domain = ? AND alias = ?", domain, alias)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	if err := writeAudit(ctx, tx, audit.ActionURLDelete, domain, alias, before, nil); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+        return fmt.Errorf("%s: commit transaction: %w", op, err)
+    }
+
+	return nil
+}
+
+// ownedURLID returns id of the url with given alias, checking that it belongs to userID.
+func ownedURLID(ctx context.Context, q querier, domain string, alias string, userID int64) (int64, error) {
+	var urlID, creatorUserID int64
+
+	err := q.QueryRowContext(ctx, "SELECT id, user_id FROM url WHERE domain = ? AND alias = ? AND deleted_at IS NULL",
+		domain, alias).Scan(&urlID, &creatorUserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, storage.ErrURLNotFound
+		}
+		return 0, err
+	}
+	if creatorUserID != userID {
+		return 0, storage.ErrURLNotOwned
+	}
+
+	return urlID, nil
+}
+
+type querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+const selectRules = `SELECT r.id, r.position, r.platform, r.language, r.country, r.time_from, r.time_to, r.target_url, r.created_at, r.updated_at
+	FROM url_rule r`
+
+func scanRules(rows *sql.Rows) ([]models.Rule, error) {
+	rules := make([]models.Rule, 0)
+	for rows.Next() {
+		var rule models.Rule
+		err := rows.Scan(
+			&rule.ID, &rule.Position, &rule.Platform, &rule.Language, &rule.Country,
+			&rule.TimeFrom, &rule.TimeTo, &rule.TargetURL, &rule.CreatedAt, &rule.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, rows.Err()
+}
+
+// GetURLRules returns targeting rules of the url ordered by position.
+func (s *Storage) GetURLRules(ctx context.Context, domain string, alias string) ([]models.Rule, error) {
+	const op = "storage.sqlite.GetURLRules"
+
+	rows, err := s.db.QueryContext(ctx, selectRules+`
+		JOIN url u ON u.id = r.url_id
+		WHERE u.domain = ? AND u.alias = ? AND u.deleted_at IS NULL
+		ORDER BY r.position, r.id`, domain, alias)
+	if err != nil {
 		return nil, fmt.Errorf("%s: execute statement: %w", op, err)
 	}
 	defer rows.Close()
 
-	// сделать пагинацию
-	var urls []models.URL = make([]models.URL, 0, 20)
-	for rows.Next() {
-		var url models.URL
-		err := rows.Scan(&url.ID, &url.URL, &url.Alias, &url.UserID, &url.CreatedAt, &url.UpdatedAt)
+	rules, err := scanRules(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: scan rows: %w", op, err)
+	}
+
+	return rules, nil
+}
+
+// GetUserURLRules is GetURLRules restricted to the url owner.
+func (s *Storage) GetUserURLRules(ctx context.Context, domain string, alias string, userID int64) ([]models.Rule, error) {
+	const op = "storage.sqlite.GetUserURLRules"
+
+	urlID, err := ownedURLID(ctx, s.db, domain, alias, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, selectRules+" WHERE r.url_id = ? ORDER BY r.position, r.id", urlID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	defer rows.Close()
+
+	rules, err := scanRules(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: scan rows: %w", op, err)
+	}
+
+	return rules, nil
+}
+
+// SaveURLRule adds a rule to the url. Rules with zero position are appended to the end.
+func (s *Storage) SaveURLRule(ctx context.Context, domain string, alias string, userID int64, rule models.Rule) (int64, error) {
+	const op = "storage.sqlite.SaveURLRule"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	urlID, err := ownedURLID(ctx, tx, domain, alias, userID)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if rule.Position == 0 {
+		err := tx.QueryRowContext(ctx, "SELECT COALESCE(MAX(position), 0) + 1 FROM url_rule WHERE url_id = ?", urlID).
+			Scan(&rule.Position)
 		if err != nil {
+			return 0, fmt.Errorf("%s: query position: %w", op, err)
+		}
+	}
+
+	res, err := tx.ExecContext(ctx, `INSERT INTO url_rule(url_id, position, platform, language, country, time_from, time_to, target_url)
+		VALUES(?, ?, ?, ?, ?, ?, ?, ?)`,
+		urlID, rule.Position, rule.Platform, rule.Language, rule.Country, rule.TimeFrom, rule.TimeTo, rule.TargetURL,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to get last insert id: %w", op, err)
+	}
+	rule.ID = id
+
+	if err := writeAudit(ctx, tx, audit.ActionRuleCreate, domain, alias, nil, newRuleState(rule)); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// getRule returns the rule of the url for audit snapshots.
+func getRule(ctx context.Context, q querier, urlID int64, ruleID int64) (models.Rule, error) {
+	var rule models.Rule
+
+	err := q.QueryRowContext(ctx, selectRules+" WHERE r.id = ? AND r.url_id = ?", ruleID, urlID).Scan(
+		&rule.ID, &rule.Position, &rule.Platform, &rule.Language, &rule.Country,
+		&rule.TimeFrom, &rule.TimeTo, &rule.TargetURL, &rule.CreatedAt, &rule.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Rule{}, storage.ErrRuleNotFound
+	}
+
+	return rule, err
+}
+
+// UpdateURLRule replaces conditions, target and position of an existing rule.
+func (s *Storage) UpdateURLRule(ctx context.Context, domain string, alias string, userID int64, rule models.Rule) error {
+	const op = "storage.sqlite.UpdateURLRule"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	urlID, err := ownedURLID(ctx, tx, domain, alias, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	before, err := getRule(ctx, tx, urlID, rule.ID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE url_rule
+		SET position = ?, platform = ?, language = ?, country = ?, time_from = ?, time_to = ?, target_url = ?,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND url_id = ?`,
+		rule.Position, rule.Platform, rule.Language, rule.Country, rule.TimeFrom, rule.TimeTo, rule.TargetURL,
+		rule.ID, urlID,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	err = writeAudit(ctx, tx, audit.ActionRuleUpdate, domain, alias, newRuleState(before), newRuleState(rule))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) DeleteURLRule(ctx context.Context, domain string, alias string, userID int64, ruleID int64) error {
+	const op = "storage.sqlite.DeleteURLRule"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	urlID, err := ownedURLID(ctx, tx, domain, alias, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	before, err := getRule(ctx, tx, urlID, ruleID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM url_rule WHERE id = ? AND url_id = ?", ruleID, urlID); err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	if err := writeAudit(ctx, tx, audit.ActionRuleDelete, domain, alias, newRuleState(before), nil); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return nil
+}
+
+// GetURLVariants returns split destinations of the url without click counters.
+func (s *Storage) GetURLVariants(ctx context.Context, domain string, alias string) ([]models.Variant, error) {
+	const op = "storage.sqlite.GetURLVariants"
+
+	rows, err := s.db.QueryContext(ctx, `SELECT v.id, v.url, v.weight, v.created_at, v.updated_at
+		FROM url_variant v
+		JOIN url u ON u.id = v.url_id
+		WHERE u.domain = ? AND u.alias = ? AND u.deleted_at IS NULL
+		ORDER BY v.id`, domain, alias)
+	if err != nil {
+		return nil, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	defer rows.Close()
+
+	variants := make([]models.Variant, 0)
+	for rows.Next() {
+		var v models.Variant
+		if err := rows.Scan(&v.ID, &v.URL, &v.Weight, &v.CreatedAt, &v.UpdatedAt); err != nil {
 			return nil, fmt.Errorf("%s: scan row: %w", op, err)
 		}
-		urls = append(urls, url)
+		variants = append(variants, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: iterate rows: %w", op, err)
 	}
 
-	return urls, nil
+	return variants, nil
 }
 
-func (s *Storage) DeleteURL(ctx context.Context, alias string, userID int64, isAdmin bool) error {
-	const op = "storage.sqlite.DeleteURL"
+// GetUserURLVariants returns split destinations of the url with per-variant
+// click counts. Only human clicks are counted.
+func (s *Storage) GetUserURLVariants(ctx context.Context, domain string, alias string, userID int64) ([]models.Variant, error) {
+	const op = "storage.sqlite.GetUserURLVariants"
+
+	urlID, err := ownedURLID(ctx, s.db, domain, alias, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT v.id, v.url, v.weight,
+			(SELECT COALESCE(SUM(d.clicks), 0) FROM click_daily d
+				WHERE d.url_id = v.url_id AND d.variant_id = v.id AND d.class = 'human') +
+			(SELECT COUNT(*) FROM click c WHERE c.variant_id = v.id AND c.class = 'human' AND c.rolled_up = 0),
+			v.created_at, v.updated_at
+		FROM url_variant v
+		WHERE v.url_id = ?
+		ORDER BY v.id`, urlID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	defer rows.Close()
+
+	variants := make([]models.Variant, 0)
+	for rows.Next() {
+		var v models.Variant
+		if err := rows.Scan(&v.ID, &v.URL, &v.Weight, &v.Clicks, &v.CreatedAt, &v.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("%s: scan row: %w", op, err)
+		}
+		variants = append(variants, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: iterate rows: %w", op, err)
+	}
+
+	return variants, nil
+}
+
+// SetURLVariants replaces the split destinations of the url. Variants with an ID
+// are updated in place so their click history is kept, the rest are inserted and
+// variants missing from the list are removed.
+func (s *Storage) SetURLVariants(
+	ctx context.Context, domain string, alias string, userID int64, variants []models.Variant,
+) error {
+	const op = "storage.sqlite.SetURLVariants"
 
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -119,28 +526,472 @@ func (s *Storage) DeleteURL(ctx context.Context, alias string, userID int64, isA
 	}
 	defer tx.Rollback()
 
-	
-	if !isAdmin {
-		var creatorUserID int64
-		err := tx.QueryRowContext(ctx, "SELECT user_id FROM url WHERE alias = ?", alias).Scan(&creatorUserID)
-		if err != nil {
-			if errors.Is(err, sql.ErrNoRows) {
-				return storage.ErrURLNotFound
+	urlID, err := ownedURLID(ctx, tx, domain, alias, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	before, err := variantStates(ctx, tx, urlID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	after := make([]variantState, 0, len(variants))
+	keep := make([]any, 0, len(variants)+1)
+	keep = append(keep, urlID)
+
+	for _, v := range variants {
+		if v.ID == 0 {
+			res, err := tx.ExecContext(ctx, "INSERT INTO url_variant(url_id, url, weight) VALUES(?, ?, ?)", urlID, v.URL, v.Weight)
+			if err != nil {
+				return fmt.Errorf("%s: insert variant: %w", op, err)
+			}
+			id, err := res.LastInsertId()
+			if err != nil {
+				return fmt.Errorf("%s: failed to get last insert id: %w", op, err)
 			}
-			return fmt.Errorf("%s: query row: %w", op, err)
+			keep = append(keep, id)
+			after = append(after, variantState{ID: id, URL: v.URL, Weight: v.Weight})
+			continue
+		}
+
+		res, err := tx.ExecContext(ctx, `UPDATE url_variant SET url = ?, weight = ?, updated_at = CURRENT_TIMESTAMP
+			WHERE id = ? AND url_id = ?`, v.URL, v.Weight, v.ID, urlID)
+		if err != nil {
+			return fmt.Errorf("%s: update variant: %w", op, err)
 		}
-		if creatorUserID != userID {
-			return storage.ErrURLNotOwned
+		if n, _ := res.RowsAffected(); n == 0 {
+			return fmt.Errorf("%s: %w", op, storage.ErrVariantNotFound)
 		}
+		keep = append(keep, v.ID)
+		after = append(after, variantState{ID: v.ID, URL: v.URL, Weight: v.Weight})
+	}
+
+	query := "DELETE FROM url_variant WHERE url_id = ?"
+	if len(keep) > 1 {
+		query += " AND id NOT IN (?" + strings.Repeat(", ?", len(keep)-2) + ")"
+	}
+	if _, err := tx.ExecContext(ctx, query, keep...); err != nil {
+		return fmt.Errorf("%s: delete variants: %w", op, err)
+	}
+
+	if err := writeAudit(ctx, tx, audit.ActionVariantsSet, domain, alias, before, after); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return nil
+}
+
+// variantStates returns the current split destinations of the url for audit snapshots.
+func variantStates(ctx context.Context, tx *sql.Tx, urlID int64) ([]variantState, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT id, url, weight FROM url_variant WHERE url_id = ? ORDER BY id", urlID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	states := make([]variantState, 0)
+	for rows.Next() {
+		var v variantState
+		if err := rows.Scan(&v.ID, &v.URL, &v.Weight); err != nil {
+			return nil, err
+		}
+		states = append(states, v)
+	}
+
+	return states, rows.Err()
+}
+
+func (s *Storage) RecordClick(ctx context.Context, click models.Click) error {
+	const op = "storage.sqlite.RecordClick"
+
+	var variantID sql.NullInt64
+	if click.VariantID != 0 {
+		variantID = sql.NullInt64{Int64: click.VariantID, Valid: true}
 	}
-	
-	if _, err = tx.ExecContext(ctx, "DELETE FROM url WHERE alias = ?", alias); err != nil {
+
+	class := click.Class
+	if class == "" {
+		class = "human"
+	}
+
+	res, err := s.db.ExecContext(ctx, `INSERT INTO click(url_id, variant_id, visitor_id, class,
+			referrer, browser, browser_version, os, device)
+		SELECT id, ?, ?, ?, ?, ?, ?, ?, ? FROM url WHERE domain = ? AND alias = ? AND deleted_at IS NULL`,
+		variantID, click.VisitorID, class, click.Referrer, click.Browser, click.BrowserVersion, click.OS, click.Device,
+		click.Domain, click.Alias)
+	if err != nil {
 		return fmt.Errorf("%s: execute statement: %w", op, err)
 	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrURLNotFound)
+	}
+
+	return nil
+}
+
+// usableDomain is the condition of the url insert that its domain, bound
+// twice followed by the user id, is the default domain or a verified domain
+// of the user.
+const usableDomain = `(? = '' OR EXISTS (SELECT 1 FROM domain
+	WHERE hostname = ? AND user_id = ? AND verified_at IS NOT NULL))`
+
+// checkDomain makes sure links can be created on the domain by userID. It
+// explains why an insert guarded by usableDomain stored nothing: the insert
+// holds the write lock, so the domain still reads as it was checked.
+func checkDomain(ctx context.Context, q querier, hostname string, userID int64) error {
+	var (
+		ownerID  int64
+		verified bool
+	)
+
+	err := q.QueryRowContext(ctx, "SELECT user_id, verified_at IS NOT NULL FROM domain WHERE hostname = ?", hostname).
+		Scan(&ownerID, &verified)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return storage.ErrDomainNotFound
+	case err != nil:
+		return fmt.Errorf("get domain: %w", err)
+	case ownerID != userID:
+		return storage.ErrDomainNotOwned
+	case !verified:
+		return storage.ErrDomainNotVerified
+	}
+
+	return nil
+}
+
+// SaveDomain registers the hostname for the user. An unverified claim of
+// another user made before staleBefore is replaced, so nobody can hold a
+// hostname they can't verify. Any other existing claim fails with
+// storage.ErrDomainExists.
+func (s *Storage) SaveDomain(
+	ctx context.Context, hostname string, userID int64, token string, staleBefore time.Time,
+) (int64, error) {
+	const op = "storage.sqlite.SaveDomain"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var staleUserID int64
+	err = tx.QueryRowContext(ctx, `DELETE FROM domain
+		WHERE hostname = ? AND user_id != ? AND verified_at IS NULL AND created_at < ?
+		RETURNING user_id`, hostname, userID, staleBefore.UTC().Format(sqliteTimeFormat)).Scan(&staleUserID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("%s: delete stale claim: %w", op, err)
+	}
+	if err == nil {
+		err = writeAudit(ctx, tx, audit.ActionDomainDelete, hostname, "",
+			domainState{Hostname: hostname, UserID: staleUserID}, nil)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	res, err := tx.ExecContext(ctx, "INSERT INTO domain(hostname, user_id, verification_token) VALUES(?, ?, ?)",
+		hostname, userID, token)
+	if err != nil {
+		if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return 0, fmt.Errorf("%s: %w", op, storage.ErrDomainExists)
+		}
+
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to get last insert id: %w", op, err)
+	}
+
+	err = writeAudit(ctx, tx, audit.ActionDomainCreate, hostname, "", nil, domainState{Hostname: hostname, UserID: userID})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
 
 	if err := tx.Commit(); err != nil {
-        return fmt.Errorf("%s: commit transaction: %w", op, err)
-    }
+		return 0, fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) GetDomain(ctx context.Context, hostname string) (models.Domain, error) {
+	const op = "storage.sqlite.GetDomain"
+
+	var d models.Domain
+
+	err := s.db.QueryRowContext(ctx, `SELECT id, hostname, user_id, verification_token, verified_at, created_at
+		FROM domain WHERE hostname = ?`, hostname).
+		Scan(&d.ID, &d.Hostname, &d.UserID, &d.VerificationToken, &d.VerifiedAt, &d.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Domain{}, storage.ErrDomainNotFound
+		}
+
+		return models.Domain{}, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	return d, nil
+}
+
+func (s *Storage) GetUserDomains(ctx context.Context, userID int64) ([]models.Domain, error) {
+	const op = "storage.sqlite.GetUserDomains"
+
+	rows, err := s.db.QueryContext(ctx, `SELECT id, hostname, user_id, verification_token, verified_at, created_at
+		FROM domain WHERE user_id = ? ORDER BY hostname`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	defer rows.Close()
+
+	domains := make([]models.Domain, 0)
+	for rows.Next() {
+		var d models.Domain
+		if err := rows.Scan(&d.ID, &d.Hostname, &d.UserID, &d.VerificationToken, &d.VerifiedAt, &d.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s: scan row: %w", op, err)
+		}
+		domains = append(domains, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: iterate rows: %w", op, err)
+	}
+
+	return domains, nil
+}
+
+func (s *Storage) MarkDomainVerified(ctx context.Context, hostname string, userID int64) error {
+	const op = "storage.sqlite.MarkDomainVerified"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var verified bool
+	err = tx.QueryRowContext(ctx, "SELECT verified_at IS NOT NULL FROM domain WHERE hostname = ? AND user_id = ?",
+		hostname, userID).Scan(&verified)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: %w", op, storage.ErrDomainNotFound)
+		}
+		return fmt.Errorf("%s: query row: %w", op, err)
+	}
+	if verified {
+		return nil
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE domain SET verified_at = CURRENT_TIMESTAMP WHERE hostname = ? AND user_id = ?",
+		hostname, userID)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	err = writeAudit(ctx, tx, audit.ActionDomainVerify, hostname, "",
+		domainState{Hostname: hostname, UserID: userID}, domainState{Hostname: hostname, UserID: userID, Verified: true})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// DeleteDomain removes a custom domain of the user. Domains that still have links can't be removed.
+func (s *Storage) DeleteDomain(ctx context.Context, hostname string, userID int64) error {
+	const op = "storage.sqlite.DeleteDomain"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var links int
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM url WHERE domain = ?", hostname).Scan(&links); err != nil {
+		return fmt.Errorf("%s: count links: %w", op, err)
+	}
+	if links > 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrDomainInUse)
+	}
+
+	var verified bool
+	err = tx.QueryRowContext(ctx, "SELECT verified_at IS NOT NULL FROM domain WHERE hostname = ? AND user_id = ?",
+		hostname, userID).Scan(&verified)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: %w", op, storage.ErrDomainNotFound)
+		}
+		return fmt.Errorf("%s: query row: %w", op, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM domain WHERE hostname = ? AND user_id = ?", hostname, userID); err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	err = writeAudit(ctx, tx, audit.ActionDomainDelete, hostname, "",
+		domainState{Hostname: hostname, UserID: userID, Verified: verified}, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return nil
+}
+
+// SaveReport files an abuse report against the url. Quarantined urls can still be reported.
+func (s *Storage) SaveReport(ctx context.Context, domain string, alias string, report models.Report) (int64, error) {
+	const op = "storage.sqlite.SaveReport"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `INSERT INTO report(url_id, reason, details, reporter_email, reporter_ip)
+		SELECT id, ?, ?, ?, ? FROM url WHERE domain = ? AND alias = ? AND deleted_at IS NULL`,
+		report.Reason, report.Details, report.ReporterEmail, report.ReporterIP, domain, alias,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return 0, fmt.Errorf("%s: %w", op, storage.ErrURLNotFound)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to get last insert id: %w", op, err)
+	}
+
+	after := map[string]any{"id": id, "reason": report.Reason}
+	if err := writeAudit(ctx, tx, audit.ActionReportCreate, domain, alias, nil, after); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// GetReports returns abuse reports with the given status, all reports if status is empty.
+func (s *Storage) GetReports(ctx context.Context, status string) ([]models.Report, error) {
+	const op = "storage.sqlite.GetReports"
+
+	rows, err := s.db.QueryContext(ctx, `SELECT r.id, u.domain, u.alias, u.url, r.reason, r.details, r.reporter_email,
+			r.reporter_ip, r.status, u.quarantined_at IS NOT NULL, r.created_at, r.resolved_at
+		FROM report r
+		JOIN url u ON u.id = r.url_id
+		WHERE ? = '' OR r.status = ?
+		ORDER BY r.created_at DESC, r.id DESC`, status, status)
+	if err != nil {
+		return nil, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	defer rows.Close()
+
+	reports := make([]models.Report, 0)
+	for rows.Next() {
+		var r models.Report
+		err := rows.Scan(
+			&r.ID, &r.Domain, &r.Alias, &r.URL, &r.Reason, &r.Details, &r.ReporterEmail,
+			&r.ReporterIP, &r.Status, &r.Quarantined, &r.CreatedAt, &r.ResolvedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("%s: scan row: %w", op, err)
+		}
+		reports = append(reports, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: iterate rows: %w", op, err)
+	}
+
+	return reports, nil
+}
+
+// SetURLQuarantined quarantines or restores the url. Either way the open
+// reports against it are resolved.
+func (s *Storage) SetURLQuarantined(ctx context.Context, domain string, alias string, quarantined bool) error {
+	const op = "storage.sqlite.SetURLQuarantined"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var urlID int64
+	var before urlState
+	err = tx.QueryRowContext(ctx, `SELECT id, url, user_id, quarantined_at IS NOT NULL FROM url
+		WHERE domain = ? AND alias = ? AND deleted_at IS NULL`,
+		domain, alias).Scan(&urlID, &before.URL, &before.UserID, &before.Quarantined)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: %w", op, storage.ErrURLNotFound)
+		}
+		return fmt.Errorf("%s: query row: %w", op, err)
+	}
+
+	query := "UPDATE url SET quarantined_at = NULL WHERE id = ?"
+	if quarantined {
+		query = "UPDATE url SET quarantined_at = COALESCE(quarantined_at, CURRENT_TIMESTAMP) WHERE id = ?"
+	}
+	if _, err := tx.ExecContext(ctx, query, urlID); err != nil {
+		return fmt.Errorf("%s: update url: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE report SET status = ?, resolved_at = CURRENT_TIMESTAMP
+		WHERE url_id = ? AND status = ?`, models.ReportStatusResolved, urlID, models.ReportStatusOpen)
+	if err != nil {
+		return fmt.Errorf("%s: resolve reports: %w", op, err)
+	}
+
+	action := audit.ActionURLRestore
+	if quarantined {
+		action = audit.ActionURLQuarantine
+	}
+	after := before
+	after.Quarantined = quarantined
+	if err := writeAudit(ctx, tx, action, domain, alias, before, after); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return nil
+}
+
+// GetURLOwner returns the id of the user owning the url.
+func (s *Storage) GetURLOwner(ctx context.Context, domain string, alias string) (int64, error) {
+	const op = "storage.sqlite.GetURLOwner"
+
+	var userID int64
+
+	err := s.db.QueryRowContext(ctx, "SELECT user_id FROM url WHERE domain = ? AND alias = ? AND deleted_at IS NULL",
+		domain, alias).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("%s: %w", op, storage.ErrURLNotFound)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	return userID, nil
+}
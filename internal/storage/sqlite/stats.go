@@ -0,0 +1,285 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"url-shortener/internal/lib/hll"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+const dayFormat = "2006-01-02"
+
+// MergeVisitorSketches merges the sketches into the stored ones of the same
+// link, day and class. Sketches of links that no longer exist are dropped.
+func (s *Storage) MergeVisitorSketches(ctx context.Context, sketches []models.VisitorSketch) error {
+	const op = "storage.sqlite.MergeVisitorSketches"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	for _, vs := range sketches {
+		var urlID int64
+		err := tx.QueryRowContext(ctx, "SELECT id FROM url WHERE domain = ? AND alias = ?", vs.Domain, vs.Alias).Scan(&urlID)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("%s: get url id: %w", op, err)
+		}
+
+		sketch, err := hll.Parse(vs.Sketch)
+		if err != nil {
+			return fmt.Errorf("%s: parse sketch: %w", op, err)
+		}
+
+		day := vs.Day.UTC().Format(dayFormat)
+		class := vs.Class
+		if class == "" {
+			class = "human"
+		}
+
+		var stored []byte
+		err = tx.QueryRowContext(ctx, "SELECT sketch FROM visitor_sketch WHERE url_id = ? AND day = ? AND class = ?",
+			urlID, day, class).Scan(&stored)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+		case err != nil:
+			return fmt.Errorf("%s: get stored sketch: %w", op, err)
+		default:
+			old, err := hll.Parse(stored)
+			if err != nil {
+				return fmt.Errorf("%s: parse stored sketch of url %d on %s (%s): %w", op, urlID, day, class, err)
+			}
+			sketch.Merge(old)
+		}
+
+		b, err := sketch.MarshalBinary()
+		if err != nil {
+			return fmt.Errorf("%s: encode sketch: %w", op, err)
+		}
+
+		_, err = tx.ExecContext(ctx, `INSERT INTO visitor_sketch(url_id, day, class, sketch) VALUES(?, ?, ?, ?)
+			ON CONFLICT(url_id, day, class) DO UPDATE SET sketch = excluded.sketch`, urlID, day, class, b)
+		if err != nil {
+			return fmt.Errorf("%s: save sketch: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return nil
+}
+
+// GetLinkStats returns per-day clicks and unique visitors of the links
+// selected by filter. Unique visitors of the whole range are estimated from
+// the merged daily sketches, so a visitor coming back on another day or to
+// another link of the user is counted once. Only human traffic is counted
+// unless filter.Traffic lists other classes. Clicks and breakdowns are read
+// from the rollup tables plus the clicks not rolled up yet, see RollupClicks.
+func (s *Storage) GetLinkStats(ctx context.Context, filter models.StatsFilter) (models.LinkStats, error) {
+	const op = "storage.sqlite.GetLinkStats"
+
+	urls, urlArg, err := s.linkScope(ctx, filter)
+	if err != nil {
+		return models.LinkStats{}, fmt.Errorf("%s: %w", op, err)
+	}
+	traffic, classes, classArgs := trafficScope(filter.Traffic)
+
+	from := filter.From.UTC().Truncate(24 * time.Hour)
+	to := filter.To.UTC().Truncate(24 * time.Hour)
+
+	stats := models.LinkStats{
+		From:                from.Format(dayFormat),
+		To:                  to.Format(dayFormat),
+		Traffic:             traffic,
+		UniqueVisitorsError: hll.RelativeError,
+	}
+
+	days := make(map[string]*models.DayStats)
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		stats.Days = append(stats.Days, models.DayStats{Day: d.Format(dayFormat)})
+	}
+	for i := range stats.Days {
+		days[stats.Days[i].Day] = &stats.Days[i]
+	}
+
+	args := append([]any{urlArg, stats.From, stats.To}, classArgs...)
+	args = append(args, urlArg, from.Format(sqliteTimeFormat), to.AddDate(0, 0, 1).Format(sqliteTimeFormat))
+	args = append(args, classArgs...)
+	rows, err := s.db.QueryContext(ctx, `SELECT day, SUM(clicks) FROM (
+			SELECT day, clicks FROM click_daily
+			WHERE `+urls+` AND day >= ? AND day <= ? AND `+classes+`
+			UNION ALL
+			SELECT date(created_at), 1 FROM click
+			WHERE rolled_up = 0 AND `+urls+` AND created_at >= ? AND created_at < ? AND `+classes+`
+		)
+		GROUP BY 1`, args...)
+	if err != nil {
+		return models.LinkStats{}, fmt.Errorf("%s: count clicks: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			day    string
+			clicks int64
+		)
+		if err := rows.Scan(&day, &clicks); err != nil {
+			return models.LinkStats{}, fmt.Errorf("%s: scan clicks: %w", op, err)
+		}
+		if d, ok := days[day]; ok {
+			d.Clicks = clicks
+			stats.Clicks += clicks
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return models.LinkStats{}, fmt.Errorf("%s: scan clicks: %w", op, err)
+	}
+
+	args = append([]any{urlArg, stats.From, stats.To}, classArgs...)
+	rows, err = s.db.QueryContext(ctx, `SELECT day, sketch FROM visitor_sketch
+		WHERE `+urls+` AND day >= ? AND day <= ? AND `+classes, args...)
+	if err != nil {
+		return models.LinkStats{}, fmt.Errorf("%s: get sketches: %w", op, err)
+	}
+	defer rows.Close()
+
+	total := hll.New()
+	perDay := make(map[string]*hll.Sketch)
+	for rows.Next() {
+		var (
+			day string
+			b   []byte
+		)
+		if err := rows.Scan(&day, &b); err != nil {
+			return models.LinkStats{}, fmt.Errorf("%s: scan sketch: %w", op, err)
+		}
+
+		sketch, err := hll.Parse(b)
+		if err != nil {
+			return models.LinkStats{}, fmt.Errorf("%s: parse sketch: %w", op, err)
+		}
+		total.Merge(sketch)
+		if perDay[day] == nil {
+			perDay[day] = hll.New()
+		}
+		perDay[day].Merge(sketch)
+	}
+	if err := rows.Err(); err != nil {
+		return models.LinkStats{}, fmt.Errorf("%s: scan sketch: %w", op, err)
+	}
+
+	stats.UniqueVisitors = total.Estimate()
+	for day, sketch := range perDay {
+		if d, ok := days[day]; ok {
+			d.UniqueVisitors = sketch.Estimate()
+		}
+	}
+
+	if filter.Top > 0 {
+		stats.Breakdowns = make(map[string][]models.BreakdownItem, len(models.Dimensions))
+		cond := urls + " AND " + classes
+		condArgs := append([]any{urlArg}, classArgs...)
+		for _, dimension := range models.Dimensions {
+			items, err := s.clickBreakdown(ctx, dimension, cond, condArgs, from, to, filter.Top)
+			if err != nil {
+				return models.LinkStats{}, fmt.Errorf("%s: %w", op, err)
+			}
+			stats.Breakdowns[dimension] = items
+		}
+	}
+
+	return stats, nil
+}
+
+// clickBreakdown returns the top values of the dimension among clicks
+// matching cond between the days from and to. Clicks the rollup job hasn't
+// counted yet are read from the click table.
+func (s *Storage) clickBreakdown(
+	ctx context.Context, dimension string, cond string, condArgs []any, from time.Time, to time.Time, top int,
+) ([]models.BreakdownItem, error) {
+	args := []any{dimension, from.Format(dayFormat), to.Format(dayFormat)}
+	args = append(args, condArgs...)
+	args = append(args, from.Format(sqliteTimeFormat), to.AddDate(0, 0, 1).Format(sqliteTimeFormat))
+	args = append(args, condArgs...)
+	args = append(args, top)
+
+	rows, err := s.db.QueryContext(ctx, `SELECT value, SUM(clicks) FROM (
+			SELECT value, clicks FROM click_breakdown
+			WHERE dimension = ? AND day >= ? AND day <= ? AND `+cond+`
+			UNION ALL
+			SELECT `+dimensionSQL[dimension]+`, COUNT(*) FROM click
+			WHERE rolled_up = 0 AND created_at >= ? AND created_at < ? AND `+cond+`
+			GROUP BY 1
+		)
+		GROUP BY 1
+		ORDER BY 2 DESC, 1
+		LIMIT ?`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("get %s breakdown: %w", dimension, err)
+	}
+	defer rows.Close()
+
+	items := make([]models.BreakdownItem, 0)
+	for rows.Next() {
+		var item models.BreakdownItem
+		if err := rows.Scan(&item.Value, &item.Clicks); err != nil {
+			return nil, fmt.Errorf("scan %s breakdown: %w", dimension, err)
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("scan %s breakdown: %w", dimension, err)
+	}
+
+	return items, nil
+}
+
+// linkScope returns the condition on url_id selecting the links of filter and
+// its argument. A single link must be owned by filter.UserID.
+func (s *Storage) linkScope(ctx context.Context, filter models.StatsFilter) (string, any, error) {
+	if filter.Alias == "" {
+		return "url_id IN (SELECT id FROM url WHERE user_id = ? AND deleted_at IS NULL)", filter.UserID, nil
+	}
+
+	var urlID, ownerID int64
+	err := s.db.QueryRowContext(ctx, "SELECT id, user_id FROM url WHERE domain = ? AND alias = ? AND deleted_at IS NULL",
+		filter.Domain, filter.Alias).Scan(&urlID, &ownerID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil, storage.ErrURLNotFound
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("get url: %w", err)
+	}
+	if ownerID != filter.UserID {
+		return "", nil, storage.ErrURLNotOwned
+	}
+
+	return "url_id = ?", urlID, nil
+}
+
+// trafficScope returns the classes of traffic, human only when none are
+// given, and the condition on class selecting them with its arguments.
+func trafficScope(traffic []string) ([]string, string, []any) {
+	if len(traffic) == 0 {
+		traffic = []string{"human"}
+	}
+
+	args := make([]any, 0, len(traffic))
+	for _, class := range traffic {
+		args = append(args, class)
+	}
+
+	return traffic, "class IN (?" + strings.Repeat(", ?", len(traffic)-1) + ")", args
+}
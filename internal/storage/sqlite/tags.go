@@ -0,0 +1,485 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+
+	"url-shortener/internal/lib/audit"
+	"url-shortener/internal/lib/urlnorm"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+const selectURLs = `SELECT u.id, u.url, u.domain, u.alias, u.user_id, COALESCE(f.name, ''),
+		u.title, u.description, u.notes, u.created_at, u.updated_at, u.quarantined_at, u.deleted_at,
+		COALESCE(m.title, ''), COALESCE(m.description, ''), COALESCE(m.image, ''), COALESCE(m.site_name, ''),
+		COALESCE(m.error, ''), m.fetched_at,
+		h.status_code, h.error, h.failures, h.checked_at, h.next_check_at, h.broken_at
+	FROM url u
+	LEFT JOIN folder f ON f.id = u.folder_id
+	LEFT JOIN url_metadata m ON m.url_id = u.id
+	LEFT JOIN url_health h ON h.url_id = u.id`
+
+func scanURLs(rows *sql.Rows) ([]models.URL, error) {
+	urls := make([]models.URL, 0)
+	for rows.Next() {
+		var (
+			url       models.URL
+			meta      models.PageMetadata
+			fetchedAt sql.NullTime
+			health    nullHealth
+		)
+		err := rows.Scan(
+			&url.ID, &url.URL, &url.Domain, &url.Alias, &url.UserID, &url.Folder,
+			&url.Title, &url.Description, &url.Notes, &url.CreatedAt, &url.UpdatedAt, &url.QuarantinedAt, &url.DeletedAt,
+			&meta.Title, &meta.Description, &meta.Image, &meta.SiteName, &meta.Error, &fetchedAt,
+			&health.statusCode, &health.err, &health.failures, &health.checkedAt, &health.nextCheckAt, &health.brokenAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if fetchedAt.Valid {
+			meta.FetchedAt = fetchedAt.Time
+			url.Metadata = &meta
+		}
+		url.Health = health.value()
+		urls = append(urls, url)
+	}
+
+	return urls, rows.Err()
+}
+
+// urlFilterSQL turns the filter into conditions on selectURLs. Paging is left to the caller.
+func urlFilterSQL(filter models.URLFilter) (string, []any) {
+	var (
+		where string
+		args  []any
+	)
+
+	if filter.Alias != "" {
+		where += ` AND u.alias LIKE ? ESCAPE '\'`
+		args = append(args, "%"+escapeLike(filter.Alias)+"%")
+	}
+	if filter.URL != "" {
+		where += ` AND u.url LIKE ? ESCAPE '\'`
+		args = append(args, "%"+escapeLike(filter.URL)+"%")
+	}
+	if filter.UserID != 0 {
+		where += " AND u.user_id = ?"
+		args = append(args, filter.UserID)
+	}
+	if filter.Tag != "" {
+		where += ` AND EXISTS (SELECT 1 FROM url_tag ut JOIN tag t ON t.id = ut.tag_id
+			WHERE ut.url_id = u.id AND t.name = ?)`
+		args = append(args, filter.Tag)
+	}
+	if filter.Folder != "" {
+		where += " AND f.name = ?"
+		args = append(args, filter.Folder)
+	}
+	if filter.Broken {
+		where += " AND h.broken_at IS NOT NULL"
+	}
+
+	return where, args
+}
+
+type rowsQuerier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// loadTags fills in tags of the urls.
+func loadTags(ctx context.Context, q rowsQuerier, urls []models.URL) error {
+	if len(urls) == 0 {
+		return nil
+	}
+
+	byID := make(map[int64]int, len(urls))
+	ids := make([]int64, 0, len(urls))
+	for i, u := range urls {
+		byID[u.ID] = i
+		ids = append(ids, u.ID)
+	}
+
+	in, args := inClause("WHERE ut.url_id IN", ids)
+
+	rows, err := q.QueryContext(ctx, "SELECT ut.url_id, t.name FROM url_tag ut JOIN tag t ON t.id = ut.tag_id "+
+		in+" ORDER BY t.name", args...)
+	if err != nil {
+		return fmt.Errorf("query tags: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			urlID int64
+			name  string
+		)
+		if err := rows.Scan(&urlID, &name); err != nil {
+			return fmt.Errorf("scan tag: %w", err)
+		}
+		i := byID[urlID]
+		urls[i].Tags = append(urls[i].Tags, name)
+	}
+
+	return rows.Err()
+}
+
+// nameID returns the id of the name in a tag or folder dictionary, adding it if needed.
+func nameID(ctx context.Context, tx *sql.Tx, table string, name string) (int64, error) {
+	if _, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO "+table+"(name) VALUES(?)", name); err != nil {
+		return 0, err
+	}
+
+	var id int64
+	err := tx.QueryRowContext(ctx, "SELECT id FROM "+table+" WHERE name = ?", name).Scan(&id)
+
+	return id, err
+}
+
+// setTags replaces the tags of the url.
+func setTags(ctx context.Context, tx *sql.Tx, urlID int64, tags []string) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM url_tag WHERE url_id = ?", urlID); err != nil {
+		return fmt.Errorf("delete tags: %w", err)
+	}
+
+	for _, name := range tags {
+		tagID, err := nameID(ctx, tx, "tag", name)
+		if err != nil {
+			return fmt.Errorf("save tag: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO url_tag(url_id, tag_id) VALUES(?, ?)", urlID, tagID); err != nil {
+			return fmt.Errorf("tag url: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// setFolder moves the url to the folder, an empty name takes it out of its folder.
+func setFolder(ctx context.Context, tx *sql.Tx, urlID int64, folder string) error {
+	var folderID sql.NullInt64
+	if folder != "" {
+		id, err := nameID(ctx, tx, "folder", folder)
+		if err != nil {
+			return fmt.Errorf("save folder: %w", err)
+		}
+		folderID = sql.NullInt64{Int64: id, Valid: true}
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE url SET folder_id = ? WHERE id = ?", folderID, urlID); err != nil {
+		return fmt.Errorf("set folder: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateURL changes the destination, labels or details of a url of the user.
+func (s *Storage) UpdateURL(ctx context.Context, domain string, alias string, userID int64, update models.URLUpdate) error {
+	const op = "storage.sqlite.UpdateURL"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	urlID, err := ownedURLID(ctx, tx, domain, alias, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	before, err := labeledURLState(ctx, tx, urlID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	after := before
+
+	if update.URL != nil {
+		if _, err := tx.ExecContext(ctx, "UPDATE url SET url = ?, url_hash = ? WHERE id = ?",
+			*update.URL, urlnorm.Hash(*update.URL), urlID); err != nil {
+			return fmt.Errorf("%s: update url: %w", op, err)
+		}
+		after.URL = *update.URL
+	}
+	if update.Tags != nil {
+		if err := setTags(ctx, tx, urlID, *update.Tags); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		after.Tags = sortedCopy(*update.Tags)
+	}
+	if update.Folder != nil {
+		if err := setFolder(ctx, tx, urlID, *update.Folder); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		after.Folder = *update.Folder
+	}
+	if update.Title != nil {
+		after.Title = *update.Title
+	}
+	if update.Description != nil {
+		after.Description = *update.Description
+	}
+	if update.Notes != nil {
+		after.Notes = *update.Notes
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE url SET title = ?, description = ?, notes = ? WHERE id = ?",
+		after.Title, after.Description, after.Notes, urlID)
+	if err != nil {
+		return fmt.Errorf("%s: update details: %w", op, err)
+	}
+
+	if after.URL != before.URL {
+		if err := resetMetadata(ctx, tx, urlID); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		// the old destination's health says nothing about the new one
+		if _, err := tx.ExecContext(ctx, "DELETE FROM url_health WHERE url_id = ?", urlID); err != nil {
+			return fmt.Errorf("%s: reset health: %w", op, err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE url SET updated_at = CURRENT_TIMESTAMP WHERE id = ?", urlID); err != nil {
+		return fmt.Errorf("%s: touch url: %w", op, err)
+	}
+
+	if err := writeAudit(ctx, tx, audit.ActionURLUpdate, domain, alias, before, after); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return nil
+}
+
+// labeledURLState snapshots the url with its tags and folder for the audit log.
+func labeledURLState(ctx context.Context, tx *sql.Tx, urlID int64) (urlState, error) {
+	rows, err := tx.QueryContext(ctx, selectURLs+" WHERE u.id = ?", urlID)
+	if err != nil {
+		return urlState{}, fmt.Errorf("query url: %w", err)
+	}
+	urls, err := scanURLs(rows)
+	rows.Close()
+	if err != nil {
+		return urlState{}, fmt.Errorf("scan url: %w", err)
+	}
+	if len(urls) == 0 {
+		return urlState{}, storage.ErrURLNotFound
+	}
+	if err := loadTags(ctx, tx, urls); err != nil {
+		return urlState{}, err
+	}
+
+	u := urls[0]
+
+	return urlState{
+		URL:         u.URL,
+		UserID:      u.UserID,
+		Quarantined: u.QuarantinedAt != nil,
+		Tags:        u.Tags,
+		Folder:      u.Folder,
+		Title:       u.Title,
+		Description: u.Description,
+		Notes:       u.Notes,
+	}, nil
+}
+
+// GetUserTags returns tags used on links of the user with link counts.
+func (s *Storage) GetUserTags(ctx context.Context, userID int64) ([]models.Tag, error) {
+	const op = "storage.sqlite.GetUserTags"
+
+	rows, err := s.db.QueryContext(ctx, `SELECT t.name, COUNT(*)
+		FROM url_tag ut
+		JOIN tag t ON t.id = ut.tag_id
+		JOIN url u ON u.id = ut.url_id
+		WHERE u.user_id = ? AND u.deleted_at IS NULL
+		GROUP BY t.id
+		ORDER BY t.name`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	defer rows.Close()
+
+	tags := make([]models.Tag, 0)
+	for rows.Next() {
+		var t models.Tag
+		if err := rows.Scan(&t.Name, &t.URLs); err != nil {
+			return nil, fmt.Errorf("%s: scan row: %w", op, err)
+		}
+		tags = append(tags, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: iterate rows: %w", op, err)
+	}
+
+	return tags, nil
+}
+
+// GetUserFolders returns folders holding links of the user with link counts.
+func (s *Storage) GetUserFolders(ctx context.Context, userID int64) ([]models.Folder, error) {
+	const op = "storage.sqlite.GetUserFolders"
+
+	rows, err := s.db.QueryContext(ctx, `SELECT f.name, COUNT(*)
+		FROM url u
+		JOIN folder f ON f.id = u.folder_id
+		WHERE u.user_id = ? AND u.deleted_at IS NULL
+		GROUP BY f.id
+		ORDER BY f.name`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	defer rows.Close()
+
+	folders := make([]models.Folder, 0)
+	for rows.Next() {
+		var f models.Folder
+		if err := rows.Scan(&f.Name, &f.URLs); err != nil {
+			return nil, fmt.Errorf("%s: scan row: %w", op, err)
+		}
+		folders = append(folders, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: iterate rows: %w", op, err)
+	}
+
+	return folders, nil
+}
+
+// RenameTag renames a tag on all links of the user. Renaming into a tag the
+// user already has fails with storage.ErrTagExists, MergeTags does that.
+func (s *Storage) RenameTag(ctx context.Context, userID int64, from string, to string) (int64, error) {
+	const op = "storage.sqlite.RenameTag"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	used, err := userTagLinks(ctx, tx, userID, to)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if used > 0 {
+		return 0, fmt.Errorf("%s: %w", op, storage.ErrTagExists)
+	}
+
+	n, err := retag(ctx, tx, userID, from, to)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if n == 0 {
+		return 0, fmt.Errorf("%s: %w", op, storage.ErrTagNotFound)
+	}
+
+	err = writeAudit(ctx, tx, audit.ActionTagRename, "", "", map[string]string{"tag": from}, map[string]string{"tag": to})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return n, nil
+}
+
+// MergeTags replaces the from tags with into on all links of the user and
+// returns the number of replaced tag assignments.
+func (s *Storage) MergeTags(ctx context.Context, userID int64, from []string, into string) (int64, error) {
+	const op = "storage.sqlite.MergeTags"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var total int64
+	for _, name := range from {
+		if name == into {
+			continue
+		}
+		n, err := retag(ctx, tx, userID, name, into)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+		total += n
+	}
+	if total == 0 {
+		return 0, fmt.Errorf("%s: %w", op, storage.ErrTagNotFound)
+	}
+
+	err = writeAudit(ctx, tx, audit.ActionTagMerge, "", "", map[string][]string{"tags": from}, map[string]string{"tag": into})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return total, nil
+}
+
+// userTagLinks counts links of the user tagged with name.
+func userTagLinks(ctx context.Context, tx *sql.Tx, userID int64, name string) (int64, error) {
+	var n int64
+
+	err := tx.QueryRowContext(ctx, `SELECT COUNT(*)
+		FROM url_tag ut
+		JOIN tag t ON t.id = ut.tag_id
+		JOIN url u ON u.id = ut.url_id
+		WHERE u.user_id = ? AND t.name = ?`, userID, name).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("count tag links: %w", err)
+	}
+
+	return n, nil
+}
+
+// retag moves links of the user from one tag to another and returns how many
+// links carried the old tag. Links that already have both keep a single one.
+func retag(ctx context.Context, tx *sql.Tx, userID int64, from string, to string) (int64, error) {
+	var fromID int64
+	err := tx.QueryRowContext(ctx, "SELECT id FROM tag WHERE name = ?", from).Scan(&fromID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("query tag: %w", err)
+	}
+
+	toID, err := nameID(ctx, tx, "tag", to)
+	if err != nil {
+		return 0, fmt.Errorf("save tag: %w", err)
+	}
+
+	const userLinks = "url_id IN (SELECT id FROM url WHERE user_id = ?)"
+
+	_, err = tx.ExecContext(ctx, `INSERT OR IGNORE INTO url_tag(url_id, tag_id)
+		SELECT url_id, ? FROM url_tag WHERE tag_id = ? AND `+userLinks, toID, fromID, userID)
+	if err != nil {
+		return 0, fmt.Errorf("copy tag: %w", err)
+	}
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM url_tag WHERE tag_id = ? AND "+userLinks, fromID, userID)
+	if err != nil {
+		return 0, fmt.Errorf("delete tag: %w", err)
+	}
+
+	return res.RowsAffected()
+}
+
+func sortedCopy(values []string) []string {
+	c := append([]string(nil), values...)
+	sort.Strings(c)
+
+	return c
+}
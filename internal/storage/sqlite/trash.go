@@ -0,0 +1,154 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"url-shortener/internal/lib/audit"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+// GetUserDeletedURLs returns urls of the user that are in the trash, most recently deleted first.
+func (s *Storage) GetUserDeletedURLs(ctx context.Context, userID int64) ([]models.URL, error) {
+	const op = "storage.sqlite.GetUserDeletedURLs"
+
+	rows, err := s.db.QueryContext(ctx, selectURLs+
+		" WHERE u.user_id = ? AND u.deleted_at IS NOT NULL ORDER BY u.deleted_at DESC, u.id DESC", userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	defer rows.Close()
+
+	urls, err := scanURLs(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: scan row: %w", op, err)
+	}
+
+	if err := loadTags(ctx, s.db, urls); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return urls, nil
+}
+
+// RestoreURL takes a url of the user out of the trash.
+func (s *Storage) RestoreURL(ctx context.Context, domain string, alias string, userID int64) error {
+	const op = "storage.sqlite.RestoreURL"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var state urlState
+	err = tx.QueryRowContext(ctx, `SELECT url, user_id, quarantined_at IS NOT NULL FROM url
+		WHERE domain = ? AND alias = ? AND deleted_at IS NOT NULL`, domain, alias).
+		Scan(&state.URL, &state.UserID, &state.Quarantined)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: %w", op, storage.ErrURLNotFound)
+		}
+		return fmt.Errorf("%s: query row: %w", op, err)
+	}
+	if state.UserID != userID {
+		return fmt.Errorf("%s: %w", op, storage.ErrURLNotOwned)
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE url SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE domain = ? AND alias = ?`, domain, alias)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	if err := writeAudit(ctx, tx, audit.ActionURLUndelete, domain, alias, nil, state); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return nil
+}
+
+// PurgeURL removes the url for good together with its rules, variants, clicks
+// and reports, whether it is in the trash or not.
+func (s *Storage) PurgeURL(ctx context.Context, domain string, alias string) error {
+	const op = "storage.sqlite.PurgeURL"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	targets, err := auditTargets(ctx, tx, "WHERE domain = ? AND alias = ?", domain, alias)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if len(targets) == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrURLNotFound)
+	}
+
+	if err := purge(ctx, tx, targets, "WHERE domain = ? AND alias = ?", domain, alias); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return nil
+}
+
+// PurgeDeletedURLs removes urls that were moved to the trash before
+// deletedBefore and returns how many were removed. Their aliases become free.
+func (s *Storage) PurgeDeletedURLs(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	const op = "storage.sqlite.PurgeDeletedURLs"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	where := "WHERE deleted_at IS NOT NULL AND deleted_at < ?"
+	before := deletedBefore.UTC().Format(sqliteTimeFormat)
+
+	targets, err := auditTargets(ctx, tx, where, before)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(targets) == 0 {
+		return 0, nil
+	}
+
+	if err := purge(ctx, tx, targets, where, before); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return int64(len(targets)), nil
+}
+
+func purge(ctx context.Context, tx *sql.Tx, targets []auditTarget, where string, args ...any) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM url "+where, args...); err != nil {
+		return fmt.Errorf("delete urls: %w", err)
+	}
+
+	for _, t := range targets {
+		if err := writeAudit(ctx, tx, audit.ActionURLPurge, t.domain, t.alias, t.state, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
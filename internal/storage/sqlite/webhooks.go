@@ -0,0 +1,302 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"url-shortener/internal/lib/audit"
+	"url-shortener/internal/models"
+	"url-shortener/internal/storage"
+)
+
+// webhookState is the audit snapshot of a webhook, without its secret.
+type webhookState struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+// SaveWebhook registers a webhook of the user.
+func (s *Storage) SaveWebhook(ctx context.Context, webhook models.Webhook) (int64, error) {
+	const op = "storage.sqlite.SaveWebhook"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "INSERT INTO webhook(user_id, url, secret, events) VALUES(?, ?, ?, ?)",
+		webhook.UserID, webhook.URL, webhook.Secret, strings.Join(webhook.Events, ","))
+	if err != nil {
+		return 0, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to get last insert id: %w", op, err)
+	}
+
+	err = writeAudit(ctx, tx, audit.ActionWebhookCreate, "", "", nil, webhookState{URL: webhook.URL, Events: webhook.Events})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// GetUserWebhooks returns webhooks of the user without their secrets.
+func (s *Storage) GetUserWebhooks(ctx context.Context, userID int64) ([]models.Webhook, error) {
+	const op = "storage.sqlite.GetUserWebhooks"
+
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT id, user_id, url, events, created_at FROM webhook WHERE user_id = ? ORDER BY id", userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	defer rows.Close()
+
+	webhooks := make([]models.Webhook, 0)
+	for rows.Next() {
+		var (
+			w      models.Webhook
+			events string
+		)
+		if err := rows.Scan(&w.ID, &w.UserID, &w.URL, &events, &w.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s: scan row: %w", op, err)
+		}
+		w.Events = strings.Split(events, ",")
+		webhooks = append(webhooks, w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: iterate rows: %w", op, err)
+	}
+
+	return webhooks, nil
+}
+
+// DeleteWebhook removes a webhook of the user with its deliveries.
+func (s *Storage) DeleteWebhook(ctx context.Context, userID int64, webhookID int64) error {
+	const op = "storage.sqlite.DeleteWebhook"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	before, err := ownedWebhook(ctx, tx, userID, webhookID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// deliveries go explicitly, foreign keys may be off
+	if _, err := tx.ExecContext(ctx, "DELETE FROM webhook_delivery WHERE webhook_id = ?", webhookID); err != nil {
+		return fmt.Errorf("%s: delete deliveries: %w", op, err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM webhook WHERE id = ?", webhookID); err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	if err := writeAudit(ctx, tx, audit.ActionWebhookDelete, "", "", before, nil); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return nil
+}
+
+// ownedWebhook returns the webhook snapshot, checking that it belongs to userID.
+// Webhooks of other users are reported as not found.
+func ownedWebhook(ctx context.Context, q querier, userID int64, webhookID int64) (webhookState, error) {
+	var (
+		state  webhookState
+		events string
+	)
+
+	err := q.QueryRowContext(ctx, "SELECT url, events FROM webhook WHERE id = ? AND user_id = ?", webhookID, userID).
+		Scan(&state.URL, &events)
+	if errors.Is(err, sql.ErrNoRows) {
+		return webhookState{}, storage.ErrWebhookNotFound
+	}
+	if err != nil {
+		return webhookState{}, err
+	}
+	state.Events = strings.Split(events, ",")
+
+	return state, nil
+}
+
+// EnqueueWebhookEvent queues the payload for every webhook of the link's owner
+// subscribed to the event and returns the number of queued deliveries. Deleted
+// links still have an owner so their link.deleted events are delivered.
+func (s *Storage) EnqueueWebhookEvent(
+	ctx context.Context, domain string, alias string, event string, payload []byte,
+) (int64, error) {
+	const op = "storage.sqlite.EnqueueWebhookEvent"
+
+	res, err := s.db.ExecContext(ctx, `INSERT INTO webhook_delivery(webhook_id, event, payload, next_attempt_at)
+		SELECT w.id, ?, ?, CURRENT_TIMESTAMP
+		FROM url u
+		JOIN webhook w ON w.user_id = u.user_id
+		WHERE u.domain = ? AND u.alias = ? AND (',' || w.events || ',') LIKE ('%,' || ? || ',%')`,
+		event, string(payload), domain, alias, event)
+	if err != nil {
+		return 0, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: rows affected: %w", op, err)
+	}
+
+	return n, nil
+}
+
+const selectDeliveries = `SELECT d.id, d.webhook_id, d.event, d.payload, d.status, d.attempts, d.next_attempt_at,
+		d.last_status_code, d.last_error, d.created_at, d.delivered_at, w.url, w.secret
+	FROM webhook_delivery d
+	JOIN webhook w ON w.id = d.webhook_id`
+
+func scanDeliveries(rows *sql.Rows) ([]models.WebhookDelivery, error) {
+	deliveries := make([]models.WebhookDelivery, 0)
+	for rows.Next() {
+		var (
+			d       models.WebhookDelivery
+			payload string
+		)
+		err := rows.Scan(&d.ID, &d.WebhookID, &d.Event, &payload, &d.Status, &d.Attempts, &d.NextAttemptAt,
+			&d.LastStatusCode, &d.LastError, &d.CreatedAt, &d.DeliveredAt, &d.URL, &d.Secret)
+		if err != nil {
+			return nil, err
+		}
+		d.Payload = []byte(payload)
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// GetWebhookDeliveries returns deliveries of a webhook of the user, newest first.
+func (s *Storage) GetWebhookDeliveries(
+	ctx context.Context, userID int64, webhookID int64, limit int, offset int,
+) ([]models.WebhookDelivery, error) {
+	const op = "storage.sqlite.GetWebhookDeliveries"
+
+	if _, err := ownedWebhook(ctx, s.db, userID, webhookID); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, selectDeliveries+" WHERE d.webhook_id = ? ORDER BY d.id DESC LIMIT ? OFFSET ?",
+		webhookID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	defer rows.Close()
+
+	deliveries, err := scanDeliveries(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: scan row: %w", op, err)
+	}
+
+	return deliveries, nil
+}
+
+// GetDueWebhookDeliveries returns up to limit pending deliveries due at now,
+// oldest first, with the url and secret of their webhook.
+func (s *Storage) GetDueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	const op = "storage.sqlite.GetDueWebhookDeliveries"
+
+	rows, err := s.db.QueryContext(ctx, selectDeliveries+`
+		WHERE d.status = ? AND d.next_attempt_at <= ?
+		ORDER BY d.next_attempt_at, d.id
+		LIMIT ?`,
+		models.DeliveryStatusPending, now.UTC().Format(sqliteTimeFormat), limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	defer rows.Close()
+
+	deliveries, err := scanDeliveries(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: scan row: %w", op, err)
+	}
+
+	return deliveries, nil
+}
+
+// SaveWebhookAttempt stores the outcome of a delivery attempt: status,
+// attempts, next attempt, last status code and error and delivery time.
+func (s *Storage) SaveWebhookAttempt(ctx context.Context, d models.WebhookDelivery) error {
+	const op = "storage.sqlite.SaveWebhookAttempt"
+
+	_, err := s.db.ExecContext(ctx, `UPDATE webhook_delivery
+		SET status = ?, attempts = ?, next_attempt_at = ?, last_status_code = ?, last_error = ?, delivered_at = ?
+		WHERE id = ?`,
+		d.Status, d.Attempts, sqliteTime(d.NextAttemptAt), d.LastStatusCode, d.LastError, sqliteTime(d.DeliveredAt), d.ID)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	return nil
+}
+
+// RedeliverWebhook queues a delivery of a webhook of the user again with a
+// fresh set of attempts.
+func (s *Storage) RedeliverWebhook(ctx context.Context, userID int64, webhookID int64, deliveryID int64) error {
+	const op = "storage.sqlite.RedeliverWebhook"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := ownedWebhook(ctx, tx, userID, webhookID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := tx.ExecContext(ctx, `UPDATE webhook_delivery
+		SET status = ?, attempts = 0, next_attempt_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND webhook_id = ?`,
+		models.DeliveryStatusPending, deliveryID, webhookID)
+	if err != nil {
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("%s: rows affected: %w", op, err)
+	} else if n == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrDeliveryNotFound)
+	}
+
+	err = writeAudit(ctx, tx, audit.ActionWebhookRedeliver, "", "", nil,
+		map[string]int64{"webhook_id": webhookID, "delivery_id": deliveryID})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return nil
+}
+
+// sqliteTime formats t like CURRENT_TIMESTAMP, nil stays NULL.
+func sqliteTime(t *time.Time) sql.NullString {
+	if t == nil {
+		return sql.NullString{}
+	}
+
+	return sql.NullString{String: t.UTC().Format(sqliteTimeFormat), Valid: true}
+}
@@ -3,6 +3,8 @@ package storage
 import (
 	"context"
 	"errors"
+	"time"
+
 	"url-shortener/internal/models"
 )
 
@@ -11,14 +13,106 @@ var (
 	ErrURLNotOwned = errors.New("url not owned")
 	ErrURLExists   = errors.New("url exists")
 	ErrUserURLsNotFound = errors.New("user urls not found")
+	ErrURLQuarantined   = errors.New("url quarantined")
+	ErrRuleNotFound     = errors.New("rule not found")
+	ErrVariantNotFound  = errors.New("variant not found")
+	ErrTagNotFound      = errors.New("tag not found")
+	ErrTagExists        = errors.New("tag exists")
+	ErrWebhookNotFound  = errors.New("webhook not found")
+	ErrDeliveryNotFound = errors.New("delivery not found")
+
+	ErrDomainNotFound    = errors.New("domain not found")
+	ErrDomainExists      = errors.New("domain exists")
+	ErrDomainNotOwned    = errors.New("domain not owned")
+	ErrDomainNotVerified = errors.New("domain not verified")
+	ErrDomainInUse       = errors.New("domain in use")
+
+	ErrIdempotencyKeyNotFound = errors.New("idempotency key not found")
+	ErrIdempotencyKeyExists   = errors.New("idempotency key exists")
 )
 
 // Storage represents the storage interface for URL operations
 
 
 type Storage interface {
-	SaveURL(ctx context.Context, urlToSave string, alias string, userID int64) (int64, error)
-	GetURL(ctx context.Context, alias string) (string, error)
-	GetUserURLs(ctx context.Context, userID int64) ([]models.URL, error)
-	DeleteURL(ctx context.Context, alias string, userID int64, isAdmin bool) error
+	SaveURL(
+		ctx context.Context, urlToSave string, domain string, alias string, userID int64, attrs models.URLAttrs,
+	) (int64, error)
+	GetURL(ctx context.Context, domain string, alias string) (string, error)
+	GetURLOwner(ctx context.Context, domain string, alias string) (int64, error)
+	SaveOrReuseURL(
+		ctx context.Context, urlToSave string, domain string, alias string, userID int64, attrs models.URLAttrs,
+	) (int64, string, error)
+	GetUserURLs(ctx context.Context, userID int64, filter models.URLFilter) ([]models.URL, error)
+	UpdateURL(ctx context.Context, domain string, alias string, userID int64, update models.URLUpdate) error
+	DeleteURL(ctx context.Context, domain string, alias string, userID int64, isAdmin bool) error
+	GetUserDeletedURLs(ctx context.Context, userID int64) ([]models.URL, error)
+	RestoreURL(ctx context.Context, domain string, alias string, userID int64) error
+	PurgeURL(ctx context.Context, domain string, alias string) error
+	PurgeDeletedURLs(ctx context.Context, deletedBefore time.Time) (int64, error)
+	GetPendingMetadata(ctx context.Context, limit int) ([]models.URL, error)
+	SaveURLMetadata(ctx context.Context, urlID int64, meta models.PageMetadata) error
+	GetURLPreview(ctx context.Context, domain string, alias string) (models.URL, error)
+	GetDueHealthChecks(ctx context.Context, now time.Time, limit int) ([]models.URL, error)
+	SaveLinkHealth(ctx context.Context, urlID int64, health models.LinkHealth) error
+
+	GetURLRules(ctx context.Context, domain string, alias string) ([]models.Rule, error)
+	GetUserURLRules(ctx context.Context, domain string, alias string, userID int64) ([]models.Rule, error)
+	SaveURLRule(ctx context.Context, domain string, alias string, userID int64, rule models.Rule) (int64, error)
+	UpdateURLRule(ctx context.Context, domain string, alias string, userID int64, rule models.Rule) error
+	DeleteURLRule(ctx context.Context, domain string, alias string, userID int64, ruleID int64) error
+
+	GetURLVariants(ctx context.Context, domain string, alias string) ([]models.Variant, error)
+	GetUserURLVariants(ctx context.Context, domain string, alias string, userID int64) ([]models.Variant, error)
+	SetURLVariants(ctx context.Context, domain string, alias string, userID int64, variants []models.Variant) error
+	RecordClick(ctx context.Context, click models.Click) error
+	MergeVisitorSketches(ctx context.Context, sketches []models.VisitorSketch) error
+	GetLinkStats(ctx context.Context, filter models.StatsFilter) (models.LinkStats, error)
+	ExportClicks(ctx context.Context, filter models.StatsFilter, fn func(models.Click) error) error
+	ExportClickCounts(ctx context.Context, filter models.StatsFilter, period string, fn func(models.ClickCount) error) error
+	RollupClicks(ctx context.Context, limit int) (int64, error)
+	PruneClicks(ctx context.Context, before time.Time, limit int) (int64, error)
+	PruneHourlyClicks(ctx context.Context, before time.Time) (int64, error)
+
+	GetUserTags(ctx context.Context, userID int64) ([]models.Tag, error)
+	GetUserFolders(ctx context.Context, userID int64) ([]models.Folder, error)
+	RenameTag(ctx context.Context, userID int64, from string, to string) (int64, error)
+	MergeTags(ctx context.Context, userID int64, from []string, into string) (int64, error)
+
+	SaveWebhook(ctx context.Context, webhook models.Webhook) (int64, error)
+	GetUserWebhooks(ctx context.Context, userID int64) ([]models.Webhook, error)
+	DeleteWebhook(ctx context.Context, userID int64, webhookID int64) error
+	EnqueueWebhookEvent(ctx context.Context, domain string, alias string, event string, payload []byte) (int64, error)
+	GetWebhookDeliveries(ctx context.Context, userID int64, webhookID int64, limit int, offset int) ([]models.WebhookDelivery, error)
+	GetDueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error)
+	SaveWebhookAttempt(ctx context.Context, delivery models.WebhookDelivery) error
+	RedeliverWebhook(ctx context.Context, userID int64, webhookID int64, deliveryID int64) error
+
+	SaveDomain(ctx context.Context, hostname string, userID int64, token string, staleBefore time.Time) (int64, error)
+	GetDomain(ctx context.Context, hostname string) (models.Domain, error)
+	GetUserDomains(ctx context.Context, userID int64) ([]models.Domain, error)
+	MarkDomainVerified(ctx context.Context, hostname string, userID int64) error
+	DeleteDomain(ctx context.Context, hostname string, userID int64) error
+
+	SaveReport(ctx context.Context, domain string, alias string, report models.Report) (int64, error)
+	GetReports(ctx context.Context, status string) ([]models.Report, error)
+	SetURLQuarantined(ctx context.Context, domain string, alias string, quarantined bool) error
+
+	AdminSearchURLs(ctx context.Context, filter models.URLFilter) ([]models.URL, error)
+	AdminDeleteURLs(ctx context.Context, ids []int64) (int64, error)
+	AdminTransferURLs(ctx context.Context, fromUserID int64, toUserID int64, ids []int64) (int64, error)
+	AdminStats(ctx context.Context) (models.Stats, error)
+
+	RecordAudit(ctx context.Context, entry models.AuditEntry) error
+	GetAuditLog(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error)
+	ExportAuditLog(ctx context.Context, filter models.AuditFilter, fn func(models.AuditEntry) error) error
+
+	SaveIdempotencyKey(ctx context.Context, key models.IdempotencyKey, expiredBefore time.Time) error
+	GetIdempotencyKey(ctx context.Context, userID int64, key string) (models.IdempotencyKey, error)
+	CompleteIdempotencyKey(ctx context.Context, key models.IdempotencyKey) error
+	DeleteIdempotencyKey(ctx context.Context, userID int64, key string) error
+	PruneIdempotencyKeys(ctx context.Context, before time.Time) (int64, error)
+
+	GetUserSettings(ctx context.Context, userID int64) (models.UserSettings, error)
+	SaveUserSettings(ctx context.Context, settings models.UserSettings) error
 }
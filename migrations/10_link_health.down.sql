@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_url_health_broken;
+DROP INDEX IF EXISTS idx_url_health_next_check_at;
+DROP TABLE IF EXISTS url_health;
@@ -0,0 +1,13 @@
+-- result of the last destination check, failures counts consecutive failed
+-- checks and broken_at is set once it reaches the configured threshold
+CREATE TABLE IF NOT EXISTS url_health(
+    url_id INTEGER PRIMARY KEY REFERENCES url(id) ON DELETE CASCADE,
+    status_code INTEGER NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    failures INTEGER NOT NULL DEFAULT 0,
+    checked_at TIMESTAMP NOT NULL,
+    next_check_at TIMESTAMP NOT NULL,
+    broken_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_url_health_next_check_at ON url_health(next_check_at);
+CREATE INDEX IF NOT EXISTS idx_url_health_broken ON url_health(url_id) WHERE broken_at IS NOT NULL;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_webhook_delivery_pending;
+DROP INDEX IF EXISTS idx_webhook_delivery_webhook_id;
+DROP TABLE IF EXISTS webhook_delivery;
+DROP INDEX IF EXISTS idx_webhook_user_id;
+DROP TABLE IF EXISTS webhook;
@@ -0,0 +1,7 @@
+-- HyperLogLog sketches of distinct visitors, one per link and UTC day
+CREATE TABLE IF NOT EXISTS visitor_sketch(
+    url_id INTEGER NOT NULL REFERENCES url(id) ON DELETE CASCADE,
+    day TEXT NOT NULL,
+    sketch BLOB NOT NULL,
+    PRIMARY KEY(url_id, day)
+);
@@ -0,0 +1,12 @@
+-- sketches can't be merged in SQL, only human traffic is kept
+CREATE TABLE IF NOT EXISTS visitor_sketch_old(
+    url_id INTEGER NOT NULL REFERENCES url(id) ON DELETE CASCADE,
+    day TEXT NOT NULL,
+    sketch BLOB NOT NULL,
+    PRIMARY KEY(url_id, day)
+);
+INSERT INTO visitor_sketch_old(url_id, day, sketch) SELECT url_id, day, sketch FROM visitor_sketch WHERE class = 'human';
+DROP TABLE visitor_sketch;
+ALTER TABLE visitor_sketch_old RENAME TO visitor_sketch;
+
+ALTER TABLE click DROP COLUMN class;
@@ -0,0 +1,14 @@
+-- human, bot or preview, see lib/botdetect
+ALTER TABLE click ADD COLUMN class TEXT NOT NULL DEFAULT 'human';
+
+-- visitor sketches are kept per class of traffic
+CREATE TABLE IF NOT EXISTS visitor_sketch_new(
+    url_id INTEGER NOT NULL REFERENCES url(id) ON DELETE CASCADE,
+    day TEXT NOT NULL,
+    class TEXT NOT NULL DEFAULT 'human',
+    sketch BLOB NOT NULL,
+    PRIMARY KEY(url_id, day, class)
+);
+INSERT INTO visitor_sketch_new(url_id, day, class, sketch) SELECT url_id, day, 'human', sketch FROM visitor_sketch;
+DROP TABLE visitor_sketch;
+ALTER TABLE visitor_sketch_new RENAME TO visitor_sketch;
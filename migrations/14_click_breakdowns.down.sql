@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS click_breakdown;
+DROP INDEX IF EXISTS idx_click_rollup_pending;
+ALTER TABLE click DROP COLUMN rolled_up;
+ALTER TABLE click DROP COLUMN device;
+ALTER TABLE click DROP COLUMN os;
+ALTER TABLE click DROP COLUMN browser_version;
+ALTER TABLE click DROP COLUMN browser;
+ALTER TABLE click DROP COLUMN referrer;
@@ -0,0 +1,19 @@
+ALTER TABLE click ADD COLUMN referrer TEXT NOT NULL DEFAULT '';
+ALTER TABLE click ADD COLUMN browser TEXT NOT NULL DEFAULT '';
+ALTER TABLE click ADD COLUMN browser_version TEXT NOT NULL DEFAULT '';
+ALTER TABLE click ADD COLUMN os TEXT NOT NULL DEFAULT '';
+ALTER TABLE click ADD COLUMN device TEXT NOT NULL DEFAULT '';
+-- set once the click is counted in the rollup tables
+ALTER TABLE click ADD COLUMN rolled_up INTEGER NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS idx_click_rollup_pending ON click(id) WHERE rolled_up = 0;
+
+-- clicks per day and value of each breakdown dimension (referrer, browser, ...)
+CREATE TABLE IF NOT EXISTS click_breakdown(
+    url_id INTEGER NOT NULL REFERENCES url(id) ON DELETE CASCADE,
+    day TEXT NOT NULL,
+    class TEXT NOT NULL,
+    dimension TEXT NOT NULL,
+    value TEXT NOT NULL,
+    clicks INTEGER NOT NULL,
+    PRIMARY KEY(url_id, dimension, day, class, value)
+);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_click_rolled_up_created_at;
+DROP TABLE IF EXISTS click_daily;
+DROP INDEX IF EXISTS idx_click_hourly_hour;
+DROP TABLE IF EXISTS click_hourly;
@@ -0,0 +1,34 @@
+-- clicks per hour and per day, raw clicks can be pruned once counted here.
+-- variant_id is 0 for clicks without a split variant.
+CREATE TABLE IF NOT EXISTS click_hourly(
+    url_id INTEGER NOT NULL REFERENCES url(id) ON DELETE CASCADE,
+    hour TEXT NOT NULL,
+    class TEXT NOT NULL,
+    variant_id INTEGER NOT NULL,
+    clicks INTEGER NOT NULL,
+    PRIMARY KEY(url_id, hour, class, variant_id)
+);
+CREATE INDEX IF NOT EXISTS idx_click_hourly_hour ON click_hourly(hour);
+
+CREATE TABLE IF NOT EXISTS click_daily(
+    url_id INTEGER NOT NULL REFERENCES url(id) ON DELETE CASCADE,
+    day TEXT NOT NULL,
+    class TEXT NOT NULL,
+    variant_id INTEGER NOT NULL,
+    clicks INTEGER NOT NULL,
+    PRIMARY KEY(url_id, day, class, variant_id)
+);
+
+-- clicks already counted in the breakdowns are not rolled up again
+INSERT INTO click_hourly(url_id, hour, class, variant_id, clicks)
+SELECT url_id, strftime('%Y-%m-%d %H:00:00', created_at), class, COALESCE(variant_id, 0), COUNT(*)
+FROM click WHERE rolled_up = 1
+GROUP BY 1, 2, 3, 4;
+
+INSERT INTO click_daily(url_id, day, class, variant_id, clicks)
+SELECT url_id, date(created_at), class, COALESCE(variant_id, 0), COUNT(*)
+FROM click WHERE rolled_up = 1
+GROUP BY 1, 2, 3, 4;
+
+-- finds raw clicks past retention
+CREATE INDEX IF NOT EXISTS idx_click_rolled_up_created_at ON click(created_at) WHERE rolled_up = 1;
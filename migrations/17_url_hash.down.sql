@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS user_settings;
+
+DROP INDEX IF EXISTS idx_url_user_hash;
+ALTER TABLE url DROP COLUMN url_hash;
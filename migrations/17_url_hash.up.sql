@@ -0,0 +1,10 @@
+-- SHA-256 of the normalized destination, see lib/urlnorm. Existing links are
+-- hashed by the storage on startup.
+ALTER TABLE url ADD COLUMN url_hash TEXT;
+CREATE INDEX IF NOT EXISTS idx_url_user_hash ON url(user_id, domain, url_hash) WHERE deleted_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS user_settings(
+    user_id INTEGER PRIMARY KEY,
+    reuse_existing INTEGER NOT NULL DEFAULT 0,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
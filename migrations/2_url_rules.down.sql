@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_url_rule_url_id;
+DROP TABLE IF EXISTS url_rule;
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS url_rule(
+    id INTEGER PRIMARY KEY,
+    url_id INTEGER NOT NULL REFERENCES url(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    platform TEXT NOT NULL DEFAULT '',
+    language TEXT NOT NULL DEFAULT '',
+    country TEXT NOT NULL DEFAULT '',
+    time_from TEXT NOT NULL DEFAULT '',
+    time_to TEXT NOT NULL DEFAULT '',
+    target_url TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_url_rule_url_id ON url_rule(url_id, position);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_click_variant_id;
+DROP INDEX IF EXISTS idx_click_url_id;
+DROP TABLE IF EXISTS click;
+DROP INDEX IF EXISTS idx_url_variant_url_id;
+DROP TABLE IF EXISTS url_variant;
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS url_variant(
+    id INTEGER PRIMARY KEY,
+    url_id INTEGER NOT NULL REFERENCES url(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    weight INTEGER NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_url_variant_url_id ON url_variant(url_id);
+
+CREATE TABLE IF NOT EXISTS click(
+    id INTEGER PRIMARY KEY,
+    url_id INTEGER NOT NULL REFERENCES url(id) ON DELETE CASCADE,
+    variant_id INTEGER REFERENCES url_variant(id) ON DELETE SET NULL,
+    visitor_id TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_click_url_id ON click(url_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_click_variant_id ON click(variant_id);
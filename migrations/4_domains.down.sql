@@ -0,0 +1,19 @@
+CREATE TABLE url_old(
+    id INTEGER PRIMARY KEY,
+    alias TEXT NOT NULL UNIQUE,
+    url TEXT NOT NULL,
+    user_id INTEGER NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+-- links on custom domains can't be kept once aliases are global again
+INSERT INTO url_old(id, alias, url, user_id, created_at, updated_at)
+    SELECT id, alias, url, user_id, created_at, updated_at FROM url WHERE domain = '';
+DROP INDEX IF EXISTS idx_user_id;
+DROP TABLE url;
+ALTER TABLE url_old RENAME TO url;
+CREATE INDEX IF NOT EXISTS idx_alias ON url(alias);
+CREATE INDEX IF NOT EXISTS idx_user_id ON url(user_id);
+
+DROP INDEX IF EXISTS idx_domain_user_id;
+DROP TABLE IF EXISTS domain;
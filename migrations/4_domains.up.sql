@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS domain(
+    id INTEGER PRIMARY KEY,
+    hostname TEXT NOT NULL UNIQUE,
+    user_id INTEGER NOT NULL,
+    verification_token TEXT NOT NULL,
+    verified_at DATETIME,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_domain_user_id ON domain(user_id);
+
+-- aliases become unique per domain, '' is the default short domain
+CREATE TABLE url_new(
+    id INTEGER PRIMARY KEY,
+    domain TEXT NOT NULL DEFAULT '',
+    alias TEXT NOT NULL,
+    url TEXT NOT NULL,
+    user_id INTEGER NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(domain, alias)
+);
+INSERT INTO url_new(id, alias, url, user_id, created_at, updated_at)
+    SELECT id, alias, url, user_id, created_at, updated_at FROM url;
+DROP INDEX IF EXISTS idx_alias;
+DROP INDEX IF EXISTS idx_user_id;
+DROP TABLE url;
+ALTER TABLE url_new RENAME TO url;
+CREATE INDEX IF NOT EXISTS idx_user_id ON url(user_id);
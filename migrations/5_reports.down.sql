@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_report_url_id;
+DROP INDEX IF EXISTS idx_report_status;
+DROP TABLE IF EXISTS report;
+
+ALTER TABLE url DROP COLUMN quarantined_at;
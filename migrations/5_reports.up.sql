@@ -0,0 +1,15 @@
+ALTER TABLE url ADD COLUMN quarantined_at DATETIME;
+
+CREATE TABLE IF NOT EXISTS report(
+    id INTEGER PRIMARY KEY,
+    url_id INTEGER NOT NULL REFERENCES url(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL,
+    details TEXT NOT NULL DEFAULT '',
+    reporter_email TEXT NOT NULL DEFAULT '',
+    reporter_ip TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'open',
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    resolved_at DATETIME
+);
+CREATE INDEX IF NOT EXISTS idx_report_status ON report(status, created_at);
+CREATE INDEX IF NOT EXISTS idx_report_url_id ON report(url_id);
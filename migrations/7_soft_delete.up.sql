@@ -0,0 +1,2 @@
+ALTER TABLE url ADD COLUMN deleted_at DATETIME;
+CREATE INDEX IF NOT EXISTS idx_url_deleted_at ON url(deleted_at) WHERE deleted_at IS NOT NULL;
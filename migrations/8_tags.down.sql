@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_url_folder_id;
+ALTER TABLE url DROP COLUMN folder_id;
+DROP TABLE IF EXISTS folder;
+DROP INDEX IF EXISTS idx_url_tag_tag_id;
+DROP TABLE IF EXISTS url_tag;
+DROP TABLE IF EXISTS tag;
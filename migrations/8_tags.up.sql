@@ -0,0 +1,21 @@
+-- tags and folders are shared dictionaries of names, a user's tags and
+-- folders are the ones attached to their links
+CREATE TABLE IF NOT EXISTS tag(
+    id INTEGER PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS url_tag(
+    url_id INTEGER NOT NULL REFERENCES url(id) ON DELETE CASCADE,
+    tag_id INTEGER NOT NULL REFERENCES tag(id) ON DELETE CASCADE,
+    PRIMARY KEY(url_id, tag_id)
+);
+CREATE INDEX IF NOT EXISTS idx_url_tag_tag_id ON url_tag(tag_id);
+
+CREATE TABLE IF NOT EXISTS folder(
+    id INTEGER PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE
+);
+
+ALTER TABLE url ADD COLUMN folder_id INTEGER REFERENCES folder(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_url_folder_id ON url(folder_id);
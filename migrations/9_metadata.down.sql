@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_url_metadata_pending;
+DROP TABLE IF EXISTS url_metadata;
+ALTER TABLE url DROP COLUMN notes;
+ALTER TABLE url DROP COLUMN description;
+ALTER TABLE url DROP COLUMN title;
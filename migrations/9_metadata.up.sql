@@ -0,0 +1,18 @@
+ALTER TABLE url ADD COLUMN title TEXT NOT NULL DEFAULT '';
+ALTER TABLE url ADD COLUMN description TEXT NOT NULL DEFAULT '';
+ALTER TABLE url ADD COLUMN notes TEXT NOT NULL DEFAULT '';
+
+-- metadata fetched from the destination page, a row without fetched_at is
+-- waiting for the fetcher
+CREATE TABLE IF NOT EXISTS url_metadata(
+    url_id INTEGER PRIMARY KEY REFERENCES url(id) ON DELETE CASCADE,
+    title TEXT NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    image TEXT NOT NULL DEFAULT '',
+    site_name TEXT NOT NULL DEFAULT '',
+    error TEXT NOT NULL DEFAULT '',
+    fetched_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_url_metadata_pending ON url_metadata(url_id) WHERE fetched_at IS NULL;
+
+INSERT INTO url_metadata(url_id) SELECT id FROM url WHERE deleted_at IS NULL;
@@ -25,7 +25,7 @@ func TestURLShortener_HappyPath(t *testing.T) {
 	}
 	e := httpexpect.Default(t, u.String())
 
-	e.POST("/url").
+	e.POST("/api/v1/url").
 		WithJSON(save.Request{
 			URL:   gofakeit.URL(),
 			Alias: random.NewRandomString(10),
@@ -75,7 +75,7 @@ func TestURLShortener_SaveRedirect(t *testing.T) {
 
 			// Save
 
-			resp := e.POST("/url").
+			resp := e.POST("/api/v1/url").
 				WithJSON(save.Request{
 					URL:   tc.url,
 					Alias: tc.alias,
@@ -116,7 +116,7 @@ func testRedirect(t *testing.T, alias string, urlToRedirect string) {
 		Path:   alias,
 	}
 
-	redirectedToURL, err := api.GetRedirect(u.String())
+	redirectedToURL, err := api.GetRedirect(u.String(), "", 0)
 	require.NoError(t, err)
 
 	require.Equal(t, urlToRedirect, redirectedToURL)